@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// runHours prints the weekly opening hours, upcoming special dates and whether
+// the restaurant is open now
+func runHours(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("hours", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var info models.RestaurantInfo
+	err := db.Where("location_id IS NOT DISTINCT FROM ?", locationOf(db)).Order("updated_at desc").First(&info).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("restaurant info not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	week := info.OpeningHours.WeekSchedule
+	days := []struct {
+		name     string
+		schedule models.DaySchedule
+	}{
+		{"Monday", week.Monday},
+		{"Tuesday", week.Tuesday},
+		{"Wednesday", week.Wednesday},
+		{"Thursday", week.Thursday},
+		{"Friday", week.Friday},
+		{"Saturday", week.Saturday},
+		{"Sunday", week.Sunday},
+	}
+
+	fmt.Println(info.Name)
+	for _, day := range days {
+		fmt.Printf("  %-10s %s\n", day.name, formatRanges(day.schedule))
+	}
+
+	today := time.Now().Format("2006-01-02")
+	for _, special := range info.OpeningHours.SpecialDates {
+		if special.Date >= today {
+			fmt.Printf("  %-10s %s\n", special.Date, formatRanges(special.Schedule))
+		}
+	}
+
+	if info.OpeningHours.IsOpen(time.Now()) {
+		fmt.Println("Open now")
+	} else {
+		fmt.Println("Closed now")
+	}
+	return nil
+}
+
+func formatRanges(schedule models.DaySchedule) string {
+	if len(schedule.Ranges) == 0 {
+		return "closed"
+	}
+	ranges := make([]string, len(schedule.Ranges))
+	for i, r := range schedule.Ranges {
+		ranges[i] = r.Open + "-" + r.Close
+	}
+	return strings.Join(ranges, ", ")
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// MenuFile is the import and export format of a menu. Items and add-ons are
+// matched by name, so a file can be exported from one database and imported
+// into another.
+type MenuFile struct {
+	Categories []MenuFileCategory `json:"categories"`
+}
+
+type MenuFileCategory struct {
+	Name         string         `json:"name"`
+	DisplayOrder int            `json:"display_order"`
+	Items        []MenuFileItem `json:"items"`
+}
+
+type MenuFileItem struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Price       float64         `json:"price"`
+	ImageURL    string          `json:"image_url,omitempty"`
+	IsAvailable *bool           `json:"is_available,omitempty"`
+	AddOns      []MenuFileAddOn `json:"add_ons,omitempty"`
+}
+
+type MenuFileAddOn struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+}
+
+func runMenu(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "export":
+		return exportMenu(db, args[1:])
+	case "import":
+		return importMenu(db, args[1:])
+	}
+	return errUsage
+}
+
+func exportMenu(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("menu export", flag.ContinueOnError)
+	output := fs.String("o", "", "file to write, stdout when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var categories []models.Category
+	err := db.Where("location_id IS NOT DISTINCT FROM ?", locationOf(db)).
+		Preload("MenuItems", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Preload("MenuItems.AddOns", func(tx *gorm.DB) *gorm.DB { return tx.Order("id") }).
+		Order("display_order").Order("id").
+		Find(&categories).Error
+	if err != nil {
+		return err
+	}
+
+	var file MenuFile
+	for _, category := range categories {
+		fileCategory := MenuFileCategory{Name: category.Name, DisplayOrder: category.DisplayOrder, Items: []MenuFileItem{}}
+		for _, item := range category.MenuItems {
+			available := item.IsAvailable
+			fileItem := MenuFileItem{
+				Name:        item.Name,
+				Description: item.Description,
+				Price:       item.Price,
+				ImageURL:    item.ImageURL,
+				IsAvailable: &available,
+			}
+			for _, addOn := range item.AddOns {
+				fileItem.AddOns = append(fileItem.AddOns, MenuFileAddOn{Name: addOn.Name, Price: addOn.Price})
+			}
+			fileCategory.Items = append(fileCategory.Items, fileItem)
+		}
+		file.Categories = append(file.Categories, fileCategory)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// importMenu creates missing categories, items and add-ons and updates the ones
+// already there. Nothing is deleted.
+func importMenu(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("menu import", flag.ContinueOnError)
+	input := fs.String("f", "", "file to read, stdin when empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	var file MenuFile
+	if err := json.NewDecoder(in).Decode(&file); err != nil {
+		return fmt.Errorf("invalid menu file: %w", err)
+	}
+
+	created, updated, err := importMenuFile(db, file)
+	if err != nil {
+		return fmt.Errorf("menu import failed, nothing was changed: %w", err)
+	}
+	fmt.Printf("Imported menu: %d created, %d updated\n", created, updated)
+	return nil
+}
+
+// importMenuFile writes file to the menu in one transaction, returning how many
+// rows were created and updated
+func importMenuFile(db *gorm.DB, file MenuFile) (created, updated int, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, fileCategory := range file.Categories {
+			if fileCategory.Name == "" {
+				return errors.New("every category needs a name")
+			}
+			var category models.Category
+			isNew, err := lookup(tx.Where("name = ?", fileCategory.Name), &category)
+			if err != nil {
+				return err
+			}
+			if isNew {
+				category = models.Category{LocationID: locationOf(tx), Name: fileCategory.Name}
+			} else if !sameLocation(category.LocationID, locationOf(tx)) {
+				// Category names are unique across locations and the master menu
+				return fmt.Errorf("category %s belongs to another menu", category.Name)
+			}
+			category.DisplayOrder = fileCategory.DisplayOrder
+			if err := save(tx, &category, isNew, &created, &updated); err != nil {
+				return err
+			}
+
+			for _, fileItem := range fileCategory.Items {
+				if fileItem.Name == "" || fileItem.Price < 0 {
+					return fmt.Errorf("item in %s needs a name and a price of zero or more", category.Name)
+				}
+				var item models.MenuItem
+				isNew, err := lookup(tx.Where("category_id = ? AND name = ?", category.ID, fileItem.Name), &item)
+				if err != nil {
+					return err
+				}
+				if isNew {
+					item = models.MenuItem{LocationID: category.LocationID, CategoryID: category.ID, Name: fileItem.Name}
+				}
+				item.Description = fileItem.Description
+				item.Price = fileItem.Price
+				item.ImageURL = fileItem.ImageURL
+				item.IsAvailable = fileItem.IsAvailable == nil || *fileItem.IsAvailable
+				if err := save(tx, &item, isNew, &created, &updated); err != nil {
+					return err
+				}
+				// A new row takes the column default for false, so set it explicitly
+				if isNew && !item.IsAvailable {
+					if err := tx.Model(&item).Update("IsAvailable", false).Error; err != nil {
+						return err
+					}
+				}
+
+				for _, fileAddOn := range fileItem.AddOns {
+					var addOn models.AddOn
+					isNew, err := lookup(tx.Where("menu_item_id = ? AND name = ?", item.ID, fileAddOn.Name), &addOn)
+					if err != nil {
+						return err
+					}
+					if isNew {
+						addOn = models.AddOn{MenuItemID: item.ID, Name: fileAddOn.Name}
+					}
+					addOn.Price = fileAddOn.Price
+					if err := save(tx, &addOn, isNew, &created, &updated); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	})
+	return created, updated, err
+}
+
+// lookup loads the row matching query into dest, reporting whether there is none
+func lookup(query *gorm.DB, dest interface{}) (bool, error) {
+	err := query.First(dest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+func save(tx *gorm.DB, value interface{}, isNew bool, created, updated *int) error {
+	if isNew {
+		*created++
+		return tx.Create(value).Error
+	}
+	*updated++
+	return tx.Save(value).Error
+}
+
+func sameLocation(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
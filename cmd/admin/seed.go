@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var demoMenu = MenuFile{Categories: []MenuFileCategory{
+	{Name: "Rice & Noodles", DisplayOrder: 1, Items: []MenuFileItem{
+		{Name: "Braised Pork Rice", Description: "Slow-braised pork belly over rice", Price: 60,
+			AddOns: []MenuFileAddOn{{Name: "Marinated Egg", Price: 15}, {Name: "Large Portion", Price: 20}}},
+		{Name: "Beef Noodle Soup", Description: "Braised beef shank in a spiced broth", Price: 180,
+			AddOns: []MenuFileAddOn{{Name: "Extra Noodles", Price: 20}, {Name: "Extra Beef", Price: 60}}},
+		{Name: "Fried Rice with Shrimp", Price: 120},
+	}},
+	{Name: "Small Plates", DisplayOrder: 2, Items: []MenuFileItem{
+		{Name: "Stir-fried Greens", Price: 80},
+		{Name: "Oyster Omelette", Price: 90},
+		{Name: "Pan-fried Dumplings", Description: "Ten pork dumplings", Price: 70},
+	}},
+	{Name: "Drinks", DisplayOrder: 3, Items: []MenuFileItem{
+		{Name: "Bubble Milk Tea", Price: 65,
+			AddOns: []MenuFileAddOn{{Name: "Less Sugar", Price: 0}, {Name: "Extra Pearls", Price: 10}}},
+		{Name: "Winter Melon Tea", Price: 40},
+		{Name: "Soy Milk", Price: 35},
+	}},
+}}
+
+var demoHours = models.OpeningHours{WeekSchedule: models.WeekSchedule{
+	Tuesday:   lunchAndDinner,
+	Wednesday: lunchAndDinner,
+	Thursday:  lunchAndDinner,
+	Friday:    lunchAndDinner,
+	Saturday:  models.DaySchedule{Ranges: []models.TimeRange{{Open: "11:00", Close: "21:30"}}},
+	Sunday:    models.DaySchedule{Ranges: []models.TimeRange{{Open: "11:00", Close: "21:30"}}},
+}}
+
+var lunchAndDinner = models.DaySchedule{Ranges: []models.TimeRange{
+	{Open: "11:30", Close: "14:00"},
+	{Open: "17:00", Close: "21:00"},
+}}
+
+// runSeed fills an empty database with a demo menu and, when there is none yet,
+// restaurant info with opening hours
+func runSeed(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	name := fs.String("name", "Demo Restaurant", "restaurant name used when restaurant info is missing")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var categories int64
+	if err := db.Model(&models.Category{}).Where("location_id IS NOT DISTINCT FROM ?", locationOf(db)).Count(&categories).Error; err != nil {
+		return err
+	}
+	if categories > 0 {
+		return errors.New("the menu already has categories; seed only fills an empty database")
+	}
+
+	created, _, err := importMenuFile(db, demoMenu)
+	if err != nil {
+		return fmt.Errorf("failed to seed menu: %w", err)
+	}
+	fmt.Printf("Seeded demo menu with %d rows\n", created)
+
+	var info models.RestaurantInfo
+	err = db.Where("location_id IS NOT DISTINCT FROM ?", locationOf(db)).First(&info).Error
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	info = models.RestaurantInfo{
+		LocationID:   locationOf(db),
+		Name:         *name,
+		OpeningHours: demoHours,
+		TaxSettings:  models.TaxSettings{TaxRate: 0.05},
+	}
+	if err := db.Create(&info).Error; err != nil {
+		return fmt.Errorf("failed to seed restaurant info: %w", err)
+	}
+	fmt.Println("Seeded restaurant info for", info.Name)
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func runUser(db *gorm.DB, args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "create":
+		return createUser(db, args[1:])
+	case "reset":
+		return resetUser(db, args[1:])
+	}
+	return errUsage
+}
+
+// createUser adds a user without going through the API, so the first admin can be made
+func createUser(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("user create", flag.ContinueOnError)
+	username := fs.String("username", "", "login name (required)")
+	name := fs.String("name", "", "display name, defaults to the username")
+	role := fs.String("role", models.RoleAdmin, "role")
+	password := fs.String("password", "", "password, generated when empty")
+	pin := fs.String("pin", "", "approval PIN")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if *name == "" {
+		*name = *username
+	}
+
+	generated := *password == ""
+	if generated {
+		*password = randomPassword()
+	}
+
+	user := models.User{
+		LocationID: locationOf(db),
+		Username:   *username,
+		Name:       *name,
+		Role:       *role,
+	}
+	if err := setSecrets(&user, *password, *pin); err != nil {
+		return err
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	fmt.Printf("Created %s user %s (ID %d)\n", user.Role, user.Username, user.ID)
+	if generated {
+		fmt.Println("Password:", *password)
+	}
+	return nil
+}
+
+// resetUser sets a new password and, optionally, a new PIN for a user locked out of the API
+func resetUser(db *gorm.DB, args []string) error {
+	fs := flag.NewFlagSet("user reset", flag.ContinueOnError)
+	username := fs.String("username", "", "login name (required)")
+	password := fs.String("password", "", "new password, generated when empty")
+	pin := fs.String("pin", "", "new approval PIN")
+	pinOnly := fs.Bool("pin-only", false, "only change the PIN")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	if *pinOnly && *pin == "" {
+		return errors.New("-pin-only needs -pin")
+	}
+
+	var user models.User
+	if err := db.Where("username = ?", *username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user %s not found", *username)
+		}
+		return err
+	}
+
+	generated := !*pinOnly && *password == ""
+	if generated {
+		*password = randomPassword()
+	}
+	if *pinOnly {
+		*password = ""
+	}
+
+	if err := setSecrets(&user, *password, *pin); err != nil {
+		return err
+	}
+	if err := db.Model(&user).Select("Password", "PIN").Updates(&user).Error; err != nil {
+		return fmt.Errorf("failed to reset user: %w", err)
+	}
+
+	fmt.Println("Reset", user.Username)
+	if generated {
+		fmt.Println("Password:", *password)
+	}
+	return nil
+}
+
+// setSecrets hashes the password and PIN into user, leaving empty ones unchanged
+func setSecrets(user *models.User, password, pin string) error {
+	if password != "" {
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("error hashing password: %w", err)
+		}
+		user.Password = string(hashedPassword)
+	}
+	if pin != "" {
+		hashedPIN, err := bcrypt.GenerateFromPassword([]byte(pin), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("error hashing PIN: %w", err)
+		}
+		user.PIN = string(hashedPIN)
+	}
+	return nil
+}
+
+func randomPassword() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -1,78 +1,302 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
-	gormlogger "gorm.io/gorm/logger"
 
 	"github.com/darrenjon/restaurant-ordering-system/internal/config"
 	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/einvoice"
 	"github.com/darrenjon/restaurant-ordering-system/internal/handlers"
+	"github.com/darrenjon/restaurant-ordering-system/internal/kitchen"
 	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/metrics"
+	"github.com/darrenjon/restaurant-ordering-system/internal/middleware"
+	"github.com/darrenjon/restaurant-ordering-system/internal/migrations"
+	"github.com/darrenjon/restaurant-ordering-system/internal/receipt"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/postgres"
+	"github.com/darrenjon/restaurant-ordering-system/internal/server"
+	"github.com/darrenjon/restaurant-ordering-system/internal/stream"
+	"github.com/darrenjon/restaurant-ordering-system/internal/tracing"
 )
 
 func main() {
-	// Load database configuration
-	dbConfig, err := config.LoadDatabaseConfig()
+	// Load configuration from defaults, an optional file, the environment and flags
+	loadConfig := config.BindFlags(flag.CommandLine)
+	flag.Parse()
+	cfg, err := loadConfig()
 	if err != nil {
-		logger.ErrorLogger.Fatalf("Failed to load database config: %v", err)
+		logger.ErrorLogger.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.ErrorLogger.Fatalf("Invalid config:\n%v", err)
+	}
+	logger.SetLevel(cfg.Log.Level)
+
+	// Send spans of handlers and SQL statements to the configured exporter
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to set up tracing: %v", err)
 	}
 
 	// Create database manager
-	dbManager, err := database.NewManager(dbConfig)
+	dbManager, err := database.NewManager(&cfg.Database)
 	if err != nil {
 		logger.ErrorLogger.Fatalf("Failed to create database manager: %v", err)
 	}
 
-	// Run auto migrations
-	if err := dbManager.AutoMigrate(); err != nil {
-		logger.ErrorLogger.Fatalf("Failed to run auto migrations: %v", err)
+	// Refuse to start against a schema this build was not written for
+	migrator, err := migrations.New(dbManager.GetDB())
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to load migrations: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		logger.ErrorLogger.Fatalf("Failed to check database schema: %v", err)
+	}
+
+	// Set the configured log level after initialization
+	dbManager.SetLogMode(logger.GormLevel(cfg.Log.Level))
+
+	// Send kitchen tickets to network printers, or to files when a sink directory is configured
+	var ticketSink kitchen.Sink = kitchen.NetworkSink{Timeout: 5 * time.Second}
+	if cfg.Files.TicketSinkDir != "" {
+		ticketSink = &kitchen.FileSink{Dir: cfg.Files.TicketSinkDir}
 	}
 
-	// Set log mode to Info after initialization
-	dbManager.SetLogMode(gormlogger.Info)
+	// Hand e-invoice documents to the platform through the Turnkey outbox folder
+	invoiceUploader := einvoice.FileUploader{Dir: cfg.Files.EInvoiceDir}
+
+	// Stop on SIGINT or SIGTERM; SIGHUP reloads the TLS certificate
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	reload := make(chan struct{}, 1)
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			select {
+			case reload <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	// Print kitchen tickets in the background, a few printers at a time
+	var background sync.WaitGroup
+	printQueue := kitchen.NewPrintQueue(dbManager, ticketSink, 256)
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		printQueue.Run(4)
+	}()
+
+	// Release scheduled takeout and delivery orders to the kitchen at their lead time
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		kitchen.RunReleaser(ctx, dbManager, printQueue, time.Minute)
+	}()
+
+	// Broker for Server-Sent Event streams such as the waitlist lobby display
+	broker := stream.NewBroker()
+
+	store := postgres.NewStore(dbManager)
 
 	r := mux.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(handlers.NotFound)
+	r.MethodNotAllowedHandler = http.HandlerFunc(handlers.MethodNotAllowed)
+	// Trace and measure every matched route
+	r.Use(middleware.TracingMiddleware)
+	r.Use(middleware.MetricsMiddleware)
+	r.Use(middleware.ClientIPMiddleware)
 
-	r.HandleFunc("/api/auth/login", handlers.Login(dbManager)).Methods("POST")
+	r.HandleFunc("/api/auth/login", handlers.Login(dbManager, cfg.JWT)).Methods("POST")
 	r.HandleFunc("/api/auth/logout", handlers.Logout).Methods("POST")
 
+	// Guests reach feedback through the token in the link they were sent
+	r.HandleFunc("/api/feedback/{token}", handlers.GetFeedbackForm(dbManager)).Methods("GET")
+	r.HandleFunc("/api/feedback/{token}", handlers.SubmitFeedback(dbManager)).Methods("POST")
+	// Lobby displays need not sign in, so the waitlist stream takes a token but does not require one
+	r.Handle("/api/waitlist/stream", middleware.OptionalTenantMiddleware(cfg.JWT)(handlers.WaitlistStream(dbManager, broker))).Methods("GET")
+
+	// Every other API route requires a token and is scoped to the caller's location
+	api := r.NewRoute().Subrouter()
+	api.Use(middleware.TenantMiddleware(cfg.JWT))
+
 	// User routes
-	r.HandleFunc("/api/users", handlers.CreateUser(dbManager)).Methods("POST")
+	api.HandleFunc("/api/users", handlers.CreateUser(store.Users)).Methods("POST")
 	// test auth middleware
-	// r.HandleFunc("/api/users", middleware.AuthMiddleware(dbConfig)(handlers.GetUsers(dbManager))).Methods("GET")
-	r.HandleFunc("/api/users", handlers.GetUsers(dbManager)).Methods("GET")
-	r.HandleFunc("/api/users/{id}", handlers.GetUser(dbManager)).Methods("GET")
-	r.HandleFunc("/api/users/{id}", handlers.UpdateUser(dbManager)).Methods("PUT")
-	r.HandleFunc("/api/users/{id}", handlers.DeleteUser(dbManager)).Methods("DELETE")
+	// api.HandleFunc("/api/users", middleware.AuthMiddleware(cfg.JWT)(handlers.GetUsers(store.Users))).Methods("GET")
+	api.HandleFunc("/api/users", handlers.GetUsers(store.Users)).Methods("GET")
+	api.HandleFunc("/api/users/{id}", handlers.GetUser(store.Users)).Methods("GET")
+	api.HandleFunc("/api/users/{id}", handlers.UpdateUser(store.Users)).Methods("PUT")
+	api.HandleFunc("/api/users/{id}", handlers.DeleteUser(store.Users)).Methods("DELETE")
+
+	// Time clock and staff report routes
+	api.HandleFunc("/api/shifts", handlers.GetShifts(dbManager)).Methods("GET")
+	api.HandleFunc("/api/shifts/clock-in", handlers.ClockIn(dbManager)).Methods("POST")
+	api.HandleFunc("/api/shifts/clock-out", handlers.ClockOut(dbManager)).Methods("POST")
+	api.HandleFunc("/api/shifts/break/start", handlers.StartBreak(dbManager)).Methods("POST")
+	api.HandleFunc("/api/shifts/break/end", handlers.EndBreak(dbManager)).Methods("POST")
+	api.HandleFunc("/api/timesheets", handlers.GetTimesheets(dbManager)).Methods("GET")
+	api.HandleFunc("/api/reports/staff-sales", handlers.GetStaffSales(dbManager)).Methods("GET")
+
+	// Cash drawer routes
+	api.HandleFunc("/api/drawers", handlers.GetDrawerSessions(dbManager)).Methods("GET")
+	api.HandleFunc("/api/drawers", handlers.OpenDrawerSession(dbManager)).Methods("POST")
+	api.HandleFunc("/api/drawers/{id}", handlers.GetDrawerSession(dbManager)).Methods("GET")
+	api.HandleFunc("/api/drawers/{id}/movements", handlers.AddDrawerMovement(dbManager)).Methods("POST")
+	api.HandleFunc("/api/drawers/{id}/close", handlers.CloseDrawerSession(dbManager)).Methods("POST")
+
+	// Customer routes
+	api.HandleFunc("/api/customers", handlers.GetCustomers(dbManager)).Methods("GET")
+	api.HandleFunc("/api/customers", handlers.SignUpCustomer(dbManager)).Methods("POST")
+	api.HandleFunc("/api/customers/{id}", handlers.GetCustomer(dbManager)).Methods("GET")
+	api.HandleFunc("/api/customers/{id}", handlers.UpdateCustomer(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/customers/{id}/orders", handlers.GetCustomerOrders(dbManager)).Methods("GET")
+	api.HandleFunc("/api/customers/{id}/points", handlers.GetCustomerPoints(dbManager)).Methods("GET")
+	api.HandleFunc("/api/customers/{id}/favorites/{menuItemId}", handlers.AddCustomerFavorite(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/customers/{id}/favorites/{menuItemId}", handlers.RemoveCustomerFavorite(dbManager)).Methods("DELETE")
+
+	// Guest rating reports
+	api.HandleFunc("/api/reports/ratings", handlers.GetRatingsReport(dbManager)).Methods("GET")
+
+	// Audit log routes
+	api.HandleFunc("/api/audit-logs", handlers.GetAuditLogs(dbManager)).Methods("GET")
+
+	// Trash routes for soft-deleted records
+	api.HandleFunc("/api/trash/{entity}", handlers.GetTrash(dbManager)).Methods("GET")
+	api.HandleFunc("/api/trash/{entity}/{id}/restore", handlers.RestoreFromTrash(dbManager)).Methods("POST")
+	api.HandleFunc("/api/trash/{entity}/{id}", handlers.PurgeFromTrash(dbManager)).Methods("DELETE")
+
+	// Location routes
+	api.HandleFunc("/api/locations", handlers.GetLocations(dbManager)).Methods("GET")
+	api.HandleFunc("/api/locations", handlers.CreateLocation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/locations/{id}", handlers.UpdateLocation(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/locations/{id}", handlers.DeleteLocation(dbManager)).Methods("DELETE")
+	api.HandleFunc("/api/locations/{id}/prices/{menuItemId}", handlers.SetMenuItemPrice(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/locations/{id}/prices/{menuItemId}", handlers.DeleteMenuItemPrice(dbManager)).Methods("DELETE")
 
 	// Restaurant info routes
-	r.HandleFunc("/api/restaurant-info", handlers.GetRestaurantInfo(dbManager)).Methods("GET")
-	r.HandleFunc("/api/restaurant-info", handlers.UpdateRestaurantInfo(dbManager)).Methods("PUT")
-	r.HandleFunc("/api/restaurant-info/open", handlers.CheckRestaurantOpen(dbManager)).Methods("GET")
+	api.HandleFunc("/api/restaurant-info", handlers.GetRestaurantInfo(store.RestaurantInfo)).Methods("GET")
+	api.HandleFunc("/api/restaurant-info", handlers.UpdateRestaurantInfo(store.RestaurantInfo)).Methods("PUT")
+	api.HandleFunc("/api/restaurant-info/open", handlers.CheckRestaurantOpen(store.RestaurantInfo)).Methods("GET")
+
+	// Table routes
+	api.HandleFunc("/api/tables", handlers.GetTables(dbManager)).Methods("GET")
+	api.HandleFunc("/api/tables", handlers.CreateTable(dbManager)).Methods("POST")
+	api.HandleFunc("/api/tables/{id}", handlers.UpdateTable(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/tables/{id}", handlers.DeleteTable(dbManager)).Methods("DELETE")
+
+	// Reservation routes
+	api.HandleFunc("/api/reservations", handlers.GetReservations(dbManager)).Methods("GET")
+	api.HandleFunc("/api/reservations", handlers.CreateReservation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/reservations/availability", handlers.CheckReservationAvailability(dbManager)).Methods("GET")
+	api.HandleFunc("/api/reservations/{id}", handlers.GetReservation(dbManager)).Methods("GET")
+	api.HandleFunc("/api/reservations/{id}", handlers.UpdateReservation(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/reservations/{id}/cancel", handlers.CancelReservation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/reservations/{id}/seat", handlers.SeatReservation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/reservations/{id}/complete", handlers.CompleteReservation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/reservations/{id}/no-show", handlers.MarkReservationNoShow(dbManager)).Methods("POST")
+
+	// Waitlist routes
+	api.HandleFunc("/api/waitlist", handlers.GetWaitlist(dbManager)).Methods("GET")
+	api.HandleFunc("/api/waitlist", handlers.AddToWaitlist(dbManager, broker)).Methods("POST")
+	api.HandleFunc("/api/waitlist/quote", handlers.QuoteWaitlist(dbManager)).Methods("GET")
+	api.HandleFunc("/api/waitlist/{id}/call", handlers.CallWaitlistEntry(dbManager, broker)).Methods("POST")
+	api.HandleFunc("/api/waitlist/{id}/seat", handlers.SeatWaitlistEntry(dbManager, broker)).Methods("POST")
+	api.HandleFunc("/api/waitlist/{id}", handlers.RemoveWaitlistEntry(dbManager, broker)).Methods("DELETE")
 
 	// Category routes
-	r.HandleFunc("/api/categories", handlers.GetCategories(dbManager)).Methods("GET")
-	r.HandleFunc("/api/categories", handlers.CreateCategory(dbManager)).Methods("POST")
-	r.HandleFunc("/api/categories/{id}", handlers.UpdateCategory(dbManager)).Methods("PUT")
-	r.HandleFunc("/api/categories/{id}", handlers.DeleteCategory(dbManager)).Methods("DELETE")
+	api.HandleFunc("/api/categories", handlers.GetCategories(store.Menu)).Methods("GET")
+	api.HandleFunc("/api/categories", handlers.CreateCategory(store.Menu)).Methods("POST")
+	api.HandleFunc("/api/categories/{id}", handlers.UpdateCategory(store.Menu)).Methods("PUT")
+	api.HandleFunc("/api/categories/{id}", handlers.DeleteCategory(store.Menu)).Methods("DELETE")
 
 	// Menu item routes
-	r.HandleFunc("/api/menu-items", handlers.GetMenuItems(dbManager)).Methods("GET")
-	r.HandleFunc("/api/menu-items/{id}", handlers.GetMenuItem(dbManager)).Methods("GET")
-	r.HandleFunc("/api/menu-items", handlers.CreateMenuItem(dbManager)).Methods("POST")
-	r.HandleFunc("/api/menu-items/{id}", handlers.UpdateMenuItem(dbManager)).Methods("PUT")
-	r.HandleFunc("/api/menu-items/{id}", handlers.DeleteMenuItem(dbManager)).Methods("DELETE")
-
-	// Add a simple health check route
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
-	}).Methods("GET")
-
-	logger.InfoLogger.Println("Starting server on :8080")
-	if err := http.ListenAndServe(":8080", r); err != nil {
-		logger.ErrorLogger.Fatalf("Error starting server: %v", err)
+	api.HandleFunc("/api/menu-items", handlers.GetMenuItems(store.Menu)).Methods("GET")
+	api.HandleFunc("/api/menu-items/{id}", handlers.GetMenuItem(store.Menu)).Methods("GET")
+	api.HandleFunc("/api/menu-items", handlers.CreateMenuItem(store.Menu)).Methods("POST")
+	api.HandleFunc("/api/menu-items/{id}", handlers.UpdateMenuItem(store.Menu)).Methods("PUT")
+	api.HandleFunc("/api/menu-items/{id}", handlers.DeleteMenuItem(store.Menu)).Methods("DELETE")
+
+	// Order routes
+	api.HandleFunc("/api/orders", handlers.GetOrders(store.Orders)).Methods("GET")
+	api.HandleFunc("/api/orders", handlers.CreateOrder(store, printQueue)).Methods("POST")
+	api.HandleFunc("/api/orders/slots", handlers.GetPickupSlots(store.Orders, store.RestaurantInfo)).Methods("GET")
+	api.HandleFunc("/api/orders/{id}", handlers.GetOrder(store.Orders)).Methods("GET")
+	api.HandleFunc("/api/orders/{id}/status", handlers.UpdateOrderStatus(store.Orders)).Methods("PUT")
+	api.HandleFunc("/api/orders/{id}/discounts", handlers.AddOrderDiscount(store.Orders, store.RestaurantInfo)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/payments", handlers.AddPayment(store.Orders)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/points", handlers.RedeemPoints(store.Orders, store.RestaurantInfo)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/voids", handlers.VoidOrderItem(store)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/refunds", handlers.RefundOrder(store, dbManager, invoiceUploader)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/feedback-link", handlers.CreateFeedbackLink(store.Orders)).Methods("POST")
+	api.HandleFunc("/api/orders/{id}/receipt", handlers.GetOrderReceipt(dbManager, receipt.NewLogos())).Methods("GET")
+	api.HandleFunc("/api/orders/{id}/invoice", handlers.IssueInvoice(store, dbManager, invoiceUploader)).Methods("POST")
+
+	// E-invoice routes
+	api.HandleFunc("/api/invoice-ranges", handlers.GetInvoiceRanges(dbManager)).Methods("GET")
+	api.HandleFunc("/api/invoice-ranges", handlers.CreateInvoiceRange(dbManager)).Methods("POST")
+	api.HandleFunc("/api/invoices/retry-uploads", handlers.RetryInvoiceUploads(dbManager, invoiceUploader)).Methods("POST")
+	api.HandleFunc("/api/invoices/{id}", handlers.GetInvoice(dbManager)).Methods("GET")
+	api.HandleFunc("/api/invoices/{id}/void", handlers.VoidInvoice(dbManager, invoiceUploader)).Methods("POST")
+	api.HandleFunc("/api/invoices/{id}/allowances", handlers.CreateInvoiceAllowance(dbManager, invoiceUploader)).Methods("POST")
+	api.HandleFunc("/api/kitchen/orders", handlers.GetKitchenOrders(store.Orders)).Methods("GET")
+
+	// Kitchen station and ticket routes
+	api.HandleFunc("/api/stations", handlers.GetStations(dbManager)).Methods("GET")
+	api.HandleFunc("/api/stations", handlers.CreateStation(dbManager)).Methods("POST")
+	api.HandleFunc("/api/stations/{id}", handlers.UpdateStation(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/stations/{id}", handlers.DeleteStation(dbManager)).Methods("DELETE")
+	api.HandleFunc("/api/tickets", handlers.GetTickets(dbManager)).Methods("GET")
+	api.HandleFunc("/api/tickets/{id}/ready", handlers.MarkTicketReady(dbManager)).Methods("PUT")
+	api.HandleFunc("/api/tickets/{id}/escpos", handlers.GetTicketESCPOS(dbManager)).Methods("GET")
+	api.HandleFunc("/api/tickets/{id}/print", handlers.PrintTicket(dbManager, ticketSink)).Methods("POST")
+
+	// Prometheus metrics, including order gauges read on each scrape
+	metrics.Registry.MustRegister(metrics.NewOrdersCollector(store.Orders))
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	// Liveness and readiness probes; /health is kept for existing checks
+	readiness := handlers.NewReadiness()
+	context.AfterFunc(ctx, readiness.ShuttingDown)
+	r.HandleFunc("/livez", handlers.Livez).Methods("GET")
+	r.HandleFunc("/health", handlers.Livez).Methods("GET")
+	r.HandleFunc("/readyz", handlers.Readyz(readiness, dbManager, migrator)).Methods("GET")
+
+	srv := server.New(cfg.Server, middleware.RequestIDMiddleware(middleware.CORSMiddleware(cfg.CORS)(r)))
+	// Event streams never finish on their own, so end them as shutdown starts
+	srv.RegisterOnShutdown(broker.Close)
+
+	readiness.Ready()
+	err = server.Run(ctx, srv, cfg.Server, reload)
+	stop()
+	if err != nil {
+		logger.ErrorLogger.Printf("Server error: %v", err)
+	}
+
+	// Let a release in progress and the queued tickets finish before the pool closes under them
+	printQueue.Close()
+	background.Wait()
+	if closeErr := dbManager.Close(); closeErr != nil {
+		logger.ErrorLogger.Printf("Failed to close database: %v", closeErr)
+	}
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if flushErr := shutdownTracing(flushCtx); flushErr != nil {
+		logger.ErrorLogger.Printf("Failed to flush traces: %v", flushErr)
+	}
+	cancelFlush()
+	if err != nil {
+		os.Exit(1)
 	}
+	logger.InfoLogger.Println("Server stopped")
 }
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/migrations"
+)
+
+const usage = `Usage: migrate [-dir DIR] COMMAND
+
+Commands:
+  up [N]        apply all pending migrations, or the next N
+  down [N]      revert the last migration, or the last N
+  status        list migrations and when they were applied
+  create NAME   write a placeholder up/down pair to DIR
+
+Database settings come from the DB_* environment variables, a .env file,
+a JSON file given with -config, or flags such as -database.host.
+`
+
+func main() {
+	dir := flag.String("dir", "internal/migrations/sql", "directory new migrations are created in")
+	loadConfig := config.BindFlags(flag.CommandLine)
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// create only touches files, so it works without a database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		paths, err := migrations.Create(*dir, args[1])
+		if err != nil {
+			logger.ErrorLogger.Fatalf("Failed to create migration: %v", err)
+		}
+		for _, path := range paths {
+			fmt.Println("Created", path)
+		}
+		return
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to load config: %v", err)
+	}
+	if err := cfg.Database.Validate(); err != nil {
+		logger.ErrorLogger.Fatalf("Invalid database config:\n%v", err)
+	}
+	dbManager, err := database.NewManager(&cfg.Database)
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to create database manager: %v", err)
+	}
+	migrator, err := migrations.New(dbManager.GetDB())
+	if err != nil {
+		logger.ErrorLogger.Fatalf("Failed to load migrations: %v", err)
+	}
+
+	if err := migrator.Run(args, os.Stdout); err != nil {
+		if errors.Is(err, migrations.ErrUsage) {
+			flag.Usage()
+			os.Exit(2)
+		}
+		logger.ErrorLogger.Fatal(err)
+	}
+}
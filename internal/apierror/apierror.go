@@ -0,0 +1,209 @@
+// Package apierror writes API errors as one JSON envelope,
+//
+//	{"code": "not_found", "message": "Order not found", "details": ..., "request_id": "..."}
+//
+// and turns database and repository errors into the matching status, so SQL
+// never reaches a client.
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+// Error is an error with the status and body to send for it
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Details interface{}
+	// Err is the underlying error, logged but never sent
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Body is the JSON envelope of every error response
+type Body struct {
+	Code      string      `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// Codes for each status; clients match on these rather than on messages
+var codes = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusMethodNotAllowed:    "method_not_allowed",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "unprocessable",
+	http.StatusInternalServerError: "internal_error",
+	http.StatusBadGateway:          "bad_gateway",
+	http.StatusServiceUnavailable:  "unavailable",
+}
+
+// New returns an error with status and message, coded after the status
+func New(status int, message string) *Error {
+	return &Error{Status: status, Code: Code(status), Message: message}
+}
+
+// Wrap returns err as an error with status, using its text as the message.
+// Database errors are mapped with From instead, and a body that cannot be
+// decoded is a plain 400, so neither leaks its text.
+func Wrap(err error, status int) *Error {
+	if isDecodeError(err) {
+		return &Error{Status: http.StatusBadRequest, Code: Code(http.StatusBadRequest), Message: "Invalid request body", Err: err}
+	}
+	if mapped := From(err); mapped.Status != http.StatusInternalServerError || isDatabaseError(err) {
+		return mapped
+	}
+	return &Error{Status: status, Code: Code(status), Message: err.Error(), Err: err}
+}
+
+// Code returns the code sent with status
+func Code(status int) string {
+	if code, ok := codes[status]; ok {
+		return code
+	}
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+// Postgres error codes
+const (
+	pgUniqueViolation     = "23505"
+	pgForeignKeyViolation = "23503"
+	pgNotNullViolation    = "23502"
+	pgCheckViolation      = "23514"
+)
+
+// keyColumns picks the column names out of a Postgres detail such as
+// `Key (name)=(Drinks) already exists.`, leaving the values behind
+var keyColumns = regexp.MustCompile(`^Key \(([^)]*)\)`)
+
+// From maps err to the error sent for it: not found to 404, unique violations
+// to 409, other constraint violations to 422, and anything unrecognised to a
+// 500 whose message reveals nothing
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrNotFound) {
+		return &Error{Status: http.StatusNotFound, Code: "not_found", Message: "Record not found", Err: err}
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		details := constraintDetails(pgErr)
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return &Error{Status: http.StatusConflict, Code: "already_exists", Message: "A record with the same values already exists", Details: details, Err: err}
+		case pgForeignKeyViolation:
+			return &Error{Status: http.StatusUnprocessableEntity, Code: "invalid_reference", Message: "The record refers to, or is still referred to by, another record", Details: details, Err: err}
+		case pgNotNullViolation:
+			return &Error{Status: http.StatusUnprocessableEntity, Code: "missing_value", Message: "A required value is missing", Details: details, Err: err}
+		case pgCheckViolation:
+			return &Error{Status: http.StatusUnprocessableEntity, Code: "invalid_value", Message: "A value is not allowed", Details: details, Err: err}
+		}
+	}
+
+	if errors.Is(err, repository.ErrConflict) {
+		return &Error{Status: http.StatusConflict, Code: "already_exists", Message: "A record with the same values already exists", Err: err}
+	}
+
+	return &Error{Status: http.StatusInternalServerError, Code: "internal_error", Message: "Internal server error", Err: err}
+}
+
+// IsUniqueViolation reports whether err is a unique violation, for handlers that
+// answer a lost race with their own message
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation || errors.Is(err, repository.ErrConflict)
+}
+
+// constraintDetails names the constraint and columns a violation is about, or
+// returns nil, so the details are left out of the response, when it names neither
+func constraintDetails(pgErr *pgconn.PgError) interface{} {
+	details := map[string]interface{}{}
+	if pgErr.ConstraintName != "" {
+		details["constraint"] = pgErr.ConstraintName
+	}
+	if pgErr.ColumnName != "" {
+		details["fields"] = []string{pgErr.ColumnName}
+	} else if m := keyColumns.FindStringSubmatch(pgErr.Detail); m != nil {
+		fields := strings.Split(m[1], ",")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		details["fields"] = fields
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return details
+}
+
+func isDatabaseError(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) || errors.Is(err, gorm.ErrRecordNotFound) ||
+		errors.Is(err, gorm.ErrInvalidTransaction) || errors.Is(err, gorm.ErrInvalidData)
+}
+
+// isDecodeError reports whether err came from decoding a JSON request body
+func isDecodeError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		strings.HasPrefix(err.Error(), "json: ")
+}
+
+// Write sends err as the JSON envelope. Errors that map to a 5xx are logged
+// with the request, as their cause is not in the response, and so are bodies
+// that could not be decoded.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	apiErr := From(err)
+	if apiErr.Status >= http.StatusInternalServerError {
+		logger.Logger.ErrorContext(r.Context(), "request failed", "status", apiErr.Status, "error", err)
+	} else if apiErr.Err != nil && isDecodeError(apiErr.Err) {
+		logger.Logger.WarnContext(r.Context(), "invalid request body", "error", apiErr.Err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(Body{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Details:   apiErr.Details,
+		RequestID: logger.RequestID(r.Context()),
+	})
+}
+
+// Respond sends message with status, in place of http.Error
+func Respond(w http.ResponseWriter, r *http.Request, message string, status int) {
+	Write(w, r, New(status, message))
+}
@@ -0,0 +1,149 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+func TestFrom(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		details interface{}
+	}{
+		{"api error", New(http.StatusForbidden, "No"), http.StatusForbidden, "forbidden", nil},
+		{"wrapped api error", fmt.Errorf("saving: %w", New(http.StatusConflict, "Taken")), http.StatusConflict, "conflict", nil},
+		{"gorm not found", gorm.ErrRecordNotFound, http.StatusNotFound, "not_found", nil},
+		{"repository not found", repository.ErrNotFound, http.StatusNotFound, "not_found", nil},
+		{"repository conflict", repository.ErrConflict, http.StatusConflict, "already_exists", nil},
+		{
+			"unique violation",
+			&pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "idx_categories_name_active", Detail: "Key (name)=(Drinks) already exists."},
+			http.StatusConflict, "already_exists",
+			map[string]interface{}{"constraint": "idx_categories_name_active", "fields": []string{"name"}},
+		},
+		{
+			"foreign key violation",
+			&pgconn.PgError{Code: pgForeignKeyViolation, Detail: "Key (category_id, location_id)=(1, 2) is not present."},
+			http.StatusUnprocessableEntity, "invalid_reference",
+			map[string]interface{}{"fields": []string{"category_id", "location_id"}},
+		},
+		{
+			"not null violation",
+			&pgconn.PgError{Code: pgNotNullViolation, ColumnName: "name"},
+			http.StatusUnprocessableEntity, "missing_value",
+			map[string]interface{}{"fields": []string{"name"}},
+		},
+		{"check violation", &pgconn.PgError{Code: pgCheckViolation}, http.StatusUnprocessableEntity, "invalid_value", nil},
+		{"other database error", &pgconn.PgError{Code: "40001"}, http.StatusInternalServerError, "internal_error", nil},
+		{"unknown error", errors.New("disk on fire"), http.StatusInternalServerError, "internal_error", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := From(tt.err)
+			if got.Status != tt.status || got.Code != tt.code {
+				t.Errorf("From = %d %q, want %d %q", got.Status, got.Code, tt.status, tt.code)
+			}
+			if fmt.Sprint(got.Details) != fmt.Sprint(tt.details) {
+				t.Errorf("details = %v, want %v", got.Details, tt.details)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	var order struct {
+		Quantity int `json:"quantity"`
+	}
+	decode := func(body string) error {
+		return json.NewDecoder(strings.NewReader(body)).Decode(&order)
+	}
+
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"validation error", errors.New("quantity must be positive"), http.StatusBadRequest, "quantity must be positive"},
+		{"empty body", decode(""), http.StatusBadRequest, "Invalid request body"},
+		{"truncated body", decode(`{"quantity": `), http.StatusBadRequest, "Invalid request body"},
+		{"malformed body", decode(`{quantity}`), http.StatusBadRequest, "Invalid request body"},
+		{"wrong type", decode(`{"quantity": "two"}`), http.StatusBadRequest, "Invalid request body"},
+		{"database error", &pgconn.PgError{Code: "40001", Message: "could not serialize access"}, http.StatusInternalServerError, "Internal server error"},
+		{"unique violation", &pgconn.PgError{Code: pgUniqueViolation}, http.StatusConflict, "A record with the same values already exists"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Wrap(tt.err, http.StatusBadRequest)
+			if got.Status != tt.status || got.Message != tt.message {
+				t.Errorf("Wrap = %d %q, want %d %q", got.Status, got.Message, tt.status, tt.message)
+			}
+			if !errors.Is(got, tt.err) {
+				t.Errorf("Wrap(%v) does not unwrap to the error", tt.err)
+			}
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&pgconn.PgError{Code: pgUniqueViolation}, true},
+		{fmt.Errorf("creating: %w", &pgconn.PgError{Code: pgUniqueViolation}), true},
+		{repository.ErrConflict, true},
+		{&pgconn.PgError{Code: pgForeignKeyViolation}, false},
+		{errors.New("duplicate"), false},
+	}
+	for _, tt := range tests {
+		if got := IsUniqueViolation(tt.err); got != tt.want {
+			t.Errorf("IsUniqueViolation(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		body   Body
+	}{
+		{"message", New(http.StatusNotFound, "Order not found"), http.StatusNotFound, Body{Code: "not_found", Message: "Order not found"}},
+		{"database error hidden", errors.New(`pq: relation "orders" does not exist`), http.StatusInternalServerError, Body{Code: "internal_error", Message: "Internal server error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			Write(w, httptest.NewRequest(http.MethodGet, "/", nil), tt.err)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			var body Body
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body != tt.body {
+				t.Errorf("body = %+v, want %+v", body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package auth
+
+import "github.com/golang-jwt/jwt/v5"
+
+// Claims are the JWT claims issued at login
+type Claims struct {
+	// LocationID is empty for head office staff, who are not limited to a single location
+	LocationID *uint  `json:"location_id,omitempty"`
+	Role       string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
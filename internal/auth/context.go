@@ -1,9 +1,50 @@
 package auth
 
+import "context"
+
 // ContextKey is a custom type for context keys
 type ContextKey string
 
 // Define constants for context keys
 const (
-	ContextUsername ContextKey = "username"
+	ContextUsername   ContextKey = "username"
+	ContextLocationID ContextKey = "location_id"
+	ContextClientIP   ContextKey = "client_ip"
 )
+
+// WithLocationID returns a copy of ctx scoped to the given location
+func WithLocationID(ctx context.Context, locationID uint) context.Context {
+	return context.WithValue(ctx, ContextLocationID, locationID)
+}
+
+// LocationIDFromContext returns the caller's location, if the request is scoped to one
+func LocationIDFromContext(ctx context.Context) (uint, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	locationID, ok := ctx.Value(ContextLocationID).(uint)
+	return locationID, ok
+}
+
+// WithClientIP returns a copy of ctx carrying the address of the client making the request
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ContextClientIP, ip)
+}
+
+// ClientIPFromContext returns the address of the client making the request
+func ClientIPFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	ip, _ := ctx.Value(ContextClientIP).(string)
+	return ip
+}
+
+// UsernameFromContext returns the username from the request's token
+func UsernameFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	username, _ := ctx.Value(ContextUsername).(string)
+	return username
+}
@@ -0,0 +1,215 @@
+// Package config loads settings in layers: built-in defaults, then an optional
+// JSON file, then environment variables (a .env file is read when present),
+// then command-line flags. Each setting's file key, variable and flag are
+// declared once in the struct tags below.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	Server   ServerConfig   `json:"server"`
+	Database DatabaseConfig `json:"database"`
+	JWT      JWTConfig      `json:"jwt"`
+	CORS     CORSConfig     `json:"cors"`
+	Log      LogConfig      `json:"log"`
+	Tracing  TracingConfig  `json:"tracing"`
+	Files    FilesConfig    `json:"files"`
+}
+
+type ServerConfig struct {
+	Port              int           `json:"port" env:"SERVER_PORT" usage:"port the API listens on"`
+	ReadHeaderTimeout time.Duration `json:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT" usage:"time allowed to read request headers"`
+	ReadTimeout       time.Duration `json:"read_timeout" env:"SERVER_READ_TIMEOUT" usage:"time allowed to read a whole request"`
+	// WriteTimeout is off by default so Server-Sent Event streams stay open
+	WriteTimeout time.Duration `json:"write_timeout" env:"SERVER_WRITE_TIMEOUT" usage:"time allowed to write a response, 0 for none"`
+	IdleTimeout  time.Duration `json:"idle_timeout" env:"SERVER_IDLE_TIMEOUT" usage:"how long idle keep-alive connections are kept"`
+	// ShutdownDelay keeps serving after SIGTERM while /readyz reports 503, so
+	// load balancers stop sending traffic before connections are drained
+	ShutdownDelay time.Duration `json:"shutdown_delay" env:"SERVER_SHUTDOWN_DELAY" usage:"how long to keep serving, unready, before shutting down"`
+	// ShutdownTimeout bounds how long in-flight requests may finish after SIGTERM
+	ShutdownTimeout time.Duration `json:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT" usage:"how long in-flight requests may finish on shutdown"`
+	// TLSCertFile and TLSKeyFile switch the server to HTTPS. The pair is
+	// reloaded when the files change or on SIGHUP, so renewals need no restart.
+	TLSCertFile string `json:"tls_cert_file" env:"SERVER_TLS_CERT_FILE" usage:"PEM certificate chain, enables HTTPS"`
+	TLSKeyFile  string `json:"tls_key_file" env:"SERVER_TLS_KEY_FILE" usage:"PEM private key for the certificate"`
+}
+
+type JWTConfig struct {
+	Secret string        `json:"secret" env:"JWT_SECRET" usage:"key signing login tokens"`
+	TTL    time.Duration `json:"ttl" env:"JWT_TTL" usage:"how long a login token is valid"`
+}
+
+// CORSConfig lists the browser origins allowed to call the API. CORS headers
+// are only sent when AllowedOrigins is set.
+type CORSConfig struct {
+	AllowedOrigins   []string      `json:"allowed_origins" env:"CORS_ALLOWED_ORIGINS" usage:"comma-separated origins, or * for any"`
+	AllowedMethods   []string      `json:"allowed_methods" env:"CORS_ALLOWED_METHODS" usage:"comma-separated methods"`
+	AllowedHeaders   []string      `json:"allowed_headers" env:"CORS_ALLOWED_HEADERS" usage:"comma-separated request headers"`
+	AllowCredentials bool          `json:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS" usage:"allow cookies and authorization headers"`
+	MaxAge           time.Duration `json:"max_age" env:"CORS_MAX_AGE" usage:"how long browsers cache a preflight response"`
+}
+
+type LogConfig struct {
+	Level string `json:"level" env:"LOG_LEVEL" usage:"debug, info, warn or error"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are sent. With the otlp
+// exporter, the standard OTEL_EXPORTER_OTLP_* variables are honoured too.
+type TracingConfig struct {
+	Exporter    string  `json:"exporter" env:"TRACING_EXPORTER" usage:"none, otlp or stdout"`
+	Endpoint    string  `json:"endpoint" env:"TRACING_OTLP_ENDPOINT" usage:"OTLP/HTTP collector host:port, localhost:4318 if empty"`
+	Insecure    bool    `json:"insecure" env:"TRACING_OTLP_INSECURE" usage:"send OTLP over plain HTTP"`
+	ServiceName string  `json:"service_name" env:"TRACING_SERVICE_NAME" usage:"service name attached to spans"`
+	SampleRatio float64 `json:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"fraction of new traces recorded, from 0 to 1"`
+}
+
+type FilesConfig struct {
+	// TicketSinkDir, when set, writes kitchen tickets to files instead of network printers
+	TicketSinkDir string `json:"ticket_sink_dir" env:"TICKET_SINK_DIR" usage:"write kitchen tickets to this directory instead of printers"`
+	// EInvoiceDir is where MIG documents are written for the e-invoice platform
+	EInvoiceDir string `json:"einvoice_dir" env:"EINVOICE_DIR" usage:"directory e-invoice documents are written to"`
+}
+
+// Tracing exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Log levels
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Defaults returns the settings used when nothing overrides them
+func Defaults() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownDelay:     5 * time.Second,
+			ShutdownTimeout:   30 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:            "localhost",
+			Port:            "5432",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		JWT: JWTConfig{TTL: 24 * time.Hour},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Authorization", "Content-Type"},
+			MaxAge:         10 * time.Minute,
+		},
+		Log: LogConfig{Level: LevelInfo},
+		Tracing: TracingConfig{
+			Exporter:    ExporterNone,
+			ServiceName: "restaurant-api",
+			SampleRatio: 1,
+		},
+		Files: FilesConfig{EInvoiceDir: "einvoice"},
+	}
+}
+
+// Validate checks every setting the API server needs, reporting all problems at once
+func (c Config) Validate() error {
+	errs := []error{c.Database.Validate()}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, invalid("server.port", "must be between 1 and 65535"))
+	}
+	if c.Server.ReadHeaderTimeout < 0 || c.Server.ReadTimeout < 0 || c.Server.WriteTimeout < 0 || c.Server.IdleTimeout < 0 {
+		errs = append(errs, invalid("server.read_timeout", "timeouts cannot be negative"))
+	}
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, invalid("server.shutdown_delay", "cannot be negative"))
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		errs = append(errs, invalid("server.shutdown_timeout", "must be positive"))
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		errs = append(errs, invalid("server.tls_cert_file", "and server.tls_key_file must be set together"))
+	}
+
+	if c.JWT.Secret == "" {
+		errs = append(errs, missing("jwt.secret"))
+	}
+	if c.JWT.TTL <= 0 {
+		errs = append(errs, invalid("jwt.ttl", "must be positive"))
+	}
+
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				errs = append(errs, invalid("cors.allowed_origins", "* cannot be combined with cors.allow_credentials"))
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+			errs = append(errs, invalid("cors.allowed_origins", fmt.Sprintf("%q is not an origin such as https://example.com", origin)))
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		errs = append(errs, invalid("cors.max_age", "cannot be negative"))
+	}
+
+	switch c.Log.Level {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+	default:
+		errs = append(errs, invalid("log.level", fmt.Sprintf("%q is not debug, info, warn or error", c.Log.Level)))
+	}
+
+	switch c.Tracing.Exporter {
+	case ExporterNone, ExporterOTLP, ExporterStdout:
+	default:
+		errs = append(errs, invalid("tracing.exporter", fmt.Sprintf("%q is not none, otlp or stdout", c.Tracing.Exporter)))
+	}
+	if c.Tracing.Exporter != ExporterNone && c.Tracing.ServiceName == "" {
+		errs = append(errs, missing("tracing.service_name"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, invalid("tracing.sample_ratio", "must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// missing reports a required setting that was not given
+func missing(key string) error {
+	return fmt.Errorf("%s must be set (%s)", key, describe(key))
+}
+
+// invalid reports a setting with an unusable value
+func invalid(key, reason string) error {
+	return fmt.Errorf("%s %s (%s)", key, reason, describe(key))
+}
+
+// describe names the variable and flag a setting can be given with
+func describe(key string) string {
+	for _, s := range settings(&Config{}) {
+		if s.key == key {
+			return fmt.Sprintf("env %s or flag -%s", s.env, s.flag)
+		}
+	}
+	return "key " + key
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
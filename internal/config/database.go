@@ -1,35 +1,56 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"os"
-
-	"github.com/joho/godotenv"
+	"time"
 )
 
 type DatabaseConfig struct {
-	Host      string
-	Port      string
-	User      string
-	Password  string
-	DBName    string
-	SSLMode   string
-	JWTSecret string
+	Host     string `json:"host" env:"DB_HOST" usage:"database host"`
+	Port     string `json:"port" env:"DB_PORT" usage:"database port"`
+	User     string `json:"user" env:"DB_USER" usage:"database user"`
+	Password string `json:"password" env:"DB_PASSWORD" usage:"database password"`
+	DBName   string `json:"name" env:"DB_NAME" usage:"database name"`
+	SSLMode  string `json:"sslmode" env:"DB_SSLMODE" usage:"Postgres sslmode"`
+
+	// MaxOpenConns caps the connections in the pool, 0 means unlimited
+	MaxOpenConns    int           `json:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"maximum open connections, 0 for unlimited"`
+	MaxIdleConns    int           `json:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" usage:"maximum idle connections"`
+	ConnMaxLifetime time.Duration `json:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"how long a connection is reused, 0 for ever"`
+	ConnMaxIdleTime time.Duration `json:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" usage:"how long a connection may sit idle, 0 for ever"`
 }
 
-func LoadDatabaseConfig() (*DatabaseConfig, error) {
-	err := godotenv.Load()
-	if err != nil {
-		return nil, fmt.Errorf("error loading .env file: %w", err)
-	}
+var sslModes = map[string]bool{
+	"disable": true, "allow": true, "prefer": true, "require": true, "verify-ca": true, "verify-full": true,
+}
 
-	return &DatabaseConfig{
-		Host:      os.Getenv("DB_HOST"),
-		Port:      os.Getenv("DB_PORT"),
-		User:      os.Getenv("DB_USER"),
-		Password:  os.Getenv("DB_PASSWORD"),
-		DBName:    os.Getenv("DB_NAME"),
-		SSLMode:   os.Getenv("DB_SSLMODE"),
-		JWTSecret: os.Getenv("JWT_SECRET"),
-	}, nil
+// Validate checks the connection settings, which is all the command-line tools need
+func (c DatabaseConfig) Validate() error {
+	var errs []error
+	if c.Host == "" {
+		errs = append(errs, missing("database.host"))
+	}
+	if c.User == "" {
+		errs = append(errs, missing("database.user"))
+	}
+	if c.DBName == "" {
+		errs = append(errs, missing("database.name"))
+	}
+	if !validPort(c.Port) {
+		errs = append(errs, invalid("database.port", "must be a port number between 1 and 65535"))
+	}
+	if !sslModes[c.SSLMode] {
+		errs = append(errs, invalid("database.sslmode", fmt.Sprintf("%q is not a Postgres sslmode", c.SSLMode)))
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		errs = append(errs, invalid("database.max_open_conns", "pool sizes cannot be negative"))
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		errs = append(errs, invalid("database.max_idle_conns", "cannot exceed database.max_open_conns"))
+	}
+	if c.ConnMaxLifetime < 0 || c.ConnMaxIdleTime < 0 {
+		errs = append(errs, invalid("database.conn_max_lifetime", "durations cannot be negative"))
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// setting is one leaf of Config, addressed by its file key, variable and flag
+type setting struct {
+	key   string // e.g. database.max_open_conns
+	env   string // e.g. DB_MAX_OPEN_CONNS
+	flag  string // e.g. database.max-open-conns
+	usage string
+	value reflect.Value
+}
+
+// settings lists the leaves of cfg so they can be set in place
+func settings(cfg *Config) []setting {
+	var out []setting
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i).Tag.Get("json")
+		group := root.Field(i)
+		for j := 0; j < group.NumField(); j++ {
+			field := group.Type().Field(j)
+			key := section + "." + field.Tag.Get("json")
+			out = append(out, setting{
+				key:   key,
+				env:   field.Tag.Get("env"),
+				flag:  strings.ReplaceAll(key, "_", "-"),
+				usage: field.Tag.Get("usage"),
+				value: group.Field(j),
+			})
+		}
+	}
+	return out
+}
+
+// set parses raw into the setting's type
+func (s setting) set(raw string) error {
+	switch v := s.value.Addr().Interface().(type) {
+	case *string:
+		*v = raw
+	case *int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a whole number", s.key, raw)
+		}
+		*v = n
+	case *float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a number", s.key, raw)
+		}
+		*v = f
+	case *bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not true or false", s.key, raw)
+		}
+		*v = b
+	case *time.Duration:
+		d, err := time.ParseDuration(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration such as 30s or 5m", s.key, raw)
+		}
+		*v = d
+	case *[]string:
+		*v = nil
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				*v = append(*v, part)
+			}
+		}
+	default:
+		return fmt.Errorf("%s: unsupported setting type %s", s.key, s.value.Type())
+	}
+	return nil
+}
+
+// BindFlags registers -config and a flag for every setting on fs. After
+// fs.Parse, the returned function loads the configuration with flags that
+// were given taking precedence over the environment and the file.
+func BindFlags(fs *flag.FlagSet) func() (*Config, error) {
+	file := fs.String("config", "", "JSON configuration file (or env CONFIG_FILE)")
+	values := make(map[string]*string)
+	for _, s := range settings(&Config{}) {
+		values[s.flag] = fs.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+
+	return func() (*Config, error) {
+		given := make(map[string]string)
+		fs.Visit(func(f *flag.Flag) {
+			if value, ok := values[f.Name]; ok {
+				given[f.Name] = *value
+			}
+		})
+		return load(*file, given)
+	}
+}
+
+// Load builds the configuration from defaults, the file named by CONFIG_FILE
+// and the environment, without looking at command-line flags
+func Load() (*Config, error) {
+	return load("", nil)
+}
+
+func load(file string, flags map[string]string) (*Config, error) {
+	// A .env file is a convenience for development; deployments set the environment directly
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+	if file == "" {
+		file = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg := Defaults()
+	all := settings(&cfg)
+
+	if file != "" {
+		values, err := readFile(file)
+		if err != nil {
+			return nil, err
+		}
+		known := make(map[string]bool)
+		for _, s := range all {
+			raw, ok := values[s.key]
+			if !ok {
+				continue
+			}
+			known[s.key] = true
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+		for key := range values {
+			if !known[key] {
+				return nil, fmt.Errorf("%s: unknown setting %s", file, key)
+			}
+		}
+	}
+
+	for _, s := range all {
+		if raw, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("env %s: %w", s.env, err)
+			}
+		}
+	}
+
+	for _, s := range all {
+		if raw, ok := flags[s.flag]; ok {
+			if err := s.set(raw); err != nil {
+				return nil, fmt.Errorf("flag -%s: %w", s.flag, err)
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// readFile flattens a JSON configuration file into dotted keys with the
+// values in the same text form the environment uses
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var sections map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	for section, fields := range sections {
+		for name, raw := range fields {
+			key := section + "." + name
+			var text string
+			var list []string
+			switch {
+			case json.Unmarshal(raw, &text) == nil:
+				values[key] = text
+			case json.Unmarshal(raw, &list) == nil:
+				values[key] = strings.Join(list, ",")
+			default:
+				// Numbers and booleans are parsed from their JSON text
+				values[key] = string(raw)
+			}
+		}
+	}
+	return values, nil
+}
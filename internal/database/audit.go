@@ -0,0 +1,251 @@
+package database
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+const (
+	auditTable       = "audit_logs"
+	auditBeforeKey   = "audit:before"
+	auditSystemActor = "system"
+)
+
+// auditRedacted columns are never copied into the audit log
+var auditRedacted = map[string]bool{"password": true, "pin": true, "feedback_token": true, "customer_phone": true}
+
+// auditRedactedByTable are guests' contact details, which share their column names
+// with the restaurant's own phone and email
+var auditRedactedByTable = map[string]map[string]bool{
+	"customers":        {"phone": true, "email": true},
+	"reservations":     {"phone": true},
+	"waitlist_entries": {"phone": true},
+}
+
+// auditIgnored columns change on every write and are left out of the diff
+var auditIgnored = map[string]bool{"updated_at": true}
+
+// registerAuditCallbacks writes an audit log entry for every row created,
+// updated or deleted through a model, in the same transaction as the write
+func registerAuditCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Create().After("gorm:create").Register("audit:create", auditCreate); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").After("tenant:update").Register("audit:before_update", auditCaptureBefore); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("audit:update", auditAfter(models.AuditUpdate)); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").After("tenant:delete").Register("audit:before_delete", auditCaptureBefore); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("audit:delete", auditAfter(models.AuditDelete))
+}
+
+func audited(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil &&
+		db.Statement.Schema.PrioritizedPrimaryField != nil && db.Statement.Table != auditTable
+}
+
+func auditCreate(db *gorm.DB) {
+	if !audited(db) || db.RowsAffected == 0 {
+		return
+	}
+
+	var ids []interface{}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	collect := func(rv reflect.Value) {
+		if id, zero := field.ValueOf(db.Statement.Context, rv); !zero {
+			ids = append(ids, id)
+		}
+	}
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			collect(reflect.Indirect(db.Statement.ReflectValue.Index(i)))
+		}
+	case reflect.Struct:
+		collect(db.Statement.ReflectValue)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	rows, err := auditRows(auditSession(db).Where(clause.IN{Column: clause.Column{Name: field.DBName}, Values: ids}))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	for _, row := range rows {
+		writeAudit(db, models.AuditCreate, row[field.DBName], nil, row)
+	}
+}
+
+// auditCaptureBefore loads the rows an update or delete is about to change
+func auditCaptureBefore(db *gorm.DB) {
+	if !audited(db) {
+		return
+	}
+
+	query := auditSession(db)
+	hasConditions := false
+	if c, ok := db.Statement.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			query.Statement.AddClause(where)
+			hasConditions = true
+		}
+	}
+	field := db.Statement.Schema.PrioritizedPrimaryField
+	if db.Statement.ReflectValue.Kind() == reflect.Struct {
+		if id, zero := field.ValueOf(db.Statement.Context, db.Statement.ReflectValue); !zero {
+			query = query.Where(clause.Eq{Column: clause.Column{Name: field.DBName}, Value: id})
+			hasConditions = true
+		}
+	}
+	if !hasConditions {
+		return
+	}
+
+	// Every row is captured, however many a bulk write touches, so none go unaudited
+	rows, err := auditRows(query)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.Statement.Settings.Store(auditBeforeKey, rows)
+}
+
+func auditAfter(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if !audited(db) || db.RowsAffected == 0 {
+			return
+		}
+		value, ok := db.Statement.Settings.LoadAndDelete(auditBeforeKey)
+		if !ok {
+			return
+		}
+		before := value.([]models.AuditData)
+		if len(before) == 0 {
+			return
+		}
+
+		field := db.Statement.Schema.PrioritizedPrimaryField
+		after := make(map[string]models.AuditData)
+		if action == models.AuditUpdate {
+			ids := make([]interface{}, len(before))
+			for i, row := range before {
+				ids[i] = row[field.DBName]
+			}
+			rows, err := auditRows(auditSession(db).Where(clause.IN{Column: clause.Column{Name: field.DBName}, Values: ids}))
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+			for _, row := range rows {
+				after[fmt.Sprint(row[field.DBName])] = row
+			}
+		}
+
+		for _, row := range before {
+			id := row[field.DBName]
+			newRow := after[fmt.Sprint(id)]
+			if action == models.AuditUpdate && len(auditDiff(row, newRow)) == 0 {
+				continue
+			}
+			writeAudit(db, action, id, row, newRow)
+		}
+	}
+}
+
+func writeAudit(db *gorm.DB, action string, id interface{}, before, after models.AuditData) {
+	ctx := db.Statement.Context
+	actor := auth.UsernameFromContext(ctx)
+	if actor == "" {
+		actor = auditSystemActor
+	}
+
+	entry := models.AuditLog{
+		Actor:    actor,
+		Action:   action,
+		Entity:   db.Statement.Table,
+		EntityID: fmt.Sprint(id),
+		IP:       auth.ClientIPFromContext(ctx),
+	}
+	if action == models.AuditUpdate {
+		entry.Changes = redact(entry.Entity, auditDiff(before, after))
+	}
+	entry.Before = redact(entry.Entity, before)
+	entry.After = redact(entry.Entity, after)
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&entry).Error; err != nil {
+		db.AddError(fmt.Errorf("failed to write audit log: %w", err))
+	}
+}
+
+// auditSession starts a query on the written model in the same transaction as the write
+func auditSession(db *gorm.DB) *gorm.DB {
+	query := db.Session(&gorm.Session{NewDB: true}).Model(reflect.New(db.Statement.Schema.ModelType).Interface())
+	if db.Statement.Unscoped {
+		query = query.Unscoped()
+	}
+	return query
+}
+
+// auditRows loads rows as column maps
+func auditRows(query *gorm.DB) ([]models.AuditData, error) {
+	var rows []map[string]interface{}
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	data := make([]models.AuditData, len(rows))
+	for i, row := range rows {
+		for column, value := range row {
+			switch v := value.(type) {
+			case []byte:
+				row[column] = string(v)
+			case time.Time:
+				row[column] = v.Format(time.RFC3339Nano)
+			}
+		}
+		data[i] = row
+	}
+	return data, nil
+}
+
+// redact hides secrets and guests' contact details while still showing that they changed
+func redact(table string, data models.AuditData) models.AuditData {
+	for column, value := range data {
+		if !auditRedacted[column] && !auditRedactedByTable[table][column] {
+			continue
+		}
+		if _, ok := value.(map[string]interface{}); ok {
+			data[column] = map[string]interface{}{"from": "[redacted]", "to": "[redacted]"}
+		} else {
+			data[column] = "[redacted]"
+		}
+	}
+	return data
+}
+
+// auditDiff returns the columns that differ between two versions of a row
+func auditDiff(before, after models.AuditData) models.AuditData {
+	changes := models.AuditData{}
+	for column, newValue := range after {
+		if auditIgnored[column] {
+			continue
+		}
+		oldValue := before[column]
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[column] = map[string]interface{}{"from": oldValue, "to": newValue}
+		}
+	}
+	return changes
+}
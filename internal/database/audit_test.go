@@ -0,0 +1,73 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		data  models.AuditData
+		want  models.AuditData
+	}{
+		{
+			name:  "user secrets",
+			table: "users",
+			data:  models.AuditData{"username": "amy", "password": "hash", "pin": "hash"},
+			want:  models.AuditData{"username": "amy", "password": "[redacted]", "pin": "[redacted]"},
+		},
+		{
+			name:  "order feedback token and customer phone",
+			table: "orders",
+			data:  models.AuditData{"customer_name": "Amy", "customer_phone": "0912345678", "feedback_token": "secret"},
+			want:  models.AuditData{"customer_name": "Amy", "customer_phone": "[redacted]", "feedback_token": "[redacted]"},
+		},
+		{
+			name:  "customer contact details",
+			table: "customers",
+			data:  models.AuditData{"name": "Amy", "phone": "0912345678", "email": "amy@example.com"},
+			want:  models.AuditData{"name": "Amy", "phone": "[redacted]", "email": "[redacted]"},
+		},
+		{
+			name:  "reservation phone",
+			table: "reservations",
+			data:  models.AuditData{"name": "Amy", "phone": "0912345678"},
+			want:  models.AuditData{"name": "Amy", "phone": "[redacted]"},
+		},
+		{
+			name:  "waitlist phone",
+			table: "waitlist_entries",
+			data:  models.AuditData{"name": "Amy", "phone": "0912345678"},
+			want:  models.AuditData{"name": "Amy", "phone": "[redacted]"},
+		},
+		{
+			name:  "restaurant contact details are kept",
+			table: "restaurant_infos",
+			data:  models.AuditData{"phone": "02-1234-5678", "email": "hello@example.com"},
+			want:  models.AuditData{"phone": "02-1234-5678", "email": "hello@example.com"},
+		},
+		{
+			name:  "changes",
+			table: "customers",
+			data:  models.AuditData{"email": map[string]interface{}{"from": "a@example.com", "to": "b@example.com"}},
+			want:  models.AuditData{"email": map[string]interface{}{"from": "[redacted]", "to": "[redacted]"}},
+		},
+		{
+			name:  "no data",
+			table: "customers",
+			data:  nil,
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redact(tt.table, tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("redact(%q) = %v, want %v", tt.table, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// OpenDrawerFor returns the user's open drawer session
+func OpenDrawerFor(tx *gorm.DB, userID uint) (models.DrawerSession, error) {
+	var session models.DrawerSession
+	err := tx.Where("user_id = ? AND closed_at IS NULL", userID).First(&session).Error
+	return session, err
+}
+
+// CashDrawerID returns the open drawer of the signed-in cashier that cash
+// payments and refunds should be counted in, or nil when they have none. The
+// session is locked so it cannot be closed while the payment is recorded.
+func CashDrawerID(tx *gorm.DB) *uint {
+	username := auth.UsernameFromContext(tx.Statement.Context)
+	if username == "" {
+		return nil
+	}
+	var user models.User
+	if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+		return nil
+	}
+	session, err := OpenDrawerFor(tx.Clauses(clause.Locking{Strength: "UPDATE"}), user.ID)
+	if err != nil {
+		return nil
+	}
+	return &session.ID
+}
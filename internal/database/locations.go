@@ -0,0 +1,22 @@
+package database
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// RestaurantInfoFor returns the restaurant info of a location, or of the whole business when locationID is nil
+func RestaurantInfoFor(tx *gorm.DB, locationID *uint) (models.RestaurantInfo, error) {
+	var info models.RestaurantInfo
+	err := AtLocation(tx, locationID).Order("updated_at desc").First(&info).Error
+	return info, err
+}
+
+// AtLocation limits a query to the rows of one location, or to those of the whole business when locationID is nil
+func AtLocation(tx *gorm.DB, locationID *uint) *gorm.DB {
+	if locationID != nil {
+		return tx.Where("location_id = ?", *locationID)
+	}
+	return tx.Where("location_id IS NULL")
+}
@@ -1,6 +1,7 @@
 package database
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -26,13 +27,32 @@ func NewManager(cfg *config.DatabaseConfig) (*Manager, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
 		Logger: logger.GetGormLogger(gormlogger.Silent),
 		NowFunc: func() time.Time {
-			return time.Now().In(time.FixedZone("Asia/Taipei", 8*60*60))
+			return time.Now().In(models.RestaurantZone)
 		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection pool: %w", err)
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+
+	if err := registerTenantCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register tenant callbacks: %w", err)
+	}
+	if err := registerAuditCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register audit callbacks: %w", err)
+	}
+	if err := registerTracingCallbacks(db); err != nil {
+		return nil, fmt.Errorf("failed to register tracing callbacks: %w", err)
+	}
+
 	logger.InfoLogger.Println("Connected to database successfully")
 	return &Manager{
 		db:     db,
@@ -44,19 +64,29 @@ func (m *Manager) GetDB() *gorm.DB {
 	return m.db
 }
 
-func (m *Manager) SetLogMode(logMode gormlogger.LogLevel) {
-	m.db.Logger = logger.GetGormLogger(logMode)
+// WithContext returns a session bound to ctx, so queries are scoped to the caller's location
+func (m *Manager) WithContext(ctx context.Context) *gorm.DB {
+	return m.db.WithContext(ctx)
 }
 
-func (m *Manager) AutoMigrate() error {
-	return m.db.AutoMigrate(
-		&models.User{},
-		&models.Category{},
-		&models.MenuItem{},
-		&models.AddOn{},
-		&models.Order{},
-		&models.OrderDetail{},
-		&models.SelectedAddOn{},
-		&models.RestaurantInfo{},
-	)
+// Ping checks that the database can be reached
+func (m *Manager) Ping(ctx context.Context) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// Close closes the connection pool, waiting for queries in progress to finish
+func (m *Manager) Close() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+func (m *Manager) SetLogMode(logMode gormlogger.LogLevel) {
+	m.db.Logger = logger.GetGormLogger(logMode)
 }
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// ApplyLocationPrices replaces master prices with the caller location's overrides
+func ApplyLocationPrices(tx *gorm.DB, menuItems []models.MenuItem) error {
+	locationID, ok := auth.LocationIDFromContext(tx.Statement.Context)
+	if !ok || len(menuItems) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(menuItems))
+	for i, item := range menuItems {
+		ids[i] = item.ID
+	}
+
+	var prices []models.MenuItemPrice
+	if err := tx.Where("location_id = ? AND menu_item_id IN ?", locationID, ids).Find(&prices).Error; err != nil {
+		return err
+	}
+
+	overrides := make(map[uint]float64, len(prices))
+	for _, price := range prices {
+		overrides[price.MenuItemID] = price.Price
+	}
+	for i := range menuItems {
+		if price, ok := overrides[menuItems[i].ID]; ok {
+			menuItems[i].Price = price
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package database
+
+import (
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// registerTenantCallbacks limits every statement on a model with a LocationID
+// to the location found in the statement context
+func registerTenantCallbacks(db *gorm.DB) error {
+	if err := db.Callback().Query().Before("gorm:query").Register("tenant:query", tenantQueryScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register("tenant:row", tenantQueryScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("tenant:update", tenantWriteScope); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("tenant:delete", tenantWriteScope); err != nil {
+		return err
+	}
+	return db.Callback().Create().Before("gorm:create").Register("tenant:create", tenantAssign)
+}
+
+func tenantField(db *gorm.DB) (*schema.Field, uint, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, 0, false
+	}
+	field := db.Statement.Schema.LookUpField("LocationID")
+	if field == nil {
+		return nil, 0, false
+	}
+	locationID, ok := auth.LocationIDFromContext(db.Statement.Context)
+	if !ok {
+		return nil, 0, false
+	}
+	return field, locationID, true
+}
+
+// tenantQueryScope lets reads see the caller's rows plus the shared master rows
+func tenantQueryScope(db *gorm.DB) {
+	field, locationID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+
+	column := clause.Column{Table: db.Statement.Table, Name: field.DBName}
+	own := clause.Eq{Column: column, Value: locationID}
+	if isMasterModel(db.Statement.Schema.ModelType) {
+		db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+			clause.Or(own, clause.Eq{Column: column, Value: nil}),
+		}})
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{own}})
+}
+
+// tenantWriteScope only lets a location change its own rows, never the master data
+func tenantWriteScope(db *gorm.DB) {
+	field, locationID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+
+	column := clause.Column{Table: db.Statement.Table, Name: field.DBName}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{clause.Eq{Column: column, Value: locationID}}})
+}
+
+// tenantAssign stamps new rows with the caller's location
+func tenantAssign(db *gorm.DB) {
+	field, locationID, ok := tenantField(db)
+	if !ok {
+		return
+	}
+
+	ctx := db.Statement.Context
+	switch db.Statement.ReflectValue.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < db.Statement.ReflectValue.Len(); i++ {
+			rv := reflect.Indirect(db.Statement.ReflectValue.Index(i))
+			if err := field.Set(ctx, rv, locationID); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, db.Statement.ReflectValue, locationID); err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+func isMasterModel(modelType reflect.Type) bool {
+	_, ok := reflect.New(modelType).Interface().(models.MasterRecord)
+	return ok
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/tracing"
+)
+
+const tracingSpanKey = "tracing:span"
+
+// registerTracingCallbacks records every statement as a span of the request
+// that ran it. The SQL is kept with its placeholders, so values never reach
+// the trace backend.
+func registerTracingCallbacks(db *gorm.DB) error {
+	type register func(name string, fn func(*gorm.DB)) error
+	callbacks := []struct {
+		name      string
+		operation string
+		before    register
+		after     register
+	}{
+		{"create", "INSERT", db.Callback().Create().Before("gorm:create").Register, db.Callback().Create().After("gorm:create").Register},
+		{"query", "SELECT", db.Callback().Query().Before("gorm:query").Register, db.Callback().Query().After("gorm:query").Register},
+		{"update", "UPDATE", db.Callback().Update().Before("gorm:update").Register, db.Callback().Update().After("gorm:update").Register},
+		{"delete", "DELETE", db.Callback().Delete().Before("gorm:delete").Register, db.Callback().Delete().After("gorm:delete").Register},
+		{"row", "SELECT", db.Callback().Row().Before("gorm:row").Register, db.Callback().Row().After("gorm:row").Register},
+		{"raw", "RAW", db.Callback().Raw().Before("gorm:raw").Register, db.Callback().Raw().After("gorm:raw").Register},
+	}
+	for _, callback := range callbacks {
+		if err := callback.before("tracing:before_"+callback.name, startSpan(callback.operation)); err != nil {
+			return err
+		}
+		if err := callback.after("tracing:after_"+callback.name, endSpan); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startSpan(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if db.Statement.Context == nil {
+			return
+		}
+		name := operation
+		if db.Statement.Table != "" {
+			name += " " + db.Statement.Table
+		}
+		ctx, span := tracing.Tracer().Start(db.Statement.Context, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBOperationName(operation),
+			),
+		)
+		if db.Statement.Table != "" {
+			span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+		}
+		db.Statement.Context = ctx
+		db.Statement.Settings.Store(tracingSpanKey, span)
+	}
+}
+
+func endSpan(db *gorm.DB) {
+	value, ok := db.Statement.Settings.LoadAndDelete(tracingSpanKey)
+	if !ok {
+		return
+	}
+	span := value.(trace.Span)
+	defer span.End()
+
+	span.SetAttributes(
+		semconv.DBQueryText(db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	)
+	if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+		span.RecordError(db.Error)
+		span.SetStatus(codes.Error, db.Error.Error())
+	}
+}
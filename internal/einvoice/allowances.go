@@ -0,0 +1,56 @@
+package einvoice
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// CreateAllowance saves an allowance (折讓) reducing an invoice by amount. The
+// invoice should be locked in tx so its allowances cannot exceed its total.
+func CreateAllowance(tx *gorm.DB, invoice models.Invoice, amount int64, reason string, now time.Time) (models.InvoiceAllowance, error) {
+	_, tax := SplitTax(amount, true)
+	allowance := models.InvoiceAllowance{
+		InvoiceID: invoice.ID,
+		IssuedAt:  now,
+		Amount:    amount,
+		TaxAmount: tax,
+		Reason:    reason,
+		// Allowance numbers only need to be unique per seller; this one fits the 16 characters allowed
+		Number: fmt.Sprintf("%s%02d%04d", invoice.Number, len(invoice.Allowances)+1, now.Unix()%10000),
+	}
+	err := tx.Create(&allowance).Error
+	return allowance, err
+}
+
+// RefundAllowance issues an allowance for a refund on an order with an issued
+// invoice, up to what the invoice has left. Orders without one are left alone.
+func RefundAllowance(tx *gorm.DB, refund models.OrderAdjustment, now time.Time) error {
+	var invoice models.Invoice
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Allowances").
+		Where("order_id = ? AND status = ?", refund.OrderID, models.InvoiceIssued).First(&invoice).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	remaining := invoice.TotalAmount
+	for _, allowance := range invoice.Allowances {
+		remaining -= allowance.Amount
+	}
+	amount := min(int64(math.Round(refund.Amount)), remaining)
+	if amount <= 0 {
+		return nil
+	}
+
+	_, err = CreateAllowance(tx, invoice, amount, "Refund: "+refund.ReasonCode, now)
+	return err
+}
@@ -0,0 +1,241 @@
+package einvoice
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// MIG 4.0 message types
+const (
+	MessageInvoice     = "F0401"
+	MessageVoid        = "F0501"
+	MessageAllowance   = "G0401"
+	consumerBuyerName  = "0000"
+	invoiceTypeGeneral = "07"
+	taxTypeTaxable     = "1"
+	taxRate            = "0.05"
+)
+
+type party struct {
+	Identifier string `xml:"Identifier"`
+	Name       string `xml:"Name"`
+}
+
+type invoiceMain struct {
+	InvoiceNumber string `xml:"InvoiceNumber"`
+	InvoiceDate   string `xml:"InvoiceDate"`
+	InvoiceTime   string `xml:"InvoiceTime"`
+	Seller        party  `xml:"Seller"`
+	Buyer         party  `xml:"Buyer"`
+	InvoiceType   string `xml:"InvoiceType"`
+	DonateMark    string `xml:"DonateMark"`
+	CarrierType   string `xml:"CarrierType,omitempty"`
+	CarrierID1    string `xml:"CarrierId1,omitempty"`
+	CarrierID2    string `xml:"CarrierId2,omitempty"`
+	PrintMark     string `xml:"PrintMark"`
+	NPOBAN        string `xml:"NPOBAN,omitempty"`
+	RandomNumber  string `xml:"RandomNumber"`
+}
+
+type productItem struct {
+	Description    string `xml:"Description"`
+	Quantity       int    `xml:"Quantity"`
+	UnitPrice      string `xml:"UnitPrice"`
+	Amount         string `xml:"Amount"`
+	SequenceNumber string `xml:"SequenceNumber"`
+}
+
+type invoiceAmount struct {
+	SalesAmount        int64  `xml:"SalesAmount"`
+	FreeTaxSalesAmount int64  `xml:"FreeTaxSalesAmount"`
+	ZeroTaxSalesAmount int64  `xml:"ZeroTaxSalesAmount"`
+	TaxType            string `xml:"TaxType"`
+	TaxRate            string `xml:"TaxRate"`
+	TaxAmount          int64  `xml:"TaxAmount"`
+	TotalAmount        int64  `xml:"TotalAmount"`
+}
+
+type invoiceDocument struct {
+	XMLName xml.Name      `xml:"urn:GEINV:eInvoiceMessage:F0401:4.0 Invoice"`
+	Main    invoiceMain   `xml:"Main"`
+	Details []productItem `xml:"Details>ProductItem"`
+	Amount  invoiceAmount `xml:"Amount"`
+}
+
+type voidDocument struct {
+	XMLName             xml.Name `xml:"urn:GEINV:eInvoiceMessage:F0501:4.0 CancelInvoice"`
+	CancelInvoiceNumber string   `xml:"CancelInvoiceNumber"`
+	InvoiceDate         string   `xml:"InvoiceDate"`
+	BuyerID             string   `xml:"BuyerId"`
+	SellerID            string   `xml:"SellerId"`
+	CancelDate          string   `xml:"CancelDate"`
+	CancelTime          string   `xml:"CancelTime"`
+	CancelReason        string   `xml:"CancelReason"`
+}
+
+type allowanceMain struct {
+	AllowanceNumber string `xml:"AllowanceNumber"`
+	AllowanceDate   string `xml:"AllowanceDate"`
+	Seller          party  `xml:"Seller"`
+	Buyer           party  `xml:"Buyer"`
+	AllowanceType   string `xml:"AllowanceType"`
+}
+
+type allowanceItem struct {
+	OriginalInvoiceDate     string `xml:"OriginalInvoiceDate"`
+	OriginalInvoiceNumber   string `xml:"OriginalInvoiceNumber"`
+	OriginalSequenceNumber  string `xml:"OriginalSequenceNumber"`
+	OriginalDescription     string `xml:"OriginalDescription"`
+	Quantity                int    `xml:"Quantity"`
+	UnitPrice               int64  `xml:"UnitPrice"`
+	Amount                  int64  `xml:"Amount"`
+	Tax                     int64  `xml:"Tax"`
+	AllowanceSequenceNumber string `xml:"AllowanceSequenceNumber"`
+	TaxType                 string `xml:"TaxType"`
+}
+
+type allowanceAmount struct {
+	TaxAmount   int64 `xml:"TaxAmount"`
+	TotalAmount int64 `xml:"TotalAmount"`
+}
+
+type allowanceDocument struct {
+	XMLName xml.Name        `xml:"urn:GEINV:eInvoiceMessage:G0401:4.0 Allowance"`
+	Main    allowanceMain   `xml:"Main"`
+	Details []allowanceItem `xml:"Details>ProductItem"`
+	Amount  allowanceAmount `xml:"Amount"`
+}
+
+// InvoiceXML renders an issued invoice as an F0401 message
+func InvoiceXML(invoice models.Invoice) ([]byte, error) {
+	buyerName := consumerBuyerName
+	if invoice.BuyerTaxID != ConsumerBuyerID {
+		buyerName = invoice.BuyerTaxID
+	}
+
+	main := invoiceMain{
+		InvoiceNumber: invoice.Number,
+		InvoiceDate:   date(invoice.IssuedAt),
+		InvoiceTime:   invoice.IssuedAt.Format("15:04:05"),
+		Seller:        party{Identifier: invoice.SellerTaxID, Name: invoice.SellerName},
+		Buyer:         party{Identifier: invoice.BuyerTaxID, Name: buyerName},
+		InvoiceType:   invoiceTypeGeneral,
+		DonateMark:    "0",
+		PrintMark:     "Y",
+		RandomNumber:  invoice.RandomNumber,
+	}
+	if invoice.CarrierType != "" {
+		main.CarrierType = invoice.CarrierType
+		main.CarrierID1 = invoice.CarrierID
+		main.CarrierID2 = invoice.CarrierID
+		main.PrintMark = "N"
+	}
+	if invoice.DonationCode != "" {
+		main.DonateMark = "1"
+		main.NPOBAN = invoice.DonationCode
+		main.PrintMark = "N"
+	}
+
+	details := make([]productItem, len(invoice.Items))
+	for i, item := range invoice.Items {
+		details[i] = productItem{
+			Description:    item.Description,
+			Quantity:       item.Quantity,
+			UnitPrice:      decimal(item.UnitPrice),
+			Amount:         decimal(item.Amount),
+			SequenceNumber: strconv.Itoa(i + 1),
+		}
+	}
+
+	return marshal(invoiceDocument{
+		Main:    main,
+		Details: details,
+		Amount: invoiceAmount{
+			SalesAmount: invoice.SalesAmount,
+			TaxType:     taxTypeTaxable,
+			TaxRate:     taxRate,
+			TaxAmount:   invoice.TaxAmount,
+			TotalAmount: invoice.TotalAmount,
+		},
+	})
+}
+
+// VoidXML renders a voided invoice as an F0501 message
+func VoidXML(invoice models.Invoice) ([]byte, error) {
+	voidedAt := time.Now()
+	if invoice.VoidedAt != nil {
+		voidedAt = *invoice.VoidedAt
+	}
+	return marshal(voidDocument{
+		CancelInvoiceNumber: invoice.Number,
+		InvoiceDate:         date(invoice.IssuedAt),
+		BuyerID:             invoice.BuyerTaxID,
+		SellerID:            invoice.SellerTaxID,
+		CancelDate:          date(voidedAt),
+		CancelTime:          voidedAt.Format("15:04:05"),
+		CancelReason:        invoice.VoidReason,
+	})
+}
+
+// AllowanceXML renders an allowance against invoice as a G0401 message issued by the seller
+func AllowanceXML(invoice models.Invoice, allowance models.InvoiceAllowance) ([]byte, error) {
+	buyerName := consumerBuyerName
+	if invoice.BuyerTaxID != ConsumerBuyerID {
+		buyerName = invoice.BuyerTaxID
+	}
+
+	return marshal(allowanceDocument{
+		Main: allowanceMain{
+			AllowanceNumber: allowance.Number,
+			AllowanceDate:   date(allowance.IssuedAt),
+			Seller:          party{Identifier: invoice.SellerTaxID, Name: invoice.SellerName},
+			Buyer:           party{Identifier: invoice.BuyerTaxID, Name: buyerName},
+			AllowanceType:   "2",
+		},
+		Details: []allowanceItem{{
+			OriginalInvoiceDate:     date(invoice.IssuedAt),
+			OriginalInvoiceNumber:   invoice.Number,
+			OriginalSequenceNumber:  "1",
+			OriginalDescription:     allowance.Reason,
+			Quantity:                1,
+			UnitPrice:               allowance.Amount - allowance.TaxAmount,
+			Amount:                  allowance.Amount - allowance.TaxAmount,
+			Tax:                     allowance.TaxAmount,
+			AllowanceSequenceNumber: "1",
+			TaxType:                 taxTypeTaxable,
+		}},
+		Amount: allowanceAmount{
+			TaxAmount:   allowance.TaxAmount,
+			TotalAmount: allowance.Amount - allowance.TaxAmount,
+		},
+	})
+}
+
+// SplitTax works the 5% business tax out of a tax-included amount. Invoices to
+// consumers show the included total; invoices to businesses show sales and tax apart.
+func SplitTax(total int64, business bool) (sales, tax int64) {
+	if !business {
+		return total, 0
+	}
+	sales = int64(float64(total)/1.05 + 0.5)
+	return sales, total - sales
+}
+
+func marshal(document interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func date(t time.Time) string {
+	return t.Format("20060102")
+}
+
+func decimal(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
@@ -0,0 +1,36 @@
+package einvoice
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// ErrNoNumbersLeft is returned when every number range of the period has been used up
+var ErrNoNumbersLeft = errors.New("no invoice numbers left for the current period")
+
+// NextNumber takes the next unused invoice number of the period. It must run inside
+// the transaction that stores the invoice so numbers are never skipped or reused.
+func NextNumber(tx *gorm.DB, period string) (string, error) {
+	var numberRange models.InvoiceNumberRange
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("period = ? AND next_no <= end_no", period).
+		Order("id").First(&numberRange).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrNoNumbersLeft
+		}
+		return "", err
+	}
+
+	number := fmt.Sprintf("%s%08d", numberRange.Track, numberRange.NextNo)
+	err = tx.Model(&numberRange).Update("next_no", numberRange.NextNo+1).Error
+	if err != nil {
+		return "", err
+	}
+	return number, nil
+}
@@ -0,0 +1,27 @@
+package einvoice
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// Uploader hands MIG documents to the e-invoice platform
+type Uploader interface {
+	Upload(ctx context.Context, messageType, documentNumber string, document []byte) error
+}
+
+// FileUploader writes each document to Dir/<message type>/<document number>.xml.
+// It stands in for the platform in development and tests, and matches the folder
+// layout the Turnkey client picks documents up from.
+type FileUploader struct {
+	Dir string
+}
+
+func (u FileUploader) Upload(ctx context.Context, messageType, documentNumber string, document []byte) error {
+	dir := filepath.Join(u.Dir, messageType)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, documentNumber+".xml"), document, 0o644)
+}
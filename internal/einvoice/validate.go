@@ -0,0 +1,68 @@
+package einvoice
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// ConsumerBuyerID is the buyer identifier of invoices issued to consumers
+const ConsumerBuyerID = "0000000000"
+
+var (
+	mobileBarcodePattern = regexp.MustCompile(`^/[0-9A-Z.+-]{7}$`)
+	citizenCertPattern   = regexp.MustCompile(`^[A-Z]{2}[0-9]{14}$`)
+	donationCodePattern  = regexp.MustCompile(`^[0-9]{3,7}$`)
+	taxIDPattern         = regexp.MustCompile(`^[0-9]{8}$`)
+)
+
+var taxIDWeights = [8]int{1, 2, 1, 2, 1, 2, 4, 1}
+
+// ValidTaxID checks the checksum of a business tax ID (統一編號). Since 2023 the
+// weighted digit sum only has to be divisible by 5; a 7 in the seventh position
+// may count as either 0 or 1.
+func ValidTaxID(id string) bool {
+	if !taxIDPattern.MatchString(id) {
+		return false
+	}
+
+	sum := 0
+	for i, c := range id {
+		product := int(c-'0') * taxIDWeights[i]
+		sum += product/10 + product%10
+	}
+	if sum%5 == 0 {
+		return true
+	}
+	return id[6] == '7' && (sum+1)%5 == 0
+}
+
+// ValidCarrier checks the format of a carrier ID for its carrier type
+func ValidCarrier(carrierType, carrierID string) bool {
+	switch carrierType {
+	case models.CarrierMobileBarcode:
+		return mobileBarcodePattern.MatchString(carrierID)
+	case models.CarrierCitizenCert:
+		return citizenCertPattern.MatchString(carrierID)
+	}
+	return false
+}
+
+// ValidDonationCode checks the format of a charity donation code (愛心碼)
+func ValidDonationCode(code string) bool {
+	return donationCodePattern.MatchString(code)
+}
+
+// Period returns the two-month invoice period t falls in, as the ROC year followed by
+// the even month that ends the period: 2024-11-05 is in period "11312". Periods
+// follow the calendar in Taiwan, whatever zone t is in.
+func Period(t time.Time) string {
+	t = t.In(models.RestaurantZone)
+	month := int(t.Month())
+	if month%2 == 1 {
+		month++
+	}
+	return fmt.Sprintf("%03d%02d", t.Year()-1911, month)
+}
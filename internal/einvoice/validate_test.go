@@ -0,0 +1,117 @@
+package einvoice
+
+import (
+	"testing"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+func TestValidTaxID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"22099131", true},
+		{"04595257", true},
+		{"10458575", true},
+		// A 7 in the seventh position may count as 1
+		{"12345675", true},
+		{"10458574", true},
+		{"22099132", false},
+		{"12345678", false},
+		{"2209913", false},
+		{"220991311", false},
+		{"2209913A", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidTaxID(tt.id); got != tt.want {
+			t.Errorf("ValidTaxID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestValidCarrier(t *testing.T) {
+	tests := []struct {
+		carrierType, carrierID string
+		want                   bool
+	}{
+		{models.CarrierMobileBarcode, "/ABC1234", true},
+		{models.CarrierMobileBarcode, "/A.+-123", true},
+		{models.CarrierMobileBarcode, "ABC12345", false},
+		{models.CarrierMobileBarcode, "/abc1234", false},
+		{models.CarrierMobileBarcode, "/ABC123", false},
+		{models.CarrierCitizenCert, "AB12345678901234", true},
+		{models.CarrierCitizenCert, "AB1234567890123", false},
+		{models.CarrierCitizenCert, "/ABC1234", false},
+		{"", "/ABC1234", false},
+	}
+	for _, tt := range tests {
+		if got := ValidCarrier(tt.carrierType, tt.carrierID); got != tt.want {
+			t.Errorf("ValidCarrier(%q, %q) = %v, want %v", tt.carrierType, tt.carrierID, got, tt.want)
+		}
+	}
+}
+
+func TestValidDonationCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"123", true},
+		{"8585", true},
+		{"1234567", true},
+		{"12", false},
+		{"12345678", false},
+		{"12A4", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := ValidDonationCode(tt.code); got != tt.want {
+			t.Errorf("ValidDonationCode(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want string
+	}{
+		{time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), "11302"},
+		{time.Date(2024, time.February, 29, 15, 59, 0, 0, time.UTC), "11302"},
+		{time.Date(2024, time.November, 5, 12, 0, 0, 0, time.UTC), "11312"},
+		{time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC), "11312"},
+		{time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC), "11404"},
+		// Already the next period in Taipei
+		{time.Date(2024, time.February, 29, 16, 0, 0, 0, time.UTC), "11304"},
+		{time.Date(2024, time.December, 31, 16, 30, 0, 0, time.UTC), "11402"},
+		{time.Date(2024, time.March, 1, 0, 0, 0, 0, models.RestaurantZone), "11304"},
+	}
+	for _, tt := range tests {
+		if got := Period(tt.t); got != tt.want {
+			t.Errorf("Period(%v) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestSplitTax(t *testing.T) {
+	tests := []struct {
+		total      int64
+		business   bool
+		sales, tax int64
+	}{
+		{1050, false, 1050, 0},
+		{1050, true, 1000, 50},
+		{100, true, 95, 5},
+		{1, true, 1, 0},
+		{0, true, 0, 0},
+	}
+	for _, tt := range tests {
+		sales, tax := SplitTax(tt.total, tt.business)
+		if sales != tt.sales || tax != tt.tax {
+			t.Errorf("SplitTax(%d, %v) = %d, %d; want %d, %d", tt.total, tt.business, sales, tax, tt.sales, tt.tax)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package escpos
+
+import (
+	"image"
+	"image/color"
+)
+
+// DotsPerChar is the width of a default-font character in printer dots, so a
+// line of Width characters is Width*DotsPerChar dots wide
+const DotsPerChar = 12
+
+// Image prints img as a raster bit image, one dot per pixel. Dark pixels print
+// black and transparent ones are left blank; anything wider than the paper is cut off.
+func (w *Writer) Image(img image.Image) *Writer {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if maxDots := w.Width * DotsPerChar; width > maxDots {
+		width = maxDots
+	}
+	if width <= 0 || height <= 0 {
+		return w
+	}
+
+	rowBytes := (width + 7) / 8
+	w.buf.Write([]byte{gs, 'v', '0', 0, byte(rowBytes), byte(rowBytes >> 8), byte(height), byte(height >> 8)})
+	row := make([]byte, rowBytes)
+	for y := 0; y < height; y++ {
+		clear(row)
+		for x := 0; x < width; x++ {
+			if isDark(img.At(bounds.Min.X+x, bounds.Min.Y+y)) {
+				row[x/8] |= 0x80 >> (x % 8)
+			}
+		}
+		w.buf.Write(row)
+	}
+	return w
+}
+
+// isDark reports whether c, laid over white paper, is closer to black than white
+func isDark(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	// The channels are premultiplied, so adding the transparent part gives white
+	white := 0xffff - a
+	luminance := (299*(r+white) + 587*(g+white) + 114*(b+white)) / 1000
+	return luminance < 0x8000
+}
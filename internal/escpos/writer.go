@@ -0,0 +1,175 @@
+package escpos
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/width"
+)
+
+// Paper widths in characters of the default font
+const (
+	Width58mm = 32
+	Width80mm = 48
+)
+
+// Alignment values for Align
+const (
+	AlignLeft   byte = 0
+	AlignCenter byte = 1
+	AlignRight  byte = 2
+)
+
+const (
+	esc = 0x1b
+	gs  = 0x1d
+	lf  = 0x0a
+)
+
+// Writer builds an ESC/POS byte stream. Text is encoded as Big5, the code page of
+// the thermal printers sold in Taiwan; characters Big5 cannot represent print as '?'.
+type Writer struct {
+	buf     bytes.Buffer
+	encoder *encoding.Encoder
+	// Width is the number of default-font characters per line
+	Width int
+}
+
+// NewWriter returns a writer for paper of the given width that has already reset the printer
+func NewWriter(width int) *Writer {
+	w := &Writer{
+		encoder: encoding.ReplaceUnsupported(traditionalchinese.Big5.NewEncoder()),
+		Width:   width,
+	}
+	w.buf.Write([]byte{esc, '@'})
+	// Select the Big5 double-byte character mode
+	w.buf.Write([]byte{0x1c, '&'})
+	return w
+}
+
+// Align sets the justification of the following lines
+func (w *Writer) Align(align byte) *Writer {
+	w.buf.Write([]byte{esc, 'a', align})
+	return w
+}
+
+// Bold turns emphasized printing on or off
+func (w *Writer) Bold(on bool) *Writer {
+	w.buf.Write([]byte{esc, 'E', boolByte(on)})
+	return w
+}
+
+// DoubleSize turns double width and height on or off
+func (w *Writer) DoubleSize(on bool) *Writer {
+	size := byte(0x00)
+	if on {
+		size = 0x11
+	}
+	w.buf.Write([]byte{gs, '!', size})
+	return w
+}
+
+// Text writes s without a line feed
+func (w *Writer) Text(s string) *Writer {
+	encoded, err := w.encoder.Bytes([]byte(s))
+	if err != nil {
+		encoded = []byte(strings.Map(asciiOnly, s))
+	}
+	w.buf.Write(encoded)
+	return w
+}
+
+// Line writes s followed by a line feed
+func (w *Writer) Line(s string) *Writer {
+	w.Text(s)
+	w.buf.WriteByte(lf)
+	return w
+}
+
+// Columns writes left and right justified on one line, truncating left if they do not fit
+func (w *Writer) Columns(left, right string) *Writer {
+	space := w.Width - DisplayWidth(right) - 1
+	left = Truncate(left, space)
+	padding := w.Width - DisplayWidth(left) - DisplayWidth(right)
+	if padding < 1 {
+		padding = 1
+	}
+	return w.Line(left + strings.Repeat(" ", padding) + right)
+}
+
+// Rule writes a full-width line of c
+func (w *Writer) Rule(c rune) *Writer {
+	return w.Line(strings.Repeat(string(c), w.Width))
+}
+
+// Feed advances the paper by n lines
+func (w *Writer) Feed(n int) *Writer {
+	w.buf.Write([]byte{esc, 'd', byte(n)})
+	return w
+}
+
+// Cut feeds past the cutter and partially cuts the paper
+func (w *Writer) Cut() *Writer {
+	w.buf.Write([]byte{gs, 'V', 66, 3})
+	return w
+}
+
+// Bytes returns the byte stream built so far
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+// DisplayWidth returns how many default-font columns s takes; CJK characters take two
+func DisplayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		n += runeWidth(r)
+	}
+	return n
+}
+
+// Truncate shortens s to at most max columns
+func Truncate(s string, max int) string {
+	if max <= 0 {
+		return ""
+	}
+	if DisplayWidth(s) <= max {
+		return s
+	}
+	n := 0
+	for i, r := range s {
+		if n+runeWidth(r) > max {
+			return s[:i]
+		}
+		n += runeWidth(r)
+	}
+	return s
+}
+
+func runeWidth(r rune) int {
+	if r == utf8.RuneError {
+		return 1
+	}
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+	return 1
+}
+
+func asciiOnly(r rune) rune {
+	if r < utf8.RuneSelf {
+		return r
+	}
+	return '?'
+}
+
+func boolByte(on bool) byte {
+	if on {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/einvoice"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var reasonCodes = map[string]bool{
+	models.ReasonWrongItem:         true,
+	models.ReasonQualityIssue:      true,
+	models.ReasonCustomerComplaint: true,
+	models.ReasonKitchenError:      true,
+	models.ReasonDuplicate:         true,
+	models.ReasonOther:             true,
+}
+
+// Approval identifies the manager approving a void or refund, by password or PIN
+type Approval struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	PIN      string `json:"pin"`
+}
+
+type AdjustmentRequest struct {
+	OrderDetailID *uint     `json:"order_detail_id"`
+	Quantity      int       `json:"quantity"`
+	Amount        float64   `json:"amount"`
+	ReasonCode    string    `json:"reason_code"`
+	Note          string    `json:"note"`
+	Method        string    `json:"method"`
+	Approval      *Approval `json:"approval"`
+}
+
+// VoidOrderItem takes items off an unpaid order. The order detail is kept and a
+// void adjustment is recorded against it.
+func VoidOrderItem(store repository.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var req AdjustmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if !reasonCodes[req.ReasonCode] {
+			apierror.Respond(w, r, "A valid reason code is required", http.StatusBadRequest)
+			return
+		}
+		if req.OrderDetailID == nil || req.Quantity <= 0 {
+			apierror.Respond(w, r, "Order detail ID and a positive quantity are required", http.StatusBadRequest)
+			return
+		}
+
+		order, err := store.Orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			info, err := store.RestaurantInfo.ForLocation(r.Context(), order.LocationID)
+			if err != nil {
+				return restaurantInfoError(err)
+			}
+
+			if order.Status == models.OrderCompleted || order.Status == models.OrderCancelled {
+				return apierror.New(http.StatusConflict, "Cannot void items on a closed order")
+			}
+			if order.PaidAmount() > 0 {
+				return apierror.New(http.StatusConflict, "Order has payments; refund the items instead")
+			}
+
+			amount, err := adjustedLineAmount(*order, *req.OrderDetailID, req.Quantity)
+			if err != nil {
+				return err
+			}
+
+			adjustment := models.OrderAdjustment{
+				Type:          models.AdjustmentVoid,
+				OrderDetailID: req.OrderDetailID,
+				Quantity:      req.Quantity,
+				Amount:        amount,
+				ReasonCode:    req.ReasonCode,
+				Note:          req.Note,
+			}
+			if err := approveAdjustment(r.Context(), store.Users, info, &adjustment, req.Approval); err != nil {
+				return err
+			}
+
+			order.Adjustments = append(order.Adjustments, adjustment)
+			order.ApplyTotals(info.TaxSettings)
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// RefundOrder returns money on a paid order, either for items or for an amount.
+// The order total is left alone; refunds are reported from their adjustments.
+// A refund on an invoiced order also issues an allowance (折讓) against the invoice.
+func RefundOrder(store repository.Store, db *database.Manager, uploader einvoice.Uploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var req AdjustmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if !reasonCodes[req.ReasonCode] {
+			apierror.Respond(w, r, "A valid reason code is required", http.StatusBadRequest)
+			return
+		}
+		switch req.Method {
+		case models.PaymentCash, models.PaymentCard, models.PaymentMobile:
+		default:
+			apierror.Respond(w, r, "Refund method must be cash, card or mobile", http.StatusBadRequest)
+			return
+		}
+		if req.OrderDetailID == nil && req.Amount <= 0 {
+			apierror.Respond(w, r, "Either an order detail ID or a positive amount is required", http.StatusBadRequest)
+			return
+		}
+		if req.OrderDetailID != nil && req.Quantity <= 0 {
+			apierror.Respond(w, r, "Quantity must be positive", http.StatusBadRequest)
+			return
+		}
+
+		order, err := store.Orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			info, err := store.RestaurantInfo.ForLocation(r.Context(), order.LocationID)
+			if err != nil {
+				return restaurantInfoError(err)
+			}
+
+			refundable := math.Round((order.PaidAmount()-order.RefundedAmount())*100) / 100
+			if refundable <= 0 {
+				return apierror.New(http.StatusConflict, "Order has nothing left to refund")
+			}
+
+			adjustment := models.OrderAdjustment{
+				Type:       models.AdjustmentRefund,
+				Amount:     req.Amount,
+				ReasonCode: req.ReasonCode,
+				Note:       req.Note,
+				Method:     req.Method,
+			}
+			if req.OrderDetailID != nil {
+				amount, err := adjustedLineAmount(*order, *req.OrderDetailID, req.Quantity)
+				if err != nil {
+					return err
+				}
+				adjustment.OrderDetailID = req.OrderDetailID
+				adjustment.Quantity = req.Quantity
+				adjustment.Amount = amount
+			}
+			if adjustment.Amount > refundable {
+				return apierror.New(http.StatusBadRequest, "Refund exceeds the amount paid")
+			}
+			if err := approveAdjustment(r.Context(), store.Users, info, &adjustment, req.Approval); err != nil {
+				return err
+			}
+
+			order.Adjustments = append(order.Adjustments, adjustment)
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		uploadRefundAllowances(r.Context(), db, uploader, order.ID)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order.Adjustments[len(order.Adjustments)-1])
+	}
+}
+
+// adjustedLineAmount prices quantity units of an order line, refusing more than
+// the units not yet voided or refunded
+func adjustedLineAmount(order models.Order, orderDetailID uint, quantity int) (float64, error) {
+	for _, detail := range order.OrderDetails {
+		if detail.ID != orderDetailID {
+			continue
+		}
+		if quantity > detail.Quantity-order.AdjustedQuantity(detail.ID) {
+			return 0, apierror.New(http.StatusBadRequest, "Quantity exceeds what is left on the line")
+		}
+		return math.Round(detail.Subtotal/float64(detail.Quantity)*float64(quantity)*100) / 100, nil
+	}
+	return 0, apierror.New(http.StatusBadRequest, "Order detail not found on this order")
+}
+
+// approveAdjustment records who asked for the adjustment and, when the amount is
+// over the restaurant's approval threshold, checks the approving manager
+func approveAdjustment(ctx context.Context, users repository.Users, info models.RestaurantInfo, adjustment *models.OrderAdjustment, approval *Approval) error {
+	adjustment.RequestedBy = auth.UsernameFromContext(ctx)
+	if adjustment.Amount <= info.ApprovalThreshold && info.ApprovalThreshold > 0 {
+		return nil
+	}
+
+	if approval == nil || approval.Username == "" {
+		return apierror.New(http.StatusForbidden, "Manager approval is required")
+	}
+
+	manager, err := users.GetByUsername(ctx, approval.Username)
+	if err != nil {
+		return apierror.New(http.StatusForbidden, "Invalid approval credentials")
+	}
+
+	switch {
+	case approval.PIN != "" && manager.PIN != "":
+		err = bcrypt.CompareHashAndPassword([]byte(manager.PIN), []byte(approval.PIN))
+	case approval.Password != "":
+		err = bcrypt.CompareHashAndPassword([]byte(manager.Password), []byte(approval.Password))
+	default:
+		err = bcrypt.ErrMismatchedHashAndPassword
+	}
+	if err != nil {
+		return apierror.New(http.StatusForbidden, "Invalid approval credentials")
+	}
+	if !manager.CanApprove() {
+		return apierror.New(http.StatusForbidden, "Approver must be a manager")
+	}
+
+	adjustment.ApprovedBy = &manager.ID
+	return nil
+}
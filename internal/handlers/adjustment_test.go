@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/memory"
+	"github.com/gorilla/mux"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAdjustments(t *testing.T) {
+	ctx := context.WithValue(context.Background(), auth.ContextUsername, "cashier")
+	store := memory.NewStore()
+	if err := store.RestaurantInfo.Save(ctx, &models.RestaurantInfo{Name: "Noodle Bar", ApprovalThreshold: 50}); err != nil {
+		t.Fatal(err)
+	}
+	pin, err := bcrypt.GenerateFromPassword([]byte("1234"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []models.User{
+		{Username: "manager", Password: "x", PIN: string(pin), Name: "Manager", Role: models.RoleManager},
+		{Username: "server", Password: "x", PIN: string(pin), Name: "Server", Role: "server"},
+	} {
+		if err := store.Users.Create(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	order := models.Order{
+		OrderType:    models.OrderTypeDineIn,
+		TableNumber:  "A1",
+		OrderDetails: []models.OrderDetail{{Quantity: 4, UnitPrice: 30, Subtotal: 120}},
+		TotalAmount:  120,
+	}
+	if _, err := store.Orders.Create(ctx, &order, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+	detailID := order.OrderDetails[0].ID
+
+	post := func(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)).WithContext(ctx)
+		handler(w, mux.SetURLVars(r, map[string]string{"id": strconv.Itoa(int(order.ID))}))
+		return w
+	}
+	line := func(quantity int, approval string) string {
+		return `{"order_detail_id":` + strconv.Itoa(int(detailID)) + `,"quantity":` + strconv.Itoa(quantity) +
+			`,"reason_code":"` + models.ReasonWrongItem + `","method":"cash"` + approval + `}`
+	}
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+		want    int
+	}{
+		{"refund of an unpaid order", RefundOrder(store, nil, nil), line(1, ""), http.StatusConflict},
+		{"void under the threshold", VoidOrderItem(store), line(1, ""), http.StatusCreated},
+		{"void over the threshold", VoidOrderItem(store), line(2, ""), http.StatusForbidden},
+		{"void approved by a server", VoidOrderItem(store), line(2, `,"approval":{"username":"server","pin":"1234"}`), http.StatusForbidden},
+		{"void with a wrong PIN", VoidOrderItem(store), line(2, `,"approval":{"username":"manager","pin":"0000"}`), http.StatusForbidden},
+		{"void approved by a manager", VoidOrderItem(store), line(2, `,"approval":{"username":"manager","pin":"1234"}`), http.StatusCreated},
+		{"void of more than is left", VoidOrderItem(store), line(2, ""), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := post(tt.handler, tt.body); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+
+	got, err := store.Orders.Get(ctx, order.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Adjustments) != 2 || got.TotalAmount != 30 {
+		t.Fatalf("order has %d adjustments and a total of %v, want 2 and 30", len(got.Adjustments), got.TotalAmount)
+	}
+	if got.Adjustments[0].RequestedBy != "cashier" || got.Adjustments[0].ApprovedBy != nil || got.Adjustments[1].ApprovedBy == nil {
+		t.Errorf("adjustments = %+v, want the second approved by the manager", got.Adjustments)
+	}
+
+	w := post(VoidOrderItem(store), line(1, ""))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("last void: status = %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	var voided models.Order
+	if err := json.NewDecoder(w.Body).Decode(&voided); err != nil {
+		t.Fatal(err)
+	}
+	if last := voided.Adjustments[len(voided.Adjustments)-1]; last.ID == 0 || voided.TotalAmount != 0 {
+		t.Errorf("last void returned %+v with a total of %v, want a saved void and nothing left to pay", last, voided.TotalAmount)
+	}
+}
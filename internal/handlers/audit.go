@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// GetAuditLogs lists audit log entries, newest first, filtered by entity,
+// entity ID, actor, action and time. Only managers may read the log.
+func GetAuditLogs(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireManager(w, r, db) {
+			return
+		}
+
+		params := r.URL.Query()
+		query := db.WithContext(r.Context()).Order("id desc")
+		if entity := params.Get("entity"); entity != "" {
+			query = query.Where("entity = ?", entity)
+		}
+		if entityID := params.Get("entity_id"); entityID != "" {
+			query = query.Where("entity_id = ?", entityID)
+		}
+		if actor := params.Get("actor"); actor != "" {
+			query = query.Where("actor = ?", actor)
+		}
+		if action := params.Get("action"); action != "" {
+			query = query.Where("action = ?", action)
+		}
+		for param, condition := range map[string]string{"since": "created_at >= ?", "until": "created_at < ?"} {
+			if value := params.Get(param); value != "" {
+				t, err := time.Parse(time.RFC3339, value)
+				if err != nil {
+					apierror.Respond(w, r, "Invalid "+param+" time, expected RFC 3339", http.StatusBadRequest)
+					return
+				}
+				query = query.Where(condition, t)
+			}
+		}
+
+		limit := 100
+		if value := params.Get("limit"); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > 1000 {
+				apierror.Respond(w, r, "Limit must be between 1 and 1000", http.StatusBadRequest)
+				return
+			}
+			limit = n
+		}
+		if value := params.Get("before_id"); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid before_id", http.StatusBadRequest)
+				return
+			}
+			query = query.Where("id < ?", id)
+		}
+
+		var entries []models.AuditLog
+		result := query.Limit(limit).Find(&entries)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
@@ -5,10 +5,14 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
 	"github.com/darrenjon/restaurant-ordering-system/internal/database"
 	"github.com/darrenjon/restaurant-ordering-system/internal/models"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 type LoginRequest struct {
@@ -20,40 +24,44 @@ type LoginResponse struct {
 	Token string `json:"token"`
 }
 
-func Login(db *database.Manager) http.HandlerFunc {
+func Login(db *database.Manager, cfg config.JWTConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		if db == nil || db.Config == nil || db.Config.JWTSecret == "" {
-			http.Error(w, "Server configuration error", http.StatusInternalServerError)
+		if db == nil || cfg.Secret == "" {
+			apierror.Respond(w, r, "Server configuration error", http.StatusInternalServerError)
 			return
 		}
 		var req LoginRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
 		var user models.User
-		result := db.GetDB().Where("username = ?", req.Username).First(&user)
+		result := db.WithContext(r.Context()).Where("username = ?", req.Username).First(&user)
 		if result.Error != nil {
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			apierror.Respond(w, r, "Invalid username or password", http.StatusUnauthorized)
 			return
 		}
 
 		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-			http.Error(w, "Invalid username or password", http.StatusUnauthorized)
+			apierror.Respond(w, r, "Invalid username or password", http.StatusUnauthorized)
 			return
 		}
 
-		expirationTime := time.Now().Add(24 * time.Hour)
-		claims := jwt.RegisteredClaims{
-			Subject:   user.Username,
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+		expirationTime := time.Now().Add(cfg.TTL)
+		claims := auth.Claims{
+			LocationID: user.LocationID,
+			Role:       user.Role,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Subject:   user.Username,
+				ExpiresAt: jwt.NewNumericDate(expirationTime),
+			},
 		}
 
 		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		tokenString, err := token.SignedString([]byte(db.Config.JWTSecret))
+		tokenString, err := token.SignedString([]byte(cfg.Secret))
 		if err != nil {
-			http.Error(w, "Could not generate token", http.StatusInternalServerError)
+			apierror.Respond(w, r, "Could not generate token", http.StatusInternalServerError)
 			return
 		}
 
@@ -66,3 +74,16 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "Logged out successfully"})
 }
+
+// currentUser loads the user the request's token was issued to
+func currentUser(r *http.Request, tx *gorm.DB) (models.User, bool) {
+	var user models.User
+	username, ok := r.Context().Value(auth.ContextUsername).(string)
+	if !ok || username == "" {
+		return user, false
+	}
+	if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+		return user, false
+	}
+	return user, true
+}
@@ -2,21 +2,21 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
 	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
 	"github.com/gorilla/mux"
-	"gorm.io/gorm"
 )
 
-func GetCategories(db *database.Manager) http.HandlerFunc {
+func GetCategories(menu repository.Menu) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var categories []models.Category
-		result := db.GetDB().Order("display_order").Find(&categories)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		categories, err := menu.ListCategories(r.Context())
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -25,17 +25,20 @@ func GetCategories(db *database.Manager) http.HandlerFunc {
 	}
 }
 
-func CreateCategory(db *database.Manager) http.HandlerFunc {
+func CreateCategory(menu repository.Menu) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var category models.Category
 		if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
 			return
 		}
 
-		result := db.GetDB().Create(&category)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		if err := menu.CreateCategory(r.Context(), &category); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				apierror.Respond(w, r, "Category name is already in use", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
 			return
 		}
 
@@ -44,39 +47,47 @@ func CreateCategory(db *database.Manager) http.HandlerFunc {
 	}
 }
 
-func UpdateCategory(db *database.Manager) http.HandlerFunc {
+func UpdateCategory(menu repository.Menu) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid category ID", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid category ID", http.StatusBadRequest)
 			return
 		}
 
 		var updatedCategory models.Category
 		if err := json.NewDecoder(r.Body).Decode(&updatedCategory); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
 			return
 		}
 
-		var existingCategory models.Category
-		result := db.GetDB().First(&existingCategory, id)
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				http.Error(w, "Category not found", http.StatusNotFound)
+		existingCategory, err := menu.GetCategory(r.Context(), uint(id))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "Category not found", http.StatusNotFound)
 			} else {
-				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+				apierror.Write(w, r, err)
 			}
 			return
 		}
 
+		if !canModify(r.Context(), existingCategory) {
+			apierror.Respond(w, r, "Master categories can only be changed by head office", http.StatusForbidden)
+			return
+		}
+
 		// Update only specific fields
 		existingCategory.Name = updatedCategory.Name
 		existingCategory.DisplayOrder = updatedCategory.DisplayOrder
+		existingCategory.StationID = updatedCategory.StationID
 
-		result = db.GetDB().Save(&existingCategory)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		if err := menu.UpdateCategory(r.Context(), &existingCategory); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				apierror.Respond(w, r, "Category name is already in use", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
 			return
 		}
 
@@ -85,28 +96,98 @@ func UpdateCategory(db *database.Manager) http.HandlerFunc {
 	}
 }
 
-func DeleteCategory(db *database.Manager) http.HandlerFunc {
+// Category deletion policies for categories that still have menu items
+const (
+	deletePolicyRestrict = "restrict"
+	deletePolicyMove     = "move"
+	deletePolicyCascade  = "cascade"
+)
+
+type BlockingMenuItem struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// DeleteCategory deletes a category. A category with menu items is refused
+// unless policy=move&target_id=N moves the items to another category or
+// policy=cascade deletes them with their add-ons.
+func DeleteCategory(menu repository.Menu) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid category ID", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid category ID", http.StatusBadRequest)
 			return
 		}
 
-		result := db.GetDB().Delete(&models.Category{}, id)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		policy := r.URL.Query().Get("policy")
+		if policy == "" {
+			policy = deletePolicyRestrict
+		}
+		var opts repository.CategoryDelete
+		switch policy {
+		case deletePolicyRestrict:
+		case deletePolicyCascade:
+			opts.Cascade = true
+		case deletePolicyMove:
+			targetID, err := strconv.Atoi(r.URL.Query().Get("target_id"))
+			if err != nil || targetID == id {
+				apierror.Respond(w, r, "A different target_id is required to move menu items", http.StatusBadRequest)
+				return
+			}
+			target := uint(targetID)
+			opts.MoveTo = &target
+		default:
+			apierror.Respond(w, r, "Policy must be restrict, move or cascade", http.StatusBadRequest)
 			return
 		}
 
-		if result.RowsAffected == 0 {
-			http.Error(w, "Category not found", http.StatusNotFound)
+		category, err := menu.GetCategory(r.Context(), uint(id))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "Category not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+		if !canModify(r.Context(), category) {
+			apierror.Respond(w, r, "Master categories can only be deleted by head office", http.StatusForbidden)
+			return
+		}
+
+		count, err := menu.DeleteCategory(r.Context(), category.ID, opts)
+		var itemsErr *repository.MenuItemsError
+		switch {
+		case errors.As(err, &itemsErr):
+			blocking := make([]BlockingMenuItem, len(itemsErr.MenuItems))
+			for i, item := range itemsErr.MenuItems {
+				blocking[i] = BlockingMenuItem{ID: item.ID, Name: item.Name}
+			}
+			apierror.Write(w, r, &apierror.Error{
+				Status:  http.StatusConflict,
+				Code:    "category_in_use",
+				Message: "Category still has menu items",
+				Details: map[string]interface{}{"menu_items": blocking},
+			})
+			return
+		case errors.Is(err, repository.ErrTargetNotFound):
+			apierror.Respond(w, r, "Target category not found", http.StatusBadRequest)
+			return
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Respond(w, r, "Category not found", http.StatusNotFound)
+			return
+		case err != nil:
+			apierror.Write(w, r, err)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"message": "Category deleted successfully"})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message":    "Category deleted successfully",
+			"menu_items": count,
+			"policy":     policy,
+		})
 	}
 }
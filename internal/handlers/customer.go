@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/loyalty"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// CustomerProfile is a customer with their current loyalty points
+type CustomerProfile struct {
+	models.Customer
+	Points int `json:"points"`
+}
+
+type CustomerPoints struct {
+	Balance int                   `json:"balance"`
+	Ledger  []models.LoyaltyEntry `json:"ledger"`
+}
+
+// SignUpCustomer creates a customer account from a phone number or email address
+func SignUpCustomer(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var customer models.Customer
+		if err := json.NewDecoder(r.Body).Decode(&customer); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		normalizeCustomerContact(&customer)
+		if customer.Name == "" || (customer.Phone == nil && customer.Email == nil) {
+			apierror.Respond(w, r, "Name and a phone number or email are required", http.StatusBadRequest)
+			return
+		}
+
+		if taken, err := customerContactTaken(db.WithContext(r.Context()), customer); err != nil {
+			apierror.Write(w, r, err)
+			return
+		} else if taken {
+			apierror.Respond(w, r, "A customer with that phone number or email already exists", http.StatusConflict)
+			return
+		}
+
+		customer.Favorites = nil
+		result := db.WithContext(r.Context()).Create(&customer)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(customer)
+	}
+}
+
+// GetCustomers looks customers up by name, phone or email
+func GetCustomers(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := db.WithContext(r.Context()).Order("name").Limit(50)
+		if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+			like := "%" + strings.ToLower(q) + "%"
+			query = query.Where("LOWER(name) LIKE ? OR phone LIKE ? OR LOWER(email) LIKE ?", like, like, like)
+		}
+
+		var customers []models.Customer
+		result := query.Find(&customers)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(customers)
+	}
+}
+
+func GetCustomer(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()).Preload("Favorites.MenuItem"))
+		if !ok {
+			return
+		}
+
+		points, err := loyalty.Balance(db.WithContext(r.Context()), customer.ID, time.Now())
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(CustomerProfile{Customer: customer, Points: points})
+	}
+}
+
+func UpdateCustomer(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var updatedCustomer models.Customer
+		if err := json.NewDecoder(r.Body).Decode(&updatedCustomer); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		normalizeCustomerContact(&updatedCustomer)
+		if updatedCustomer.Name != "" {
+			customer.Name = updatedCustomer.Name
+		}
+		if updatedCustomer.Phone != nil {
+			customer.Phone = updatedCustomer.Phone
+		}
+		if updatedCustomer.Email != nil {
+			customer.Email = updatedCustomer.Email
+		}
+
+		if taken, err := customerContactTaken(db.WithContext(r.Context()), customer); err != nil {
+			apierror.Write(w, r, err)
+			return
+		} else if taken {
+			apierror.Respond(w, r, "A customer with that phone number or email already exists", http.StatusConflict)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Omit("Favorites").Save(&customer)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(customer)
+	}
+}
+
+// GetCustomerOrders lists the customer's orders, newest first
+func GetCustomerOrders(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		var orders []models.Order
+		result := db.WithContext(r.Context()).Preload("OrderDetails.SelectedAddOns").
+			Where("customer_id = ?", customer.ID).Order("created_at desc").Find(&orders)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(orders)
+	}
+}
+
+// GetCustomerPoints returns the customer's points balance and ledger
+func GetCustomerPoints(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		var points CustomerPoints
+		err := db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := loyalty.Expire(tx, customer.ID, now); err != nil {
+				return err
+			}
+			if err := tx.Where("customer_id = ?", customer.ID).Order("created_at desc").Find(&points.Ledger).Error; err != nil {
+				return err
+			}
+			var err error
+			points.Balance, err = loyalty.Balance(tx, customer.ID, now)
+			return err
+		})
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(points)
+	}
+}
+
+func AddCustomerFavorite(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		menuItemID, err := strconv.Atoi(vars["menuItemId"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid menu item ID", http.StatusBadRequest)
+			return
+		}
+
+		var menuItem models.MenuItem
+		if err := db.WithContext(r.Context()).First(&menuItem, menuItemID).Error; err != nil {
+			apierror.Respond(w, r, "Menu item not found", http.StatusNotFound)
+			return
+		}
+
+		favorite := models.CustomerFavorite{CustomerID: customer.ID, MenuItemID: menuItem.ID}
+		result := db.WithContext(r.Context()).Where(favorite).FirstOrCreate(&favorite)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		favorite.MenuItem = &menuItem
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(favorite)
+	}
+}
+
+func RemoveCustomerFavorite(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customer, ok := loadCustomer(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		vars := mux.Vars(r)
+		menuItemID, err := strconv.Atoi(vars["menuItemId"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid menu item ID", http.StatusBadRequest)
+			return
+		}
+
+		// Favorites are removed outright so the same item can be saved again
+		result := db.WithContext(r.Context()).Unscoped().
+			Where("customer_id = ? AND menu_item_id = ?", customer.ID, menuItemID).
+			Delete(&models.CustomerFavorite{})
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, "Favorite not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Favorite removed successfully"})
+	}
+}
+
+// normalizeCustomerContact trims the phone and email, treating blanks as not given
+func normalizeCustomerContact(customer *models.Customer) {
+	customer.Name = strings.TrimSpace(customer.Name)
+	if customer.Phone != nil {
+		phone := strings.TrimSpace(*customer.Phone)
+		customer.Phone = &phone
+		if phone == "" {
+			customer.Phone = nil
+		}
+	}
+	if customer.Email != nil {
+		email := strings.ToLower(strings.TrimSpace(*customer.Email))
+		customer.Email = &email
+		if email == "" {
+			customer.Email = nil
+		}
+	}
+}
+
+// customerContactTaken reports whether another customer has the phone number or email
+func customerContactTaken(tx *gorm.DB, customer models.Customer) (bool, error) {
+	query := tx.Model(&models.Customer{}).Where("id <> ?", customer.ID)
+	switch {
+	case customer.Phone != nil && customer.Email != nil:
+		query = query.Where("phone = ? OR email = ?", *customer.Phone, *customer.Email)
+	case customer.Phone != nil:
+		query = query.Where("phone = ?", *customer.Phone)
+	case customer.Email != nil:
+		query = query.Where("email = ?", *customer.Email)
+	default:
+		return false, nil
+	}
+
+	var count int64
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func loadCustomer(w http.ResponseWriter, r *http.Request, tx *gorm.DB) (models.Customer, bool) {
+	var customer models.Customer
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid customer ID", http.StatusBadRequest)
+		return customer, false
+	}
+
+	result := tx.First(&customer, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Customer not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return customer, false
+	}
+	return customer, true
+}
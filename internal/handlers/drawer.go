@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DrawerReport is a drawer session with its expected cash worked out
+type DrawerReport struct {
+	models.DrawerSession
+	CashSales   float64 `json:"cash_sales"`
+	CashRefunds float64 `json:"cash_refunds"`
+	PaidIn      float64 `json:"paid_in"`
+	PaidOut     float64 `json:"paid_out"`
+	Expected    float64 `json:"expected"`
+}
+
+type CloseDrawerRequest struct {
+	CountedCash *float64 `json:"counted_cash"`
+	Note        string   `json:"note"`
+}
+
+func GetDrawerSessions(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := db.WithContext(r.Context()).Order("opened_at desc")
+		switch r.URL.Query().Get("status") {
+		case "open":
+			query = query.Where("closed_at IS NULL")
+		case "closed":
+			query = query.Where("closed_at IS NOT NULL")
+		}
+
+		var sessions []models.DrawerSession
+		result := query.Find(&sessions)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sessions)
+	}
+}
+
+func GetDrawerSession(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		session, ok := loadDrawerSession(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(drawerReport(session))
+	}
+}
+
+// OpenDrawerSession opens a drawer for the signed-in cashier with a starting float
+func OpenDrawerSession(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var session models.DrawerSession
+		if err := json.NewDecoder(r.Body).Decode(&session); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if session.OpeningFloat < 0 {
+			apierror.Respond(w, r, "Opening float cannot be negative", http.StatusBadRequest)
+			return
+		}
+
+		tx := db.WithContext(r.Context())
+		user, ok := currentUser(r, tx)
+		if !ok {
+			apierror.Respond(w, r, "Sign in to open a drawer", http.StatusUnauthorized)
+			return
+		}
+
+		if _, err := database.OpenDrawerFor(tx, user.ID); err == nil {
+			apierror.Respond(w, r, "You already have an open drawer", http.StatusConflict)
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		session = models.DrawerSession{
+			UserID:       user.ID,
+			OpenedAt:     time.Now(),
+			OpeningFloat: session.OpeningFloat,
+			Note:         session.Note,
+		}
+		// A unique index allows one open drawer per user, so a concurrent open fails here
+		if err := tx.Create(&session).Error; err != nil {
+			if apierror.IsUniqueViolation(err) {
+				apierror.Respond(w, r, "You already have an open drawer", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(session)
+	}
+}
+
+// AddDrawerMovement records a paid-in or paid-out on an open drawer
+func AddDrawerMovement(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var movement models.DrawerMovement
+		if err := json.NewDecoder(r.Body).Decode(&movement); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if movement.Type != models.DrawerPaidIn && movement.Type != models.DrawerPaidOut {
+			apierror.Respond(w, r, "Type must be paid_in or paid_out", http.StatusBadRequest)
+			return
+		}
+		if movement.Amount <= 0 || movement.Reason == "" {
+			apierror.Respond(w, r, "A positive amount and a reason are required", http.StatusBadRequest)
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		session, ok := loadDrawerSession(w, r, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if !ok || !canHandleDrawer(w, r, tx, session) {
+			tx.Rollback()
+			return
+		}
+		if session.IsClosed() {
+			tx.Rollback()
+			apierror.Respond(w, r, "Drawer session is closed", http.StatusConflict)
+			return
+		}
+		if movement.Type == models.DrawerPaidOut && movement.Amount > session.Expected() {
+			tx.Rollback()
+			apierror.Respond(w, r, "Paid-out exceeds the cash in the drawer", http.StatusBadRequest)
+			return
+		}
+
+		movement.ID = 0
+		movement.DrawerSessionID = session.ID
+		movement.RecordedBy, _ = r.Context().Value(auth.ContextUsername).(string)
+		if err := tx.Create(&movement).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(movement)
+	}
+}
+
+// CloseDrawerSession records the counted cash and fixes the expected amount and
+// over/short. A closed session takes no further payments or movements.
+func CloseDrawerSession(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CloseDrawerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if req.CountedCash == nil || *req.CountedCash < 0 {
+			apierror.Respond(w, r, "Counted cash is required", http.StatusBadRequest)
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		session, ok := loadDrawerSession(w, r, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if !ok || !canHandleDrawer(w, r, tx, session) {
+			tx.Rollback()
+			return
+		}
+		if session.IsClosed() {
+			tx.Rollback()
+			apierror.Respond(w, r, "Drawer session is already closed", http.StatusConflict)
+			return
+		}
+
+		session.Close(*req.CountedCash, time.Now())
+		if req.Note != "" {
+			session.Note = req.Note
+		}
+		err := tx.Model(&session).Select("ClosedAt", "CountedCash", "ExpectedCash", "OverShort", "Note").Updates(&session).Error
+		if err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(drawerReport(session))
+	}
+}
+
+func drawerReport(session models.DrawerSession) DrawerReport {
+	return DrawerReport{
+		DrawerSession: session,
+		CashSales:     session.CashSales(),
+		CashRefunds:   session.CashRefunds(),
+		PaidIn:        session.PaidIn(),
+		PaidOut:       session.PaidOut(),
+		Expected:      session.Expected(),
+	}
+}
+
+// canHandleDrawer refuses the request unless it comes from the cashier who opened
+// the drawer or from a manager
+func canHandleDrawer(w http.ResponseWriter, r *http.Request, tx *gorm.DB, session models.DrawerSession) bool {
+	user, ok := currentUser(r, tx)
+	if !ok || (user.ID != session.UserID && !user.CanApprove()) {
+		apierror.Respond(w, r, "Only the drawer's cashier or a manager can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func loadDrawerSession(w http.ResponseWriter, r *http.Request, tx *gorm.DB) (models.DrawerSession, bool) {
+	var session models.DrawerSession
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid drawer session ID", http.StatusBadRequest)
+		return session, false
+	}
+
+	result := tx.Preload("Movements").Preload("Payments").
+		Preload("Refunds", "type = ? AND method = ?", models.AdjustmentRefund, models.PaymentCash).
+		First(&session, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Drawer session not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return session, false
+	}
+	return session, true
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+)
+
+// NotFound answers requests for routes that do not exist
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	apierror.Respond(w, r, "Route not found", http.StatusNotFound)
+}
+
+// MethodNotAllowed answers requests for a route with a method it does not take
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	apierror.Respond(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+}
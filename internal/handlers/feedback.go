@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FeedbackLink struct {
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// FeedbackForm is what a guest sees when opening a feedback link
+type FeedbackForm struct {
+	OrderID   uint               `json:"order_id"`
+	Items     []FeedbackFormItem `json:"items"`
+	Submitted bool               `json:"submitted"`
+}
+
+type FeedbackFormItem struct {
+	MenuItemID uint   `json:"menu_item_id"`
+	Name       string `json:"name"`
+}
+
+type RatingSummary struct {
+	Date       string  `json:"date,omitempty"`
+	MenuItemID uint    `json:"menu_item_id,omitempty"`
+	Name       string  `json:"name,omitempty"`
+	Average    float64 `json:"average"`
+	Count      int     `json:"count"`
+}
+
+type RatingsReport struct {
+	Days  []RatingSummary `json:"days"`
+	Items []RatingSummary `json:"items"`
+}
+
+// CreateFeedbackLink returns the order's feedback link, creating it once the order is paid
+func CreateFeedbackLink(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		order, err := orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			if order.PaidAmount() <= 0 || order.Balance() > 0.005 {
+				return apierror.New(http.StatusConflict, "Feedback links are only sent for paid orders")
+			}
+			if order.FeedbackToken != nil {
+				return nil
+			}
+			token, err := newFeedbackToken()
+			if err != nil {
+				return err
+			}
+			order.FeedbackToken = &token
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(FeedbackLink{Token: *order.FeedbackToken, URL: "/api/feedback/" + *order.FeedbackToken})
+	}
+}
+
+// GetFeedbackForm lists the items a guest can rate through their feedback link
+func GetFeedbackForm(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		order, ok := loadOrderByFeedbackToken(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		var submitted int64
+		if err := db.WithContext(r.Context()).Model(&models.Feedback{}).Where("order_id = ?", order.ID).Count(&submitted).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(feedbackForm(order, submitted > 0))
+	}
+}
+
+// SubmitFeedback stores a guest's order and item ratings. Each order takes one submission.
+func SubmitFeedback(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var feedback models.Feedback
+		if err := json.NewDecoder(r.Body).Decode(&feedback); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if feedback.Rating < 1 || feedback.Rating > 5 {
+			apierror.Respond(w, r, "Rating must be between 1 and 5", http.StatusBadRequest)
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		order, ok := loadOrderByFeedbackToken(w, r, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+		if !ok {
+			tx.Rollback()
+			return
+		}
+
+		var submitted int64
+		if err := tx.Model(&models.Feedback{}).Where("order_id = ?", order.ID).Count(&submitted).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if submitted > 0 {
+			tx.Rollback()
+			apierror.Respond(w, r, "Feedback has already been submitted for this order", http.StatusConflict)
+			return
+		}
+
+		ordered := make(map[uint]bool)
+		for _, detail := range order.OrderDetails {
+			ordered[detail.MenuItemID] = true
+		}
+		rated := make(map[uint]bool)
+		for i := range feedback.ItemRatings {
+			rating := &feedback.ItemRatings[i]
+			if !ordered[rating.MenuItemID] || rated[rating.MenuItemID] {
+				tx.Rollback()
+				apierror.Respond(w, r, "Item ratings must be for items on the order, once each", http.StatusBadRequest)
+				return
+			}
+			if rating.Rating < 1 || rating.Rating > 5 {
+				tx.Rollback()
+				apierror.Respond(w, r, "Rating must be between 1 and 5", http.StatusBadRequest)
+				return
+			}
+			rated[rating.MenuItemID] = true
+			rating.ID = 0
+			rating.LocationID = order.LocationID
+		}
+
+		feedback.ID = 0
+		feedback.OrderID = order.ID
+		feedback.LocationID = order.LocationID
+		if err := tx.Create(&feedback).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(feedback)
+	}
+}
+
+// GetRatingsReport returns average order ratings per day and item ratings per menu item
+func GetRatingsReport(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parsePeriod(w, r)
+		if !ok {
+			return
+		}
+
+		report := RatingsReport{Days: []RatingSummary{}, Items: []RatingSummary{}}
+		err := db.WithContext(r.Context()).Model(&models.Feedback{}).
+			Select("TO_CHAR(created_at, 'YYYY-MM-DD') AS date, AVG(rating) AS average, COUNT(*) AS count").
+			Where("created_at >= ? AND created_at < ?", from, to).
+			Group("date").Order("date").Scan(&report.Days).Error
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		err = db.WithContext(r.Context()).Model(&models.ItemRating{}).
+			Select("item_ratings.menu_item_id, menu_items.name, AVG(item_ratings.rating) AS average, COUNT(*) AS count").
+			Joins("JOIN menu_items ON menu_items.id = item_ratings.menu_item_id").
+			Where("item_ratings.created_at >= ? AND item_ratings.created_at < ?", from, to).
+			Group("item_ratings.menu_item_id, menu_items.name").Order("average desc").Scan(&report.Items).Error
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		for i := range report.Days {
+			report.Days[i].Average = roundRating(report.Days[i].Average)
+		}
+		for i := range report.Items {
+			report.Items[i].Average = roundRating(report.Items[i].Average)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func feedbackForm(order models.Order, submitted bool) FeedbackForm {
+	form := FeedbackForm{OrderID: order.ID, Submitted: submitted, Items: []FeedbackFormItem{}}
+	seen := make(map[uint]bool)
+	for _, detail := range order.OrderDetails {
+		if seen[detail.MenuItemID] {
+			continue
+		}
+		seen[detail.MenuItemID] = true
+		form.Items = append(form.Items, FeedbackFormItem{MenuItemID: detail.MenuItemID, Name: detail.MenuItemName})
+	}
+	return form
+}
+
+func loadOrderByFeedbackToken(w http.ResponseWriter, r *http.Request, tx *gorm.DB) (models.Order, bool) {
+	var order models.Order
+	token := mux.Vars(r)["token"]
+	result := tx.Preload("OrderDetails").Where("feedback_token = ?", token).First(&order)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Feedback link not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return order, false
+	}
+	return order, true
+}
+
+func newFeedbackToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func roundRating(average float64) float64 {
+	return math.Round(average*10) / 10
+}
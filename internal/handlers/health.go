@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/migrations"
+)
+
+// readinessCheckTimeout bounds each dependency check of /readyz
+const readinessCheckTimeout = 2 * time.Second
+
+// Server lifecycle states reported by /readyz
+const (
+	serverStarting int32 = iota
+	serverServing
+	serverShuttingDown
+)
+
+// Readiness tracks whether the server should receive traffic. It starts out
+// not ready and stops being ready once shutdown begins.
+type Readiness struct {
+	state atomic.Int32
+}
+
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// Ready marks startup as finished
+func (r *Readiness) Ready() {
+	r.state.CompareAndSwap(serverStarting, serverServing)
+}
+
+// ShuttingDown marks the server as draining; it is not ready again
+func (r *Readiness) ShuttingDown() {
+	r.state.Store(serverShuttingDown)
+}
+
+type ComponentStatus struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms,omitempty"`
+	Version   *int64  `json:"version,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// Livez reports that the process is up, without checking its dependencies
+func Livez(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// Readyz reports whether the server can take traffic: it has finished starting,
+// is not shutting down, reaches the database and finds the schema version it expects
+func Readyz(readiness *Readiness, db *database.Manager, migrator *migrations.Migrator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		response := ReadinessResponse{Status: "ready", Components: make(map[string]ComponentStatus)}
+
+		switch readiness.state.Load() {
+		case serverStarting:
+			response.Components["server"] = ComponentStatus{Status: "starting"}
+		case serverShuttingDown:
+			response.Components["server"] = ComponentStatus{Status: "shutting_down"}
+		default:
+			response.Components["server"] = ComponentStatus{Status: "ok"}
+
+			response.Components["database"] = checkComponent(r.Context(), func(ctx context.Context) (*int64, error) {
+				return nil, db.Ping(ctx)
+			})
+			response.Components["migrations"] = checkComponent(r.Context(), func(ctx context.Context) (*int64, error) {
+				m := migrator.WithContext(ctx)
+				if err := m.Check(); err != nil {
+					return nil, err
+				}
+				version := m.Latest()
+				return &version, nil
+			})
+		}
+
+		status := http.StatusOK
+		for _, component := range response.Components {
+			if component.Status != "ok" {
+				response.Status = "unavailable"
+				status = http.StatusServiceUnavailable
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	}
+}
+
+// checkComponent runs check with a timeout and reports how it went
+func checkComponent(ctx context.Context, check func(context.Context) (*int64, error)) ComponentStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	version, err := check(ctx)
+	component := ComponentStatus{
+		Status:    "ok",
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1e3,
+		Version:   version,
+	}
+	if err != nil {
+		component.Status = "error"
+		component.Error = err.Error()
+	}
+	return component
+}
@@ -0,0 +1,504 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/einvoice"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	trackPattern  = regexp.MustCompile(`^[A-Z]{2}$`)
+	periodPattern = regexp.MustCompile(`^[0-9]{3}(02|04|06|08|10|12)$`)
+)
+
+type InvoiceRequest struct {
+	BuyerTaxID   string `json:"buyer_tax_id"`
+	CarrierType  string `json:"carrier_type"`
+	CarrierID    string `json:"carrier_id"`
+	DonationCode string `json:"donation_code"`
+}
+
+type VoidInvoiceRequest struct {
+	Reason string `json:"reason"`
+}
+
+type AllowanceRequest struct {
+	Amount int64  `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+func GetInvoiceRanges(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var ranges []models.InvoiceNumberRange
+		result := db.WithContext(r.Context()).Order("period desc, id").Find(&ranges)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ranges)
+	}
+}
+
+func CreateInvoiceRange(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var numberRange models.InvoiceNumberRange
+		if err := json.NewDecoder(r.Body).Decode(&numberRange); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if !periodPattern.MatchString(numberRange.Period) {
+			apierror.Respond(w, r, "Period must be the ROC year and even month ending the period, e.g. 11312", http.StatusBadRequest)
+			return
+		}
+		if !trackPattern.MatchString(numberRange.Track) {
+			apierror.Respond(w, r, "Track must be two uppercase letters", http.StatusBadRequest)
+			return
+		}
+		if numberRange.StartNo < 0 || numberRange.EndNo > 99999999 || numberRange.StartNo > numberRange.EndNo {
+			apierror.Respond(w, r, "Invalid number range", http.StatusBadRequest)
+			return
+		}
+
+		numberRange.ID = 0
+		numberRange.NextNo = numberRange.StartNo
+		result := db.WithContext(r.Context()).Create(&numberRange)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(numberRange)
+	}
+}
+
+func GetInvoice(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invoice, ok := loadInvoice(w, r, db.WithContext(r.Context()))
+		if !ok {
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	}
+}
+
+// IssueInvoice issues the uniform invoice of a paid order and uploads it as an F0401 message
+func IssueInvoice(store repository.Store, db *database.Manager, uploader einvoice.Uploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req InvoiceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+		if err := validateInvoiceRequest(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+		order, err := store.Orders.Get(r.Context(), uint(id))
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		if order.Status == models.OrderCancelled || order.Balance() > 0.005 {
+			apierror.Respond(w, r, "Invoices can only be issued for paid orders", http.StatusConflict)
+			return
+		}
+
+		info, err := store.RestaurantInfo.ForLocation(r.Context(), order.LocationID)
+		if err != nil {
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+		if !einvoice.ValidTaxID(info.TaxID) {
+			apierror.Respond(w, r, "The restaurant's business tax ID is not configured", http.StatusConflict)
+			return
+		}
+
+		randomNumber, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		now := time.Now()
+		invoice := models.Invoice{
+			LocationID:   order.LocationID,
+			OrderID:      order.ID,
+			Period:       einvoice.Period(now),
+			IssuedAt:     now,
+			RandomNumber: fmt.Sprintf("%04d", randomNumber.Int64()),
+			SellerTaxID:  info.TaxID,
+			SellerName:   info.Name,
+			BuyerTaxID:   req.BuyerTaxID,
+			CarrierType:  req.CarrierType,
+			CarrierID:    req.CarrierID,
+			DonationCode: req.DonationCode,
+			TotalAmount:  int64(math.Round(order.TotalAmount)),
+			Status:       models.InvoiceIssued,
+			Items:        invoiceItems(order),
+		}
+		invoice.SalesAmount, invoice.TaxAmount = einvoice.SplitTax(invoice.TotalAmount, req.BuyerTaxID != einvoice.ConsumerBuyerID)
+
+		// An order has one issued invoice at most, so of two requests issuing one only the first is saved
+		tx := db.WithContext(r.Context()).Begin()
+		invoice.Number, err = einvoice.NextNumber(tx, invoice.Period)
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, einvoice.ErrNoNumbersLeft) {
+				apierror.Write(w, r, apierror.Wrap(err, http.StatusConflict))
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+		if err := tx.Create(&invoice).Error; err != nil {
+			tx.Rollback()
+			if apierror.IsUniqueViolation(err) {
+				apierror.Respond(w, r, "The order already has an invoice", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		uploadInvoice(r.Context(), db, uploader, &invoice)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(invoice)
+	}
+}
+
+// VoidInvoice voids an invoice of the current period and uploads an F0501 message.
+// Invoices of earlier periods are corrected with allowances instead.
+func VoidInvoice(db *database.Manager, uploader einvoice.Uploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req VoidInvoiceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+		if req.Reason == "" {
+			apierror.Respond(w, r, "A reason is required", http.StatusBadRequest)
+			return
+		}
+
+		// The invoice stays locked until it is voided, so an allowance cannot be issued against it meanwhile
+		tx := db.WithContext(r.Context()).Begin()
+		invoice, ok := loadInvoiceForUpdate(w, r, tx)
+		if !ok {
+			tx.Rollback()
+			return
+		}
+
+		now := time.Now()
+		if invoice.Status != models.InvoiceIssued {
+			tx.Rollback()
+			apierror.Respond(w, r, "Invoice is already voided", http.StatusConflict)
+			return
+		}
+		if len(invoice.Allowances) > 0 {
+			tx.Rollback()
+			apierror.Respond(w, r, "Invoices with allowances cannot be voided", http.StatusConflict)
+			return
+		}
+		if invoice.Period != einvoice.Period(now) {
+			tx.Rollback()
+			apierror.Respond(w, r, "Invoices of earlier periods need an allowance instead", http.StatusConflict)
+			return
+		}
+
+		invoice.Status = models.InvoiceVoided
+		invoice.VoidedAt = &now
+		invoice.VoidReason = req.Reason
+		err := tx.Model(&invoice).Select("Status", "VoidedAt", "VoidReason").Updates(&invoice).Error
+		if err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		uploadInvoiceVoid(r.Context(), db, uploader, &invoice)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	}
+}
+
+// CreateInvoiceAllowance issues an allowance (折讓) reducing an invoice and uploads a G0401 message
+func CreateInvoiceAllowance(db *database.Manager, uploader einvoice.Uploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req AllowanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+		if req.Amount <= 0 || req.Reason == "" {
+			apierror.Respond(w, r, "A positive amount and a reason are required", http.StatusBadRequest)
+			return
+		}
+
+		// The invoice stays locked until the allowance is saved, so two allowances cannot overdraw it
+		tx := db.WithContext(r.Context()).Begin()
+		invoice, ok := loadInvoiceForUpdate(w, r, tx)
+		if !ok {
+			tx.Rollback()
+			return
+		}
+
+		if invoice.Status != models.InvoiceIssued {
+			tx.Rollback()
+			apierror.Respond(w, r, "Allowances cannot be issued against a voided invoice", http.StatusConflict)
+			return
+		}
+		allowed := int64(0)
+		for _, allowance := range invoice.Allowances {
+			allowed += allowance.Amount
+		}
+		if allowed+req.Amount > invoice.TotalAmount {
+			tx.Rollback()
+			apierror.Respond(w, r, "Allowances exceed the invoice amount", http.StatusBadRequest)
+			return
+		}
+
+		allowance, err := einvoice.CreateAllowance(tx, invoice, req.Amount, req.Reason, time.Now())
+		if err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		uploadAllowance(r.Context(), db, uploader, invoice, &allowance)
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(allowance)
+	}
+}
+
+// RetryInvoiceUploads uploads every invoice, void and allowance the platform has not received yet
+func RetryInvoiceUploads(db *database.Manager, uploader einvoice.Uploader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var invoices []models.Invoice
+		err := db.WithContext(r.Context()).Preload("Items").Preload("Allowances").
+			Where("uploaded_at IS NULL OR (status = ? AND void_uploaded_at IS NULL) OR id IN (?)",
+				models.InvoiceVoided,
+				db.WithContext(r.Context()).Model(&models.InvoiceAllowance{}).Select("invoice_id").Where("uploaded_at IS NULL")).
+			Find(&invoices).Error
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		pending := 0
+		for i := range invoices {
+			invoice := &invoices[i]
+			if invoice.UploadedAt == nil && !uploadInvoice(r.Context(), db, uploader, invoice) {
+				pending++
+				continue
+			}
+			if invoice.Status == models.InvoiceVoided && invoice.VoidUploadedAt == nil && !uploadInvoiceVoid(r.Context(), db, uploader, invoice) {
+				pending++
+			}
+			for j := range invoice.Allowances {
+				if invoice.Allowances[j].UploadedAt == nil && !uploadAllowance(r.Context(), db, uploader, *invoice, &invoice.Allowances[j]) {
+					pending++
+				}
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"pending": pending})
+	}
+}
+
+func validateInvoiceRequest(req *InvoiceRequest) error {
+	if req.BuyerTaxID == "" {
+		req.BuyerTaxID = einvoice.ConsumerBuyerID
+	} else if !einvoice.ValidTaxID(req.BuyerTaxID) {
+		return errors.New("invalid buyer tax ID")
+	}
+
+	if req.CarrierType != "" || req.CarrierID != "" {
+		if !einvoice.ValidCarrier(req.CarrierType, req.CarrierID) {
+			return errors.New("invalid carrier")
+		}
+	}
+	if req.DonationCode != "" {
+		if !einvoice.ValidDonationCode(req.DonationCode) {
+			return errors.New("invalid donation code")
+		}
+		if req.CarrierType != "" {
+			return errors.New("an invoice cannot both use a carrier and be donated")
+		}
+		if req.BuyerTaxID != einvoice.ConsumerBuyerID {
+			return errors.New("invoices with a buyer tax ID cannot be donated")
+		}
+	}
+	return nil
+}
+
+// invoiceItems lists the order lines, with discounts as negative lines so the items add up to the total
+func invoiceItems(order models.Order) []models.InvoiceItem {
+	var items []models.InvoiceItem
+	for _, detail := range order.OrderDetails {
+		items = append(items, models.InvoiceItem{
+			Description: detail.MenuItemName,
+			Quantity:    detail.Quantity,
+			UnitPrice:   detail.UnitPrice,
+			Amount:      detail.Subtotal,
+		})
+	}
+	for _, adjustment := range order.Adjustments {
+		if adjustment.Type != models.AdjustmentVoid {
+			continue
+		}
+		items = append(items, models.InvoiceItem{
+			Description: "Void " + adjustment.ReasonCode,
+			Quantity:    1,
+			UnitPrice:   -adjustment.Amount,
+			Amount:      -adjustment.Amount,
+		})
+	}
+	for _, discount := range order.Discounts {
+		items = append(items, models.InvoiceItem{
+			Description: discount.Description,
+			Quantity:    1,
+			UnitPrice:   -discount.Amount,
+			Amount:      -discount.Amount,
+		})
+	}
+	return items
+}
+
+// loadInvoiceForUpdate loads the invoice named in the request, locking it until tx ends
+func loadInvoiceForUpdate(w http.ResponseWriter, r *http.Request, tx *gorm.DB) (models.Invoice, bool) {
+	return loadInvoice(w, r, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+}
+
+func loadInvoice(w http.ResponseWriter, r *http.Request, tx *gorm.DB) (models.Invoice, bool) {
+	var invoice models.Invoice
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid invoice ID", http.StatusBadRequest)
+		return invoice, false
+	}
+
+	result := tx.Preload("Items").Preload("Allowances").First(&invoice, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Invoice not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return invoice, false
+	}
+	return invoice, true
+}
+
+// The upload helpers record when the platform received a document. Failures are only
+// logged; the document stays pending until RetryInvoiceUploads succeeds.
+
+func uploadInvoice(ctx context.Context, db *database.Manager, uploader einvoice.Uploader, invoice *models.Invoice) bool {
+	document, err := einvoice.InvoiceXML(*invoice)
+	if err == nil {
+		err = uploader.Upload(ctx, einvoice.MessageInvoice, invoice.Number, document)
+	}
+	if err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to upload invoice", "invoice", invoice.Number, "error", err)
+		return false
+	}
+	return markUploaded(ctx, db, invoice, "UploadedAt", &invoice.UploadedAt)
+}
+
+func uploadInvoiceVoid(ctx context.Context, db *database.Manager, uploader einvoice.Uploader, invoice *models.Invoice) bool {
+	document, err := einvoice.VoidXML(*invoice)
+	if err == nil {
+		err = uploader.Upload(ctx, einvoice.MessageVoid, invoice.Number, document)
+	}
+	if err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to upload invoice void", "invoice", invoice.Number, "error", err)
+		return false
+	}
+	return markUploaded(ctx, db, invoice, "VoidUploadedAt", &invoice.VoidUploadedAt)
+}
+
+func uploadAllowance(ctx context.Context, db *database.Manager, uploader einvoice.Uploader, invoice models.Invoice, allowance *models.InvoiceAllowance) bool {
+	document, err := einvoice.AllowanceXML(invoice, *allowance)
+	if err == nil {
+		err = uploader.Upload(ctx, einvoice.MessageAllowance, allowance.Number, document)
+	}
+	if err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to upload allowance", "allowance", allowance.Number, "error", err)
+		return false
+	}
+	return markUploaded(ctx, db, allowance, "UploadedAt", &allowance.UploadedAt)
+}
+
+// uploadRefundAllowances uploads the allowances a refund issued against the
+// order's invoice, if it has one
+func uploadRefundAllowances(ctx context.Context, db *database.Manager, uploader einvoice.Uploader, orderID uint) {
+	var invoice models.Invoice
+	err := db.WithContext(ctx).Preload("Items").Preload("Allowances").
+		Where("order_id = ? AND status = ?", orderID, models.InvoiceIssued).Find(&invoice).Error
+	if err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to load the refunded invoice", "order", orderID, "error", err)
+		return
+	}
+	for i := range invoice.Allowances {
+		if invoice.Allowances[i].UploadedAt == nil {
+			uploadAllowance(ctx, db, uploader, invoice, &invoice.Allowances[i])
+		}
+	}
+}
+
+func markUploaded(ctx context.Context, db *database.Manager, model interface{}, field string, uploadedAt **time.Time) bool {
+	now := time.Now()
+	if err := db.WithContext(ctx).Model(model).Update(field, now).Error; err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to record upload", "error", err)
+		return false
+	}
+	*uploadedAt = &now
+	return true
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MenuItemPriceRequest struct {
+	Price float64 `json:"price"`
+}
+
+func GetLocations(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var locations []models.Location
+		result := db.WithContext(r.Context()).Order("name").Find(&locations)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(locations)
+	}
+}
+
+func CreateLocation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isHeadOffice(r.Context()) {
+			apierror.Respond(w, r, "Only head office can manage locations", http.StatusForbidden)
+			return
+		}
+
+		var location models.Location
+		if err := json.NewDecoder(r.Body).Decode(&location); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if location.Name == "" || location.Code == "" {
+			apierror.Respond(w, r, "Name and code are required", http.StatusBadRequest)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Create(&location)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(location)
+	}
+}
+
+func UpdateLocation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isHeadOffice(r.Context()) {
+			apierror.Respond(w, r, "Only head office can manage locations", http.StatusForbidden)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+
+		var updatedLocation models.Location
+		if err := json.NewDecoder(r.Body).Decode(&updatedLocation); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		var location models.Location
+		result := db.WithContext(r.Context()).First(&location, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Location not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		location.Name = updatedLocation.Name
+		location.Code = updatedLocation.Code
+		location.Address = updatedLocation.Address
+		location.Phone = updatedLocation.Phone
+
+		result = db.WithContext(r.Context()).Save(&location)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(location)
+	}
+}
+
+func DeleteLocation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isHeadOffice(r.Context()) {
+			apierror.Respond(w, r, "Only head office can manage locations", http.StatusForbidden)
+			return
+		}
+
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Delete(&models.Location{}, id)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, "Location not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Location deleted successfully"})
+	}
+}
+
+// SetMenuItemPrice overrides the master menu price of an item at one location
+func SetMenuItemPrice(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		locationID, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+		menuItemID, err := strconv.Atoi(vars["menuItemId"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid menu item ID", http.StatusBadRequest)
+			return
+		}
+
+		if callerLocation, ok := auth.LocationIDFromContext(r.Context()); ok && callerLocation != uint(locationID) {
+			apierror.Respond(w, r, "Cannot change prices of another location", http.StatusForbidden)
+			return
+		}
+
+		var req MenuItemPriceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+		if req.Price < 0 {
+			apierror.Respond(w, r, "Price must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		var menuItem models.MenuItem
+		result := db.WithContext(r.Context()).First(&menuItem, menuItemID)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Menu item not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		price := models.MenuItemPrice{
+			LocationID: uint(locationID),
+			MenuItemID: menuItem.ID,
+			Price:      req.Price,
+		}
+		result = db.WithContext(r.Context()).Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "location_id"}, {Name: "menu_item_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"price", "updated_at", "deleted_at"}),
+		}).Create(&price)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(price)
+	}
+}
+
+// DeleteMenuItemPrice removes a price override so the location falls back to the master price
+func DeleteMenuItemPrice(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		locationID, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+			return
+		}
+		menuItemID, err := strconv.Atoi(vars["menuItemId"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid menu item ID", http.StatusBadRequest)
+			return
+		}
+
+		if callerLocation, ok := auth.LocationIDFromContext(r.Context()); ok && callerLocation != uint(locationID) {
+			apierror.Respond(w, r, "Cannot change prices of another location", http.StatusForbidden)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Unscoped().
+			Where("location_id = ? AND menu_item_id = ?", locationID, menuItemID).
+			Delete(&models.MenuItemPrice{})
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, "Price override not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Price override deleted successfully"})
+	}
+}
+
+// isHeadOffice reports whether the caller is not limited to a single location
+func isHeadOffice(ctx context.Context) bool {
+	if auth.UsernameFromContext(ctx) == "" {
+		return false
+	}
+	_, scoped := auth.LocationIDFromContext(ctx)
+	return !scoped
+}
+
+// callerLocation returns the caller's location, or for head office the location the request names
+func callerLocation(ctx context.Context, requested *uint) *uint {
+	if locationID, ok := auth.LocationIDFromContext(ctx); ok {
+		return &locationID
+	}
+	return requested
+}
+
+// canModify reports whether the caller may change the record; locations cannot change master data
+func canModify(ctx context.Context, record models.MasterRecord) bool {
+	return isHeadOffice(ctx) || !record.IsMasterRecord()
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/loyalty"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+type RedeemPointsRequest struct {
+	Points int `json:"points"`
+}
+
+// RedeemPoints takes loyalty points off the order's customer and adds their
+// value to the order as a discount
+func RedeemPoints(orders repository.Orders, infos repository.RestaurantInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var req RedeemPointsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if req.Points <= 0 {
+			apierror.Respond(w, r, "Points must be positive", http.StatusBadRequest)
+			return
+		}
+
+		order, err := orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			if order.CustomerID == nil {
+				return apierror.New(http.StatusConflict, "Order has no customer")
+			}
+			if order.Status == models.OrderCancelled || order.PaidAmount() > 0 {
+				return apierror.New(http.StatusConflict, "Points can only be redeemed on unpaid orders")
+			}
+
+			info, err := infos.ForLocation(r.Context(), order.LocationID)
+			if err != nil {
+				return restaurantInfoError(err)
+			}
+
+			discount := models.OrderDiscount{
+				Description:    fmt.Sprintf("Loyalty points (%d)", req.Points),
+				Amount:         info.RedemptionValue(req.Points),
+				RedeemedPoints: req.Points,
+			}
+			if discount.Amount > order.ItemsTotal()-order.DiscountTotal() {
+				return apierror.New(http.StatusBadRequest, "Points are worth more than the order")
+			}
+
+			order.Discounts = append(order.Discounts, discount)
+			order.ApplyTotals(info.TaxSettings)
+			return nil
+		})
+		if errors.Is(err, loyalty.ErrNotEnoughPoints) {
+			apierror.Respond(w, r, "Customer does not have enough points", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
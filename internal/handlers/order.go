@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/kitchen"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+// orderTransitions lists the statuses an order may move to from each status
+var orderTransitions = map[string][]string{
+	models.OrderScheduled: {models.OrderCancelled},
+	models.OrderPending:   {models.OrderPreparing, models.OrderCancelled},
+	models.OrderPreparing: {models.OrderServed, models.OrderCancelled},
+	models.OrderServed:    {models.OrderCompleted},
+}
+
+type OrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+type PickupSlot struct {
+	Start     time.Time `json:"start"`
+	Remaining int       `json:"remaining"`
+}
+
+func GetOrders(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter := repository.OrderFilter{
+			Status:    r.URL.Query().Get("status"),
+			OrderType: r.URL.Query().Get("type"),
+		}
+
+		list, err := orders.List(r.Context(), filter)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	}
+}
+
+func GetOrder(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		order, err := orders.Get(r.Context(), uint(id))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "Order not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// GetKitchenOrders lists the orders the kitchen is working on, oldest first
+func GetKitchenOrders(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		queue, err := orders.KitchenQueue(r.Context())
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(queue)
+	}
+}
+
+// GetPickupSlots lists the open pickup slots of a day with their remaining capacity
+func GetPickupSlots(orders repository.Orders, infos repository.RestaurantInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		day, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("date"), models.RestaurantZone)
+		if err != nil {
+			apierror.Respond(w, r, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		info, err := infos.Get(r.Context())
+		if err != nil {
+			apierror.Respond(w, r, "Restaurant info not found", http.StatusNotFound)
+			return
+		}
+
+		end := day.AddDate(0, 0, 1)
+		scheduled, err := orders.Scheduled(r.Context(), day, end)
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		booked := make(map[time.Time]int)
+		for _, order := range scheduled {
+			booked[models.PickupSlot(order.ScheduledFor.In(models.RestaurantZone))]++
+		}
+
+		slots := []PickupSlot{}
+		for start := day; start.Before(end); start = start.Add(models.PickupSlotLength) {
+			if !info.OpeningHours.IsOpen(start) {
+				continue
+			}
+			remaining := -1
+			if info.SlotCapacity > 0 {
+				remaining = info.SlotCapacity - booked[start]
+				if remaining < 0 {
+					remaining = 0
+				}
+			}
+			slots = append(slots, PickupSlot{Start: start, Remaining: remaining})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(slots)
+	}
+}
+
+// CreateOrder prices an order from the menu and saves it, sending it straight to
+// the kitchen when it is due
+func CreateOrder(store repository.Store, printer *kitchen.PrintQueue) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var order models.Order
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if order.OrderType == "" {
+			order.OrderType = models.OrderTypeDineIn
+		}
+		if err := validateOrder(order); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		now := time.Now()
+		order.LocationID = callerLocation(r.Context(), order.LocationID)
+		info, err := store.RestaurantInfo.ForLocation(r.Context(), order.LocationID)
+		if err != nil {
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+
+		if order.IsOffPremises() {
+			if order.ScheduledFor != nil {
+				// Kept in the restaurant's zone, whatever offset the client sent
+				scheduledFor := order.ScheduledFor.In(models.RestaurantZone)
+				order.ScheduledFor = &scheduledFor
+			}
+			if order.ScheduledFor == nil {
+				order.ScheduledFor = &now
+			} else if order.ScheduledFor.Before(now) {
+				apierror.Respond(w, r, "Pickup or delivery time must be in the future", http.StatusBadRequest)
+				return
+			} else if !info.OpeningHours.IsOpen(*order.ScheduledFor) {
+				apierror.Write(w, r, apierror.Wrap(errRestaurantClosed, http.StatusConflict))
+				return
+			}
+		} else {
+			order.ScheduledFor = nil
+		}
+
+		order.Discounts = nil
+		order.Payments = nil
+		order.Adjustments = nil
+		order.ServerID = nil
+		if username := auth.UsernameFromContext(r.Context()); username != "" {
+			if server, err := store.Users.GetByUsername(r.Context(), username); err == nil {
+				order.ServerID = &server.ID
+			}
+		}
+		if err := priceOrder(r.Context(), store.Menu, &order); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+		order.ApplyTotals(info.TaxSettings)
+
+		var releaseAt *time.Time
+		if kitchen.IsDue(order, info.KitchenLead(), now) {
+			releaseAt = &now
+		}
+		tickets, err := store.Orders.Create(r.Context(), &order, info.SlotCapacity, releaseAt)
+		switch {
+		case errors.Is(err, repository.ErrSlotFull):
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusConflict))
+			return
+		case errors.Is(err, repository.ErrCustomerNotFound):
+			apierror.Respond(w, r, "Customer not found", http.StatusBadRequest)
+			return
+		case err != nil:
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// The order is taken even if a printer is offline; tickets can be reprinted
+		printer.Enqueue(r.Context(), kitchen.TicketIDs(tickets))
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+func UpdateOrderStatus(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var req OrderStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		order, err := orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			if !canTransition(order.Status, req.Status) {
+				return apierror.New(http.StatusConflict, fmt.Sprintf("Cannot change order from %s to %s", order.Status, req.Status))
+			}
+
+			order.Status = req.Status
+			now := time.Now()
+			switch order.Status {
+			case models.OrderServed:
+				order.ServedAt = &now
+			case models.OrderCompleted:
+				order.CompletedAt = &now
+			}
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+func validateOrder(order models.Order) error {
+	switch order.OrderType {
+	case models.OrderTypeDineIn:
+		if order.TableNumber == "" {
+			return errors.New("table number is required for dine-in orders")
+		}
+	case models.OrderTypeTakeout:
+		if order.CustomerName == "" || order.CustomerPhone == "" {
+			return errors.New("customer name and phone are required for takeout orders")
+		}
+	case models.OrderTypeDelivery:
+		if order.CustomerName == "" || order.CustomerPhone == "" || order.DeliveryAddress == "" {
+			return errors.New("customer name, phone and delivery address are required for delivery orders")
+		}
+	default:
+		return fmt.Errorf("unknown order type %q", order.OrderType)
+	}
+
+	if len(order.OrderDetails) == 0 {
+		return errors.New("an order needs at least one item")
+	}
+	for _, detail := range order.OrderDetails {
+		if detail.Quantity <= 0 {
+			return errors.New("item quantities must be positive")
+		}
+	}
+	return nil
+}
+
+// priceOrder fills in unit prices, add-on names and prices and subtotals from the
+// menu as the order's location sees it, at that location's prices
+func priceOrder(ctx context.Context, menu repository.Menu, order *models.Order) error {
+	if order.LocationID != nil {
+		ctx = auth.WithLocationID(ctx, *order.LocationID)
+	}
+	byID := make(map[uint]models.MenuItem)
+	for _, detail := range order.OrderDetails {
+		if _, ok := byID[detail.MenuItemID]; ok {
+			continue
+		}
+		item, err := menu.GetItem(ctx, detail.MenuItemID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		byID[item.ID] = item
+	}
+
+	for i := range order.OrderDetails {
+		detail := &order.OrderDetails[i]
+		item, ok := byID[detail.MenuItemID]
+		if !ok {
+			return fmt.Errorf("menu item %d not found", detail.MenuItemID)
+		}
+		if !item.IsAvailable {
+			return fmt.Errorf("%s is not available", item.Name)
+		}
+
+		detail.ID = 0
+		detail.MenuItemName = item.Name
+		detail.UnitPrice = item.Price
+		for j := range detail.SelectedAddOns {
+			selected := &detail.SelectedAddOns[j]
+			addOn, ok := findAddOn(item.AddOns, selected.AddOnID)
+			if !ok {
+				return fmt.Errorf("add-on %d is not offered for %s", selected.AddOnID, item.Name)
+			}
+			selected.ID = 0
+			selected.Name = addOn.Name
+			selected.Price = addOn.Price
+			detail.UnitPrice += addOn.Price
+		}
+		detail.Subtotal = detail.UnitPrice * float64(detail.Quantity)
+	}
+	return nil
+}
+
+func findAddOn(addOns []models.AddOn, id uint) (models.AddOn, bool) {
+	for _, addOn := range addOns {
+		if addOn.ID == id {
+			return addOn, true
+		}
+	}
+	return models.AddOn{}, false
+}
+
+func canTransition(from, to string) bool {
+	for _, status := range orderTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
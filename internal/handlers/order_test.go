@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/memory"
+)
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{models.OrderScheduled, models.OrderCancelled, true},
+		{models.OrderScheduled, models.OrderPending, false},
+		{models.OrderScheduled, models.OrderPreparing, false},
+		{models.OrderPending, models.OrderPreparing, true},
+		{models.OrderPending, models.OrderCancelled, true},
+		{models.OrderPending, models.OrderServed, false},
+		{models.OrderPreparing, models.OrderServed, true},
+		{models.OrderPreparing, models.OrderCancelled, true},
+		{models.OrderPreparing, models.OrderPending, false},
+		{models.OrderServed, models.OrderCompleted, true},
+		{models.OrderServed, models.OrderCancelled, false},
+		{models.OrderCompleted, models.OrderCancelled, false},
+		{models.OrderCancelled, models.OrderPending, false},
+		{models.OrderPending, models.OrderPending, false},
+		{models.OrderPending, "shipped", false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestPriceOrder(t *testing.T) {
+	menu := memory.NewMenu()
+	headOffice := context.Background()
+	noodles := models.MenuItem{Name: "Noodles", Price: 120, IsAvailable: true, AddOns: []models.AddOn{{Name: "Egg", Price: 15}}}
+	if err := menu.CreateItem(headOffice, &noodles); err != nil {
+		t.Fatal(err)
+	}
+	branchOnly := models.MenuItem{Name: "Branch Soup", Price: 90, IsAvailable: true}
+	if err := menu.CreateItem(auth.WithLocationID(headOffice, 2), &branchOnly); err != nil {
+		t.Fatal(err)
+	}
+	menu.SetLocationPrice(1, noodles.ID, 140)
+
+	one, two := uint(1), uint(2)
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		locationID *uint
+		itemID     uint
+		unitPrice  float64
+		wantErr    bool
+	}{
+		{"head office order for the business", headOffice, nil, noodles.ID, 135, false},
+		{"head office order at a location", headOffice, &one, noodles.ID, 155, false},
+		{"location order", auth.WithLocationID(headOffice, 1), &one, noodles.ID, 155, false},
+		{"location without an override", headOffice, &two, noodles.ID, 135, false},
+		{"another location's item", headOffice, &one, branchOnly.ID, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := models.Order{
+				LocationID: tt.locationID,
+				OrderDetails: []models.OrderDetail{{
+					MenuItemID:     tt.itemID,
+					Quantity:       2,
+					SelectedAddOns: []models.SelectedAddOn{{AddOnID: noodles.AddOns[0].ID}},
+				}},
+			}
+			if tt.itemID != noodles.ID {
+				order.OrderDetails[0].SelectedAddOns = nil
+			}
+
+			err := priceOrder(tt.ctx, menu, &order)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("priceOrder succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("priceOrder: %v", err)
+			}
+			detail := order.OrderDetails[0]
+			if detail.UnitPrice != tt.unitPrice || detail.Subtotal != 2*tt.unitPrice {
+				t.Errorf("priced at %v, subtotal %v; want %v, %v", detail.UnitPrice, detail.Subtotal, tt.unitPrice, 2*tt.unitPrice)
+			}
+		})
+	}
+}
+
+func TestGetPickupSlots(t *testing.T) {
+	ctx := context.Background()
+	infos := memory.NewRestaurantInfo()
+	lunch := models.DaySchedule{Ranges: []models.TimeRange{{Open: "11:00", Close: "12:00"}}}
+	info := models.RestaurantInfo{Name: "Noodle Bar", SlotCapacity: 2, OpeningHours: models.OpeningHours{WeekSchedule: models.WeekSchedule{Monday: lunch}}}
+	if err := infos.Save(ctx, &info); err != nil {
+		t.Fatal(err)
+	}
+
+	// Booked at 11:15 in Taipei, stored as UTC the way the database returns it
+	orders := memory.NewOrders()
+	scheduledFor := time.Date(2024, time.June, 17, 3, 15, 0, 0, time.UTC)
+	order := models.Order{OrderType: models.OrderTypeTakeout, ScheduledFor: &scheduledFor}
+	if _, err := orders.Create(ctx, &order, 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	GetPickupSlots(orders, infos)(w, httptest.NewRequest(http.MethodGet, "/pickup-slots?date=2024-06-17", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusOK)
+	}
+	var slots []PickupSlot
+	if err := json.NewDecoder(w.Body).Decode(&slots); err != nil {
+		t.Fatal(err)
+	}
+
+	open := time.Date(2024, time.June, 17, 11, 0, 0, 0, models.RestaurantZone)
+	want := []PickupSlot{
+		{Start: open, Remaining: 2},
+		{Start: open.Add(models.PickupSlotLength), Remaining: 1},
+		{Start: open.Add(2 * models.PickupSlotLength), Remaining: 2},
+		{Start: open.Add(3 * models.PickupSlotLength), Remaining: 2},
+	}
+	if len(slots) != len(want) {
+		t.Fatalf("got %d slots, want %d: %v", len(slots), len(want), slots)
+	}
+	for i := range want {
+		if !slots[i].Start.Equal(want[i].Start) || slots[i].Remaining != want[i].Remaining {
+			t.Errorf("slot %d = %v with %d left, want %v with %d", i, slots[i].Start, slots[i].Remaining, want[i].Start, want[i].Remaining)
+		}
+	}
+}
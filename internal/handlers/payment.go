@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+)
+
+func AddOrderDiscount(orders repository.Orders, infos repository.RestaurantInfo) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var discount models.OrderDiscount
+		if err := json.NewDecoder(r.Body).Decode(&discount); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if discount.Description == "" || discount.Amount <= 0 {
+			apierror.Respond(w, r, "Description and a positive amount are required", http.StatusBadRequest)
+			return
+		}
+
+		order, err := orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			info, err := infos.ForLocation(r.Context(), order.LocationID)
+			if err != nil {
+				return restaurantInfoError(err)
+			}
+
+			if order.Status == models.OrderCancelled || order.PaidAmount() > 0 {
+				return apierror.New(http.StatusConflict, "Discounts can only be added to unpaid orders")
+			}
+			if discount.Amount > order.ItemsTotal()-order.DiscountTotal() {
+				return apierror.New(http.StatusBadRequest, "Discount exceeds the order amount")
+			}
+
+			order.Discounts = append(order.Discounts, discount)
+			order.ApplyTotals(info.TaxSettings)
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order)
+	}
+}
+
+// AddPayment records a payment against an order. Cash beyond the balance is
+// recorded as tendered so the receipt can show the change.
+func AddPayment(orders repository.Orders) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var payment models.Payment
+		if err := json.NewDecoder(r.Body).Decode(&payment); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		switch payment.Method {
+		case models.PaymentCash, models.PaymentCard, models.PaymentMobile:
+		default:
+			apierror.Respond(w, r, "Payment method must be cash, card or mobile", http.StatusBadRequest)
+			return
+		}
+		if payment.Amount <= 0 {
+			apierror.Respond(w, r, "Payment amount must be positive", http.StatusBadRequest)
+			return
+		}
+
+		order, err := orders.Update(r.Context(), uint(id), func(order *models.Order) error {
+			if order.Status == models.OrderCancelled {
+				return apierror.New(http.StatusConflict, "Cannot take payment for a cancelled order")
+			}
+
+			balance := order.Balance()
+			if balance <= 0 {
+				return apierror.New(http.StatusConflict, "Order is already paid")
+			}
+			payment.Tendered = 0
+			if payment.Amount > balance {
+				if payment.Method != models.PaymentCash {
+					return apierror.New(http.StatusBadRequest, "Payment exceeds the balance")
+				}
+				payment.Tendered = payment.Amount
+				payment.Amount = balance
+			}
+
+			order.Payments = append(order.Payments, payment)
+			return nil
+		})
+		if err != nil {
+			writeOrderError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(order.Payments[len(order.Payments)-1])
+	}
+}
+
+// writeOrderError answers a failed order update, whose change may have refused it with an *apierror.Error
+func writeOrderError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Respond(w, r, "Order not found", http.StatusNotFound)
+		return
+	}
+	apierror.Write(w, r, err)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/receipt"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetOrderReceipt renders an order's receipt. format is html (the default), pdf,
+// escpos58 or escpos80 for 58mm and 80mm thermal printers.
+func GetOrderReceipt(db *database.Manager, logos *receipt.Logos) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid order ID", http.StatusBadRequest)
+			return
+		}
+
+		var order models.Order
+		result := db.WithContext(r.Context()).
+			Preload("OrderDetails.SelectedAddOns").Preload("Discounts").Preload("Payments").Preload("Adjustments").
+			First(&order, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Order not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		info, err := database.RestaurantInfoFor(db.WithContext(r.Context()), order.LocationID)
+		if err != nil {
+			apierror.Respond(w, r, "Restaurant info not found", http.StatusNotFound)
+			return
+		}
+
+		rec := receipt.New(order, info, time.Now())
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "html" {
+			// HTML links the logo; the other formats carry the image itself
+			rec.Logo = logos.Get(r.Context(), info.LogoURL)
+		}
+		switch format {
+		case "", "html":
+			var buf bytes.Buffer
+			if err := receipt.WriteHTML(&buf, rec); err != nil {
+				apierror.Write(w, r, err)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write(buf.Bytes())
+		case "pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=receipt-%d.pdf", order.ID))
+			w.Write(receipt.PDF(rec))
+		case "escpos58":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(receipt.ESCPOS(rec, escpos.Width58mm))
+		case "escpos80":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(receipt.ESCPOS(rec, escpos.Width80mm))
+		default:
+			apierror.Respond(w, r, "Format must be html, pdf, escpos58 or escpos80", http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,459 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	errRestaurantClosed = errors.New("restaurant is closed for part of the requested time")
+	errNoTableAvailable = errors.New("no table available for the requested party size and time")
+)
+
+type AvailabilityResponse struct {
+	Available bool   `json:"available"`
+	TableID   *uint  `json:"table_id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+func GetReservations(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := db.WithContext(r.Context()).Preload("Table").Order("reserved_at")
+
+		if date := r.URL.Query().Get("date"); date != "" {
+			day, err := time.ParseInLocation("2006-01-02", date, models.RestaurantZone)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			query = query.Where("reserved_at >= ? AND reserved_at < ?", day, day.AddDate(0, 0, 1))
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			query = query.Where("status = ?", status)
+		}
+
+		var reservations []models.Reservation
+		result := query.Find(&reservations)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservations)
+	}
+}
+
+func GetReservation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid reservation ID", http.StatusBadRequest)
+			return
+		}
+
+		var reservation models.Reservation
+		result := db.WithContext(r.Context()).Preload("Table").First(&reservation, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Reservation not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+func CheckReservationAvailability(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start, err := time.Parse(time.RFC3339, r.URL.Query().Get("time"))
+		if err != nil {
+			apierror.Respond(w, r, "Invalid time, expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		partySize, err := strconv.Atoi(r.URL.Query().Get("party_size"))
+		if err != nil || partySize <= 0 {
+			apierror.Respond(w, r, "Invalid party size", http.StatusBadRequest)
+			return
+		}
+
+		var requested *uint
+		if param := r.URL.Query().Get("location_id"); param != "" {
+			locationID, err := strconv.Atoi(param)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+				return
+			}
+			id := uint(locationID)
+			requested = &id
+		}
+
+		info, err := database.RestaurantInfoFor(db.WithContext(r.Context()), callerLocation(r.Context(), requested))
+		if err != nil {
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+
+		table, err := findAvailableTable(db.WithContext(r.Context()), info, partySize, start, info.TurnMinutes(), 0, nil)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			if errors.Is(err, errRestaurantClosed) || errors.Is(err, errNoTableAvailable) {
+				json.NewEncoder(w).Encode(AvailabilityResponse{Available: false, Reason: err.Error()})
+				return
+			}
+			apierror.Write(w, r, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(AvailabilityResponse{Available: true, TableID: &table.ID})
+	}
+}
+
+func CreateReservation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var reservation models.Reservation
+		if err := json.NewDecoder(r.Body).Decode(&reservation); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if reservation.Name == "" || reservation.Phone == "" || reservation.PartySize <= 0 || reservation.ReservedAt.IsZero() {
+			apierror.Respond(w, r, "Name, phone, party size and reservation time are required", http.StatusBadRequest)
+			return
+		}
+
+		reservation.LocationID = callerLocation(r.Context(), reservation.LocationID)
+		info, err := database.RestaurantInfoFor(db.WithContext(r.Context()), reservation.LocationID)
+		if err != nil {
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		table, err := findAvailableTable(tx, info, reservation.PartySize, reservation.ReservedAt, info.TurnMinutes(), 0, reservation.TableID)
+		if err != nil {
+			tx.Rollback()
+			writeAvailabilityError(w, r, err)
+			return
+		}
+
+		reservation.ID = 0
+		reservation.TableID = &table.ID
+		reservation.Table = nil
+		reservation.TurnMinutes = info.TurnMinutes()
+		reservation.Status = models.ReservationBooked
+		if err := tx.Create(&reservation).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		reservation.Table = table
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+func UpdateReservation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid reservation ID", http.StatusBadRequest)
+			return
+		}
+
+		var updatedReservation models.Reservation
+		if err := json.NewDecoder(r.Body).Decode(&updatedReservation); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		var reservation models.Reservation
+		result := tx.First(&reservation, id)
+		if result.Error != nil {
+			tx.Rollback()
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Reservation not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		info, err := database.RestaurantInfoFor(tx, reservation.LocationID)
+		if err != nil {
+			tx.Rollback()
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+
+		if reservation.Status != models.ReservationBooked {
+			tx.Rollback()
+			apierror.Respond(w, r, "Only booked reservations can be changed", http.StatusConflict)
+			return
+		}
+
+		if updatedReservation.Name != "" {
+			reservation.Name = updatedReservation.Name
+		}
+		if updatedReservation.Phone != "" {
+			reservation.Phone = updatedReservation.Phone
+		}
+		if updatedReservation.PartySize > 0 {
+			reservation.PartySize = updatedReservation.PartySize
+		}
+		if !updatedReservation.ReservedAt.IsZero() {
+			reservation.ReservedAt = updatedReservation.ReservedAt
+		}
+		if updatedReservation.TableID != nil {
+			reservation.TableID = updatedReservation.TableID
+		}
+		reservation.Notes = updatedReservation.Notes
+
+		table, err := findAvailableTable(tx, info, reservation.PartySize, reservation.ReservedAt, reservation.TurnMinutes, reservation.ID, reservation.TableID)
+		if errors.Is(err, errNoTableAvailable) && updatedReservation.TableID == nil {
+			// The current table no longer fits, so let any other table take the party
+			table, err = findAvailableTable(tx, info, reservation.PartySize, reservation.ReservedAt, reservation.TurnMinutes, reservation.ID, nil)
+		}
+		if err != nil {
+			tx.Rollback()
+			writeAvailabilityError(w, r, err)
+			return
+		}
+		reservation.TableID = &table.ID
+
+		if err := tx.Save(&reservation).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		reservation.Table = table
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+func CancelReservation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid reservation ID", http.StatusBadRequest)
+			return
+		}
+
+		var reservation models.Reservation
+		result := db.WithContext(r.Context()).First(&reservation, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Reservation not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		if reservation.Status != models.ReservationBooked {
+			apierror.Respond(w, r, "Only booked reservations can be cancelled", http.StatusConflict)
+			return
+		}
+
+		reservation.Status = models.ReservationCancelled
+		if err := db.WithContext(r.Context()).Save(&reservation).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// SeatReservation marks a booked party as arrived and seated at its table
+func SeatReservation(db *database.Manager) http.HandlerFunc {
+	return changeReservationStatus(db, models.ReservationBooked, models.ReservationSeated,
+		"Only booked reservations can be seated")
+}
+
+// CompleteReservation releases the table of a seated party that has left
+func CompleteReservation(db *database.Manager) http.HandlerFunc {
+	return changeReservationStatus(db, models.ReservationSeated, models.ReservationCompleted,
+		"Only seated reservations can be completed")
+}
+
+// changeReservationStatus moves a reservation from one status to the next. The
+// update only applies while the reservation is still in the expected status, so
+// concurrent changes cannot both succeed.
+func changeReservationStatus(db *database.Manager, from, to, conflictMessage string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid reservation ID", http.StatusBadRequest)
+			return
+		}
+
+		var reservation models.Reservation
+		result := db.WithContext(r.Context()).First(&reservation, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Reservation not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		result = db.WithContext(r.Context()).Model(&reservation).Where("status = ?", from).Update("status", to)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, conflictMessage, http.StatusConflict)
+			return
+		}
+		reservation.Status = to
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// MarkReservationNoShow releases the table of a party that did not arrive within the grace period
+func MarkReservationNoShow(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid reservation ID", http.StatusBadRequest)
+			return
+		}
+
+		var reservation models.Reservation
+		result := db.WithContext(r.Context()).First(&reservation, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Reservation not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		info, err := database.RestaurantInfoFor(db.WithContext(r.Context()), reservation.LocationID)
+		if err != nil {
+			writeRestaurantInfoError(w, r, err)
+			return
+		}
+
+		if reservation.Status != models.ReservationBooked {
+			apierror.Respond(w, r, "Only booked reservations can be marked as no-show", http.StatusConflict)
+			return
+		}
+		if time.Now().Before(reservation.ReservedAt.Add(info.NoShowGrace())) {
+			apierror.Respond(w, r, "The no-show grace period has not passed yet", http.StatusConflict)
+			return
+		}
+
+		reservation.Status = models.ReservationNoShow
+		if err := db.WithContext(r.Context()).Save(&reservation).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reservation)
+	}
+}
+
+// findAvailableTable returns the smallest table that seats the party for the whole turn,
+// which must end by closing time. When tableID is set only that table is considered.
+// The candidate tables stay locked until tx ends, so a concurrent booking for any of
+// them waits and then sees this one.
+func findAvailableTable(tx *gorm.DB, info models.RestaurantInfo, partySize int, start time.Time, turnMinutes int, excludeReservationID uint, tableID *uint) (*models.Table, error) {
+	end := start.Add(time.Duration(turnMinutes) * time.Minute)
+	if !info.OpeningHours.IsOpenThrough(start, end) {
+		return nil, errRestaurantClosed
+	}
+
+	query := database.AtLocation(tx, info.LocationID).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("capacity >= ?", partySize).Order("capacity, number")
+	if tableID != nil {
+		query = query.Where("id = ?", *tableID)
+	}
+	var tables []models.Table
+	if err := query.Find(&tables).Error; err != nil {
+		return nil, err
+	}
+
+	// Turns never last longer than a day, so anything booked before that cannot overlap
+	var reservations []models.Reservation
+	err := database.AtLocation(tx, info.LocationID).Where("status IN ? AND reserved_at < ? AND reserved_at > ? AND id <> ?",
+		[]string{models.ReservationBooked, models.ReservationSeated}, end, start.Add(-24*time.Hour), excludeReservationID).
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tables {
+		taken := false
+		for _, reservation := range reservations {
+			if reservation.TableID != nil && *reservation.TableID == tables[i].ID && reservation.Overlaps(start, end) {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return &tables[i], nil
+		}
+	}
+	return nil, errNoTableAvailable
+}
+
+func writeRestaurantInfoError(w http.ResponseWriter, r *http.Request, err error) {
+	apierror.Write(w, r, restaurantInfoError(err))
+}
+
+// restaurantInfoError turns a missing restaurant info into a 404 that says so
+func restaurantInfoError(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) || errors.Is(err, repository.ErrNotFound) {
+		return apierror.New(http.StatusNotFound, "Restaurant info not found")
+	}
+	return err
+}
+
+func writeAvailabilityError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errRestaurantClosed) || errors.Is(err, errNoTableAvailable) {
+		apierror.Write(w, r, apierror.Wrap(err, http.StatusConflict))
+		return
+	}
+	apierror.Write(w, r, err)
+}
@@ -6,20 +6,19 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
 	"github.com/darrenjon/restaurant-ordering-system/internal/models"
-	"gorm.io/gorm"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
 )
 
-func GetRestaurantInfo(db *database.Manager) http.HandlerFunc {
+func GetRestaurantInfo(infos repository.RestaurantInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var info models.RestaurantInfo
-		result := db.GetDB().Order("updated_at desc").First(&info)
-		if result.Error != nil {
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				http.Error(w, "Restaurant info not found", http.StatusNotFound)
+		info, err := infos.Get(r.Context())
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "Restaurant info not found", http.StatusNotFound)
 			} else {
-				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+				apierror.Write(w, r, err)
 			}
 			return
 		}
@@ -28,35 +27,17 @@ func GetRestaurantInfo(db *database.Manager) http.HandlerFunc {
 	}
 }
 
-func UpdateRestaurantInfo(db *database.Manager) http.HandlerFunc {
+func UpdateRestaurantInfo(infos repository.RestaurantInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var info models.RestaurantInfo
 		if err := json.NewDecoder(r.Body).Decode(&info); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
 			return
 		}
 
-		// First, try to get the existing restaurant info
-		var existingInfo models.RestaurantInfo
-		result := db.GetDB().First(&existingInfo)
-
-		if result.Error != nil {
-			if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-				// If no record exists, create a new one
-				result = db.GetDB().Create(&info)
-			} else {
-				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
-				return
-			}
-		} else {
-			// If a record exists, update it
-			info.ID = existingInfo.ID // Ensure we're updating the existing record
-			info.CreatedAt = existingInfo.CreatedAt
-			result = db.GetDB().Save(&info)
-		}
-
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		// Update the existing restaurant info, or create it the first time
+		if err := infos.Save(r.Context(), &info); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -65,12 +46,11 @@ func UpdateRestaurantInfo(db *database.Manager) http.HandlerFunc {
 	}
 }
 
-func CheckRestaurantOpen(db *database.Manager) http.HandlerFunc {
+func CheckRestaurantOpen(infos repository.RestaurantInfo) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var info models.RestaurantInfo
-		result := db.GetDB().First(&info)
-		if result.Error != nil {
-			http.Error(w, "Restaurant info not found", http.StatusNotFound)
+		info, err := infos.Get(r.Context())
+		if err != nil {
+			apierror.Respond(w, r, "Restaurant info not found", http.StatusNotFound)
 			return
 		}
 
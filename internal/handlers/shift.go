@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"gorm.io/gorm"
+)
+
+type TimesheetDay struct {
+	Date          string  `json:"date"`
+	WorkedHours   float64 `json:"worked_hours"`
+	RegularHours  float64 `json:"regular_hours"`
+	OvertimeHours float64 `json:"overtime_hours"`
+}
+
+type Timesheet struct {
+	UserID        uint           `json:"user_id"`
+	Name          string         `json:"name"`
+	From          string         `json:"from"`
+	To            string         `json:"to"`
+	Shifts        int            `json:"shifts"`
+	BreakHours    float64        `json:"break_hours"`
+	RegularHours  float64        `json:"regular_hours"`
+	OvertimeHours float64        `json:"overtime_hours"`
+	Days          []TimesheetDay `json:"days"`
+}
+
+type StaffSales struct {
+	UserID      *uint   `json:"user_id"`
+	Name        string  `json:"name"`
+	Orders      int     `json:"orders"`
+	Sales       float64 `json:"sales"`
+	Refunds     float64 `json:"refunds"`
+	NetSales    float64 `json:"net_sales"`
+	AverageBill float64 `json:"average_bill"`
+}
+
+// ClockIn starts a shift for the signed-in staff member
+func ClockIn(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tx := db.WithContext(r.Context())
+		user, ok := currentUser(r, tx)
+		if !ok {
+			apierror.Respond(w, r, "Sign in to clock in", http.StatusUnauthorized)
+			return
+		}
+
+		_, err := openShift(tx, user.ID)
+		if err == nil {
+			apierror.Respond(w, r, "Already clocked in", http.StatusConflict)
+			return
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		// A unique index allows one open shift per user, so a concurrent clock-in fails here
+		shift := models.Shift{UserID: user.ID, ClockIn: time.Now()}
+		if err := tx.Create(&shift).Error; err != nil {
+			if apierror.IsUniqueViolation(err) {
+				apierror.Respond(w, r, "Already clocked in", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(shift)
+	}
+}
+
+// ClockOut ends the signed-in staff member's shift, closing any open break
+func ClockOut(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shift, ok := loadOpenShift(w, r, db)
+		if !ok {
+			return
+		}
+
+		now := time.Now()
+		err := db.WithContext(r.Context()).Transaction(func(tx *gorm.DB) error {
+			if open := shift.OpenBreak(); open != nil {
+				open.EndedAt = &now
+				if err := tx.Model(open).Update("ended_at", now).Error; err != nil {
+					return err
+				}
+			}
+			shift.ClockOut = &now
+			return tx.Model(&shift).Update("clock_out", now).Error
+		})
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(shift)
+	}
+}
+
+func StartBreak(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shift, ok := loadOpenShift(w, r, db)
+		if !ok {
+			return
+		}
+
+		if shift.OpenBreak() != nil {
+			apierror.Respond(w, r, "Already on a break", http.StatusConflict)
+			return
+		}
+
+		shiftBreak := models.ShiftBreak{ShiftID: shift.ID, StartedAt: time.Now()}
+		if err := db.WithContext(r.Context()).Create(&shiftBreak).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		shift.Breaks = append(shift.Breaks, shiftBreak)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(shift)
+	}
+}
+
+func EndBreak(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		shift, ok := loadOpenShift(w, r, db)
+		if !ok {
+			return
+		}
+
+		open := shift.OpenBreak()
+		if open == nil {
+			apierror.Respond(w, r, "Not on a break", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		open.EndedAt = &now
+		if err := db.WithContext(r.Context()).Model(open).Update("ended_at", now).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(shift)
+	}
+}
+
+// GetShifts lists shifts started in a period, optionally for one user
+func GetShifts(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parsePeriod(w, r)
+		if !ok {
+			return
+		}
+
+		query := db.WithContext(r.Context()).Preload("Breaks").
+			Where("clock_in >= ? AND clock_in < ?", from, to).Order("clock_in")
+		if userID := r.URL.Query().Get("user_id"); userID != "" {
+			id, err := strconv.Atoi(userID)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			query = query.Where("user_id = ?", id)
+		}
+
+		var shifts []models.Shift
+		if err := query.Find(&shifts).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(shifts)
+	}
+}
+
+// GetTimesheets reports each staff member's hours in a pay period. Hours past
+// eight a day, or past forty regular hours in a week, count as overtime.
+func GetTimesheets(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parsePeriod(w, r)
+		if !ok {
+			return
+		}
+
+		query := db.WithContext(r.Context()).Preload("Breaks").
+			Where("clock_in >= ? AND clock_in < ?", from, to).Order("clock_in")
+		if userID := r.URL.Query().Get("user_id"); userID != "" {
+			id, err := strconv.Atoi(userID)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid user ID", http.StatusBadRequest)
+				return
+			}
+			query = query.Where("user_id = ?", id)
+		}
+
+		var shifts []models.Shift
+		if err := query.Find(&shifts).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		byUser := make(map[uint][]models.Shift)
+		var userIDs []uint
+		for _, shift := range shifts {
+			if _, seen := byUser[shift.UserID]; !seen {
+				userIDs = append(userIDs, shift.UserID)
+			}
+			byUser[shift.UserID] = append(byUser[shift.UserID], shift)
+		}
+
+		var users []models.User
+		if err := db.WithContext(r.Context()).Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		names := make(map[uint]string, len(users))
+		for _, user := range users {
+			names[user.ID] = user.Name
+		}
+
+		now := time.Now()
+		timesheets := make([]Timesheet, 0, len(userIDs))
+		for _, userID := range userIDs {
+			timesheet := buildTimesheet(byUser[userID], now)
+			timesheet.UserID = userID
+			timesheet.Name = names[userID]
+			timesheet.From = from.Format("2006-01-02")
+			timesheet.To = to.AddDate(0, 0, -1).Format("2006-01-02")
+			timesheets = append(timesheets, timesheet)
+		}
+		sort.Slice(timesheets, func(i, j int) bool { return timesheets[i].Name < timesheets[j].Name })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(timesheets)
+	}
+}
+
+// GetStaffSales reports the orders and sales taken by each server in a period
+func GetStaffSales(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		from, to, ok := parsePeriod(w, r)
+		if !ok {
+			return
+		}
+
+		var rows []StaffSales
+		err := db.WithContext(r.Context()).Model(&models.Order{}).
+			Select("server_id AS user_id, COUNT(*) AS orders, COALESCE(SUM(total_amount), 0) AS sales").
+			Where("status <> ? AND created_at >= ? AND created_at < ?", models.OrderCancelled, from, to).
+			Group("server_id").Scan(&rows).Error
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		type refundRow struct {
+			UserID  *uint
+			Refunds float64
+		}
+		var refunds []refundRow
+		err = db.WithContext(r.Context()).Model(&models.OrderAdjustment{}).
+			Select("orders.server_id AS user_id, SUM(order_adjustments.amount) AS refunds").
+			Joins("JOIN orders ON orders.id = order_adjustments.order_id").
+			Where("order_adjustments.type = ? AND orders.created_at >= ? AND orders.created_at < ?", models.AdjustmentRefund, from, to).
+			Group("orders.server_id").Scan(&refunds).Error
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		var users []models.User
+		if err := db.WithContext(r.Context()).Find(&users).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+		names := make(map[uint]string, len(users))
+		for _, user := range users {
+			names[user.ID] = user.Name
+		}
+
+		for i := range rows {
+			row := &rows[i]
+			for _, refund := range refunds {
+				if sameUser(refund.UserID, row.UserID) {
+					row.Refunds = refund.Refunds
+				}
+			}
+			row.NetSales = row.Sales - row.Refunds
+			if row.Orders > 0 {
+				row.AverageBill = row.Sales / float64(row.Orders)
+			}
+			if row.UserID != nil {
+				row.Name = names[*row.UserID]
+			}
+		}
+		sort.Slice(rows, func(i, j int) bool { return rows[i].NetSales > rows[j].NetSales })
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rows)
+	}
+}
+
+// buildTimesheet totals shifts by the day they started, splitting regular and overtime hours
+func buildTimesheet(shifts []models.Shift, now time.Time) Timesheet {
+	var timesheet Timesheet
+	worked := make(map[string]time.Duration)
+	var dates []string
+	for _, shift := range shifts {
+		date := shift.ClockIn.In(time.Local).Format("2006-01-02")
+		if _, seen := worked[date]; !seen {
+			dates = append(dates, date)
+		}
+		worked[date] += shift.Worked(now)
+		end := now
+		if shift.ClockOut != nil {
+			end = *shift.ClockOut
+		}
+		timesheet.BreakHours += shift.BreakDuration(end).Hours()
+	}
+	sort.Strings(dates)
+
+	weekRegular := make(map[string]time.Duration)
+	for _, date := range dates {
+		day, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+		year, week := day.ISOWeek()
+		weekKey := strconv.Itoa(year) + "-" + strconv.Itoa(week)
+
+		regular := min(worked[date], models.RegularHoursPerDay)
+		if remaining := models.RegularHoursPerWeek - weekRegular[weekKey]; regular > remaining {
+			regular = max(remaining, 0)
+		}
+		weekRegular[weekKey] += regular
+		overtime := worked[date] - regular
+
+		timesheet.Days = append(timesheet.Days, TimesheetDay{
+			Date:          date,
+			WorkedHours:   roundHours(worked[date]),
+			RegularHours:  roundHours(regular),
+			OvertimeHours: roundHours(overtime),
+		})
+		timesheet.RegularHours += roundHours(regular)
+		timesheet.OvertimeHours += roundHours(overtime)
+	}
+	timesheet.Shifts = len(shifts)
+	timesheet.BreakHours = math.Round(timesheet.BreakHours*100) / 100
+	return timesheet
+}
+
+func roundHours(d time.Duration) float64 {
+	return d.Round(36 * time.Second).Hours()
+}
+
+// parsePeriod reads the inclusive from and to dates of a report
+func parsePeriod(w http.ResponseWriter, r *http.Request) (time.Time, time.Time, bool) {
+	from, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("from"), time.Local)
+	if err != nil {
+		apierror.Respond(w, r, "Invalid from date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return from, from, false
+	}
+	to, err := time.ParseInLocation("2006-01-02", r.URL.Query().Get("to"), time.Local)
+	if err != nil {
+		apierror.Respond(w, r, "Invalid to date, expected YYYY-MM-DD", http.StatusBadRequest)
+		return from, to, false
+	}
+	if to.Before(from) {
+		apierror.Respond(w, r, "The to date is before the from date", http.StatusBadRequest)
+		return from, to, false
+	}
+	return from, to.AddDate(0, 0, 1), true
+}
+
+func openShift(tx *gorm.DB, userID uint) (models.Shift, error) {
+	var shift models.Shift
+	err := tx.Preload("Breaks").Where("user_id = ? AND clock_out IS NULL", userID).First(&shift).Error
+	return shift, err
+}
+
+func loadOpenShift(w http.ResponseWriter, r *http.Request, db *database.Manager) (models.Shift, bool) {
+	tx := db.WithContext(r.Context())
+	user, ok := currentUser(r, tx)
+	if !ok {
+		apierror.Respond(w, r, "Sign in to use the time clock", http.StatusUnauthorized)
+		return models.Shift{}, false
+	}
+
+	shift, err := openShift(tx, user.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierror.Respond(w, r, "Not clocked in", http.StatusConflict)
+		} else {
+			apierror.Write(w, r, err)
+		}
+		return shift, false
+	}
+	return shift, true
+}
+
+func sameUser(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/kitchen"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func GetStations(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var stations []models.Station
+		result := db.WithContext(r.Context()).Order("name").Find(&stations)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stations)
+	}
+}
+
+func CreateStation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var station models.Station
+		if err := json.NewDecoder(r.Body).Decode(&station); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if station.Name == "" {
+			apierror.Respond(w, r, "Name is required", http.StatusBadRequest)
+			return
+		}
+		if station.PrinterAddress != "" {
+			if err := kitchen.ValidatePrinterAddress(station.PrinterAddress); err != nil {
+				apierror.Respond(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		result := db.WithContext(r.Context()).Create(&station)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(station)
+	}
+}
+
+func UpdateStation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid station ID", http.StatusBadRequest)
+			return
+		}
+
+		var updatedStation models.Station
+		if err := json.NewDecoder(r.Body).Decode(&updatedStation); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if updatedStation.PrinterAddress != "" {
+			if err := kitchen.ValidatePrinterAddress(updatedStation.PrinterAddress); err != nil {
+				apierror.Respond(w, r, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var station models.Station
+		result := db.WithContext(r.Context()).First(&station, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Station not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		if updatedStation.Name != "" {
+			station.Name = updatedStation.Name
+		}
+		station.PrinterAddress = updatedStation.PrinterAddress
+
+		result = db.WithContext(r.Context()).Save(&station)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(station)
+	}
+}
+
+func DeleteStation(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid station ID", http.StatusBadRequest)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Delete(&models.Station{}, id)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, "Station not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Station deleted successfully"})
+	}
+}
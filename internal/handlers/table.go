@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+func GetTables(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var tables []models.Table
+		result := db.WithContext(r.Context()).Order("number").Find(&tables)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tables)
+	}
+}
+
+func CreateTable(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var table models.Table
+		if err := json.NewDecoder(r.Body).Decode(&table); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if table.Number == "" || table.Capacity <= 0 {
+			apierror.Respond(w, r, "Number and a positive capacity are required", http.StatusBadRequest)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Create(&table)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(table)
+	}
+}
+
+func UpdateTable(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid table ID", http.StatusBadRequest)
+			return
+		}
+
+		var updatedTable models.Table
+		if err := json.NewDecoder(r.Body).Decode(&updatedTable); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if updatedTable.Number == "" || updatedTable.Capacity <= 0 {
+			apierror.Respond(w, r, "Number and a positive capacity are required", http.StatusBadRequest)
+			return
+		}
+
+		var table models.Table
+		result := db.WithContext(r.Context()).First(&table, id)
+		if result.Error != nil {
+			if result.Error == gorm.ErrRecordNotFound {
+				apierror.Respond(w, r, "Table not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, result.Error)
+			}
+			return
+		}
+
+		table.Number = updatedTable.Number
+		table.Capacity = updatedTable.Capacity
+
+		result = db.WithContext(r.Context()).Save(&table)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(table)
+	}
+}
+
+func DeleteTable(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, err := strconv.Atoi(vars["id"])
+		if err != nil {
+			apierror.Respond(w, r, "Invalid table ID", http.StatusBadRequest)
+			return
+		}
+
+		result := db.WithContext(r.Context()).Delete(&models.Table{}, id)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if result.RowsAffected == 0 {
+			apierror.Respond(w, r, "Table not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Table deleted successfully"})
+	}
+}
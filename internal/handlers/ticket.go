@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+	"github.com/darrenjon/restaurant-ordering-system/internal/kitchen"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetTickets lists tickets for the kitchen display, optionally for one station and status
+func GetTickets(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := db.WithContext(r.Context()).Preload("Items").Preload("Order").Order("created_at")
+		if stationID := r.URL.Query().Get("station_id"); stationID != "" {
+			id, err := strconv.Atoi(stationID)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid station ID", http.StatusBadRequest)
+				return
+			}
+			query = query.Where("station_id = ?", id)
+		}
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = models.TicketPending
+		}
+		query = query.Where("status = ?", status)
+
+		var tickets []models.Ticket
+		result := query.Find(&tickets)
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tickets)
+	}
+}
+
+func MarkTicketReady(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticket, ok := loadTicket(w, r, db)
+		if !ok {
+			return
+		}
+
+		if ticket.Status == models.TicketReady {
+			apierror.Respond(w, r, "Ticket is already ready", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		ticket.Status = models.TicketReady
+		ticket.ReadyAt = &now
+		if err := db.WithContext(r.Context()).Model(&ticket).Select("Status", "ReadyAt").Updates(&ticket).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(ticket)
+	}
+}
+
+// GetTicketESCPOS returns the ticket as a raw ESC/POS byte stream
+func GetTicketESCPOS(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticket, ok := loadTicket(w, r, db)
+		if !ok {
+			return
+		}
+
+		paperWidth := escpos.Width80mm
+		if r.URL.Query().Get("width") == "58" {
+			paperWidth = escpos.Width58mm
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(kitchen.RenderTicket(ticket, paperWidth))
+	}
+}
+
+func PrintTicket(db *database.Manager, sink kitchen.Sink) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ticket, ok := loadTicket(w, r, db)
+		if !ok {
+			return
+		}
+
+		if ticket.Station == nil || ticket.Station.PrinterAddress == "" {
+			apierror.Respond(w, r, "The ticket's station has no printer", http.StatusConflict)
+			return
+		}
+
+		if err := kitchen.PrintTickets(r.Context(), db.WithContext(r.Context()), sink, []uint{ticket.ID}); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadGateway))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Ticket sent to printer"})
+	}
+}
+
+func loadTicket(w http.ResponseWriter, r *http.Request, db *database.Manager) (models.Ticket, bool) {
+	var ticket models.Ticket
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid ticket ID", http.StatusBadRequest)
+		return ticket, false
+	}
+
+	result := db.WithContext(r.Context()).Preload("Order").Preload("Station").Preload("Items").First(&ticket, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Ticket not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return ticket, false
+	}
+	return ticket, true
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// trashModels are the soft-deleted records that can be listed, restored and purged
+var trashModels = map[string]func() interface{}{
+	"users":      func() interface{} { return &models.User{} },
+	"categories": func() interface{} { return &models.Category{} },
+	"menu-items": func() interface{} { return &models.MenuItem{} },
+}
+
+var errRestoreConflict = errors.New("restore conflict")
+
+// GetTrash lists the soft-deleted records of an entity, most recently deleted first
+func GetTrash(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireManager(w, r, db) {
+			return
+		}
+		newModel, ok := trashModel(w, r)
+		if !ok {
+			return
+		}
+
+		records := reflect.New(reflect.SliceOf(reflect.TypeOf(newModel()).Elem()))
+		result := db.WithContext(r.Context()).Unscoped().Where("deleted_at IS NOT NULL").
+			Order("deleted_at desc").Find(records.Interface())
+		if result.Error != nil {
+			apierror.Write(w, r, result.Error)
+			return
+		}
+
+		if users, ok := records.Interface().(*[]models.User); ok {
+			for i := range *users {
+				(*users)[i].Password = ""
+				(*users)[i].PIN = ""
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(records.Interface())
+	}
+}
+
+// RestoreFromTrash brings a soft-deleted record back, unless a live record now
+// has its unique name
+func RestoreFromTrash(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireManager(w, r, db) {
+			return
+		}
+		newModel, ok := trashModel(w, r)
+		if !ok {
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		record, ok := loadTrashed(w, r, tx, newModel())
+		if !ok {
+			tx.Rollback()
+			return
+		}
+
+		if err := restoreRecord(tx, record); err != nil {
+			tx.Rollback()
+			if errors.Is(err, errRestoreConflict) {
+				apierror.Write(w, r, apierror.Wrap(err, http.StatusConflict))
+			} else {
+				apierror.Write(w, r, err)
+			}
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		if user, ok := record.(*models.User); ok {
+			user.Password = ""
+			user.PIN = ""
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(record)
+	}
+}
+
+// PurgeFromTrash permanently deletes a soft-deleted record
+func PurgeFromTrash(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireManager(w, r, db) {
+			return
+		}
+		newModel, ok := trashModel(w, r)
+		if !ok {
+			return
+		}
+
+		tx := db.WithContext(r.Context()).Begin()
+		record, ok := loadTrashed(w, r, tx, newModel())
+		if !ok {
+			tx.Rollback()
+			return
+		}
+
+		if menuItem, ok := record.(*models.MenuItem); ok {
+			if err := tx.Unscoped().Where("menu_item_id = ? AND deleted_at IS NOT NULL", menuItem.ID).Delete(&models.AddOn{}).Error; err != nil {
+				tx.Rollback()
+				apierror.Write(w, r, err)
+				return
+			}
+		}
+		if err := tx.Unscoped().Delete(record).Error; err != nil {
+			tx.Rollback()
+			// A record still referenced, for example by past orders, is answered as invalid_reference
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Record permanently deleted"})
+	}
+}
+
+func restoreRecord(tx *gorm.DB, record interface{}) error {
+	var taken int64
+	switch record := record.(type) {
+	case *models.User:
+		if err := tx.Model(&models.User{}).Where("username = ?", record.Username).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: the username is in use", errRestoreConflict)
+		}
+	case *models.Category:
+		if err := tx.Model(&models.Category{}).Where("name = ?", record.Name).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return fmt.Errorf("%w: the category name is in use", errRestoreConflict)
+		}
+	case *models.MenuItem:
+		if err := tx.First(&models.Category{}, record.CategoryID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: restore the item's category first", errRestoreConflict)
+			}
+			return err
+		}
+		// Add-ons are deleted just before their item, while older versions
+		// replaced by edits were deleted earlier and stay deleted
+		deletedAt := record.DeletedAt.Time
+		err := tx.Unscoped().Model(&models.AddOn{}).
+			Where("menu_item_id = ? AND deleted_at BETWEEN ? AND ?", record.ID, deletedAt.Add(-time.Second), deletedAt).
+			Update("deleted_at", nil).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Unscoped().Model(record).Update("deleted_at", nil).Error
+}
+
+func trashModel(w http.ResponseWriter, r *http.Request) (func() interface{}, bool) {
+	newModel, ok := trashModels[mux.Vars(r)["entity"]]
+	if !ok {
+		apierror.Respond(w, r, "Trash is kept for users, categories and menu-items", http.StatusNotFound)
+	}
+	return newModel, ok
+}
+
+func loadTrashed(w http.ResponseWriter, r *http.Request, tx *gorm.DB, record interface{}) (interface{}, bool) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid ID", http.StatusBadRequest)
+		return nil, false
+	}
+
+	result := tx.Unscoped().Where("deleted_at IS NOT NULL").First(record, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Record not found in trash", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return nil, false
+	}
+	return record, true
+}
+
+// requireManager refuses the request unless it comes from a manager or admin
+func requireManager(w http.ResponseWriter, r *http.Request, db *database.Manager) bool {
+	user, ok := currentUser(r, db.WithContext(r.Context()))
+	if !ok || !user.CanApprove() {
+		apierror.Respond(w, r, "Only managers can do this", http.StatusForbidden)
+		return false
+	}
+	return true
+}
@@ -1,118 +1,137 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
-	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
 	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
 	"github.com/gorilla/mux"
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 )
 
-func CreateUser(db *database.Manager) http.HandlerFunc {
+func CreateUser(users repository.Users) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var user models.User
 		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
 			return
 		}
 
 		// Validate required fields
 		if user.Username == "" || user.Password == "" || user.Name == "" || user.Role == "" {
-			http.Error(w, "Username, password, name, and role are required", http.StatusBadRequest)
+			apierror.Respond(w, r, "Username, password, name, and role are required", http.StatusBadRequest)
+			return
+		}
+		if !canAssignRoles(r.Context(), users) {
+			apierror.Respond(w, r, "Only managers can assign roles", http.StatusForbidden)
 			return
 		}
 
 		// Hash the password
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
-			http.Error(w, "Error hashing password", http.StatusInternalServerError)
+			apierror.Respond(w, r, "Error hashing password", http.StatusInternalServerError)
 			return
 		}
 		user.Password = string(hashedPassword)
 
-		result := db.GetDB().Create(&user)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		if user.PIN != "" {
+			hashedPIN, err := bcrypt.GenerateFromPassword([]byte(user.PIN), bcrypt.DefaultCost)
+			if err != nil {
+				apierror.Respond(w, r, "Error hashing PIN", http.StatusInternalServerError)
+				return
+			}
+			user.PIN = string(hashedPIN)
+		}
+
+		if err := users.Create(r.Context(), &user); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				apierror.Respond(w, r, "Username is already taken", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
 			return
 		}
 
 		user.Password = "" // Don't send the password back
+		user.PIN = ""
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(user)
 	}
 }
 
-func GetUsers(db *database.Manager) http.HandlerFunc {
+func GetUsers(users repository.Users) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var users []models.User
-		result := db.GetDB().Find(&users)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		list, err := users.List(r.Context())
+		if err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
 		// Don't send the password back
-		for i := range users {
-			users[i].Password = ""
+		for i := range list {
+			list[i].Password = ""
+			list[i].PIN = ""
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(users)
+		json.NewEncoder(w).Encode(list)
 	}
 }
 
-func GetUser(db *database.Manager) http.HandlerFunc {
+func GetUser(users repository.Users) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		var user models.User
-		result := db.GetDB().First(&user, id)
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				http.Error(w, "User not found", http.StatusNotFound)
+		user, err := users.Get(r.Context(), uint(id))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "User not found", http.StatusNotFound)
 			} else {
-				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+				apierror.Write(w, r, err)
 			}
 			return
 		}
 
 		user.Password = "" // Don't send the password back
+		user.PIN = ""
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(user)
 	}
 }
 
-func UpdateUser(db *database.Manager) http.HandlerFunc {
+func UpdateUser(users repository.Users) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
 		var updatedUser models.User
 		if err := json.NewDecoder(r.Body).Decode(&updatedUser); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
 			return
 		}
 
-		var user models.User
-		result := db.GetDB().First(&user, id)
-		if result.Error != nil {
-			if result.Error == gorm.ErrRecordNotFound {
-				http.Error(w, "User not found", http.StatusNotFound)
+		user, err := users.Get(r.Context(), uint(id))
+		if err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "User not found", http.StatusNotFound)
 			} else {
-				http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+				apierror.Write(w, r, err)
 			}
 			return
 		}
@@ -123,47 +142,64 @@ func UpdateUser(db *database.Manager) http.HandlerFunc {
 		if updatedUser.Name != "" {
 			user.Name = updatedUser.Name
 		}
-		if updatedUser.Role != "" {
+		if updatedUser.Role != "" && updatedUser.Role != user.Role {
+			if !canAssignRoles(r.Context(), users) {
+				apierror.Respond(w, r, "Only managers can assign roles", http.StatusForbidden)
+				return
+			}
 			user.Role = updatedUser.Role
 		}
+		if updatedUser.LocationID != nil && isHeadOffice(r.Context()) {
+			user.LocationID = updatedUser.LocationID
+		}
 		if updatedUser.Password != "" {
 			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(updatedUser.Password), bcrypt.DefaultCost)
 			if err != nil {
-				http.Error(w, "Error hashing password", http.StatusInternalServerError)
+				apierror.Respond(w, r, "Error hashing password", http.StatusInternalServerError)
 				return
 			}
 			user.Password = string(hashedPassword)
 		}
+		if updatedUser.PIN != "" {
+			hashedPIN, err := bcrypt.GenerateFromPassword([]byte(updatedUser.PIN), bcrypt.DefaultCost)
+			if err != nil {
+				apierror.Respond(w, r, "Error hashing PIN", http.StatusInternalServerError)
+				return
+			}
+			user.PIN = string(hashedPIN)
+		}
 
-		result = db.GetDB().Save(&user)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
+		if err := users.Update(r.Context(), &user); err != nil {
+			if errors.Is(err, repository.ErrConflict) {
+				apierror.Respond(w, r, "Username is already taken", http.StatusConflict)
+			} else {
+				apierror.Write(w, r, err)
+			}
 			return
 		}
 
 		user.Password = ""
+		user.PIN = ""
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(user)
 	}
 }
 
-func DeleteUser(db *database.Manager) http.HandlerFunc {
+func DeleteUser(users repository.Users) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
 		id, err := strconv.Atoi(vars["id"])
 		if err != nil {
-			http.Error(w, "Invalid user ID", http.StatusBadRequest)
+			apierror.Respond(w, r, "Invalid user ID", http.StatusBadRequest)
 			return
 		}
 
-		result := db.GetDB().Delete(&models.User{}, id)
-		if result.Error != nil {
-			http.Error(w, result.Error.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		if result.RowsAffected == 0 {
-			http.Error(w, "User not found", http.StatusNotFound)
+		if err := users.Delete(r.Context(), uint(id)); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Respond(w, r, "User not found", http.StatusNotFound)
+			} else {
+				apierror.Write(w, r, err)
+			}
 			return
 		}
 
@@ -171,3 +207,13 @@ func DeleteUser(db *database.Manager) http.HandlerFunc {
 		json.NewEncoder(w).Encode(map[string]string{"message": "User deleted successfully"})
 	}
 }
+
+// canAssignRoles reports whether the caller's own user may set the roles of others
+func canAssignRoles(ctx context.Context, users repository.Users) bool {
+	username := auth.UsernameFromContext(ctx)
+	if username == "" {
+		return false
+	}
+	caller, err := users.GetByUsername(ctx, username)
+	return err == nil && caller.CanApprove()
+}
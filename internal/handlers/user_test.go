@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/memory"
+	"github.com/gorilla/mux"
+)
+
+func TestUserRoles(t *testing.T) {
+	ctx := context.Background()
+	users := memory.NewUsers()
+	for _, user := range []models.User{
+		{Username: "manager", Password: "x", Name: "Manager", Role: models.RoleManager},
+		{Username: "cashier", Password: "x", Name: "Cashier", Role: "cashier"},
+	} {
+		if err := users.Create(ctx, &user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := models.User{Username: "server", Password: "x", Name: "Server", Role: "server"}
+	if err := users.Create(ctx, &server); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		caller string
+		update bool
+		body   string
+		want   int
+	}{
+		{"manager creates", "manager", false, `{"username":"new1","password":"x","name":"New","role":"manager"}`, http.StatusCreated},
+		{"cashier creates", "cashier", false, `{"username":"new2","password":"x","name":"New","role":"server"}`, http.StatusForbidden},
+		{"unknown caller creates", "ghost", false, `{"username":"new3","password":"x","name":"New","role":"server"}`, http.StatusForbidden},
+		{"cashier promotes", "cashier", true, `{"role":"admin"}`, http.StatusForbidden},
+		{"cashier keeps the role", "cashier", true, `{"name":"Renamed","role":"server"}`, http.StatusOK},
+		{"cashier renames", "cashier", true, `{"name":"Renamed"}`, http.StatusOK},
+		{"manager changes the role", "manager", true, `{"role":"cashier"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			callerCtx := context.WithValue(ctx, auth.ContextUsername, tt.caller)
+			w := httptest.NewRecorder()
+			if tt.update {
+				r := httptest.NewRequest(http.MethodPut, "/api/users/"+strconv.Itoa(int(server.ID)), strings.NewReader(tt.body))
+				r = mux.SetURLVars(r.WithContext(callerCtx), map[string]string{"id": strconv.Itoa(int(server.ID))})
+				UpdateUser(users)(w, r)
+			} else {
+				r := httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader(tt.body))
+				CreateUser(users)(w, r.WithContext(callerCtx))
+			}
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
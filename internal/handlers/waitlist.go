@@ -0,0 +1,478 @@
+package handlers
+
+import (
+	"container/heap"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/stream"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+var errNoTableFitsParty = errors.New("no table can seat a party of this size")
+
+// waitlistLockKey serialises queue numbering per location through a Postgres advisory lock
+const waitlistLockKey = 7231605
+
+// seatingHistoryWindow is how far back completed orders are used to estimate seating durations
+const seatingHistoryWindow = 30 * 24 * time.Hour
+
+type WaitQuote struct {
+	PartySize     int `json:"party_size"`
+	PartiesAhead  int `json:"parties_ahead"`
+	QuotedMinutes int `json:"quoted_minutes"`
+}
+
+type SeatWaitlistRequest struct {
+	TableID *uint `json:"table_id"`
+}
+
+// WaitlistDisplayEntry is the public view of a waiting party shown on the lobby display
+type WaitlistDisplayEntry struct {
+	QueueNumber   int    `json:"queue_number"`
+	PartySize     int    `json:"party_size"`
+	Status        string `json:"status"`
+	QuotedMinutes int    `json:"quoted_minutes"`
+}
+
+type WaitlistEvent struct {
+	Entries []WaitlistDisplayEntry `json:"entries"`
+}
+
+func GetWaitlist(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entries, err := activeWaitlist(db.WithContext(r.Context()))
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entries)
+	}
+}
+
+func QuoteWaitlist(db *database.Manager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		partySize, err := strconv.Atoi(r.URL.Query().Get("party_size"))
+		if err != nil || partySize <= 0 {
+			apierror.Respond(w, r, "Invalid party size", http.StatusBadRequest)
+			return
+		}
+
+		quote, err := estimateWait(db.WithContext(r.Context()), partySize, 0, time.Now())
+		if err != nil {
+			writeWaitlistError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(quote)
+	}
+}
+
+func AddToWaitlist(db *database.Manager, broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var entry models.WaitlistEntry
+		if err := json.NewDecoder(r.Body).Decode(&entry); err != nil {
+			apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+			return
+		}
+
+		if entry.Name == "" || entry.PartySize <= 0 {
+			apierror.Respond(w, r, "Name and party size are required", http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		entry.LocationID = callerLocation(r.Context(), entry.LocationID)
+		// Head office adds to a location's queue, so quote and publish for that location
+		ctx := scopedTo(r.Context(), entry.LocationID)
+		tx := db.WithContext(ctx).Begin()
+
+		// Parties joining one location take turns, so no two get the same number
+		var lockLocation uint
+		if entry.LocationID != nil {
+			lockLocation = *entry.LocationID
+		}
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", waitlistLockKey, lockLocation).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+
+		quote, err := estimateWait(tx, entry.PartySize, 0, now)
+		if err != nil {
+			tx.Rollback()
+			writeWaitlistError(w, r, err)
+			return
+		}
+
+		// Queue numbers restart every day at the restaurant
+		var lastNumber int
+		today := now.In(models.RestaurantZone)
+		queueDate := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, models.RestaurantZone)
+		err = database.AtLocation(tx.Model(&models.WaitlistEntry{}), entry.LocationID).Where("queue_date = ?", queueDate).
+			Select("COALESCE(MAX(queue_number), 0)").Scan(&lastNumber).Error
+		if err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+
+		entry.ID = 0
+		entry.QueueNumber = lastNumber + 1
+		entry.QueueDate = queueDate
+		entry.Status = models.WaitlistWaiting
+		entry.QuotedMinutes = quote.QuotedMinutes
+		entry.CalledAt = nil
+		entry.SeatedAt = nil
+		entry.TableID = nil
+		if err := tx.Create(&entry).Error; err != nil {
+			tx.Rollback()
+			apierror.Write(w, r, err)
+			return
+		}
+		if err := tx.Commit().Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		publishWaitlist(ctx, db, broker, entry.LocationID)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+// CallWaitlistEntry marks a party as called to the host stand
+func CallWaitlistEntry(db *database.Manager, broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, ok := loadWaitlistEntry(w, r, db)
+		if !ok {
+			return
+		}
+
+		if entry.Status != models.WaitlistWaiting {
+			apierror.Respond(w, r, "Only waiting parties can be called", http.StatusConflict)
+			return
+		}
+
+		now := time.Now()
+		entry.Status = models.WaitlistCalled
+		entry.CalledAt = &now
+		if err := db.WithContext(r.Context()).Save(&entry).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		publishWaitlist(r.Context(), db, broker, entry.LocationID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+func SeatWaitlistEntry(db *database.Manager, broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SeatWaitlistRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				apierror.Write(w, r, apierror.Wrap(err, http.StatusBadRequest))
+				return
+			}
+		}
+
+		entry, ok := loadWaitlistEntry(w, r, db)
+		if !ok {
+			return
+		}
+
+		if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistCalled {
+			apierror.Respond(w, r, "Only waiting or called parties can be seated", http.StatusConflict)
+			return
+		}
+
+		if req.TableID != nil {
+			var table models.Table
+			if err := db.WithContext(r.Context()).First(&table, *req.TableID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					apierror.Respond(w, r, "Table not found", http.StatusNotFound)
+				} else {
+					apierror.Write(w, r, err)
+				}
+				return
+			}
+		}
+
+		now := time.Now()
+		entry.Status = models.WaitlistSeated
+		entry.SeatedAt = &now
+		entry.TableID = req.TableID
+		if err := db.WithContext(r.Context()).Save(&entry).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		publishWaitlist(r.Context(), db, broker, entry.LocationID)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(entry)
+	}
+}
+
+func RemoveWaitlistEntry(db *database.Manager, broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		entry, ok := loadWaitlistEntry(w, r, db)
+		if !ok {
+			return
+		}
+
+		if entry.Status != models.WaitlistWaiting && entry.Status != models.WaitlistCalled {
+			apierror.Respond(w, r, "Only waiting or called parties can be removed", http.StatusConflict)
+			return
+		}
+
+		entry.Status = models.WaitlistRemoved
+		if err := db.WithContext(r.Context()).Save(&entry).Error; err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		publishWaitlist(r.Context(), db, broker, entry.LocationID)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]string{"message": "Party removed from waitlist"})
+	}
+}
+
+// WaitlistStream pushes the queue to lobby displays as Server-Sent Events.
+// Displays that are not signed in pick their location with ?location_id=.
+func WaitlistStream(db *database.Manager, broker *stream.Broker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if _, scoped := auth.LocationIDFromContext(ctx); !scoped {
+			param := r.URL.Query().Get("location_id")
+			if param == "" && !isHeadOffice(ctx) {
+				apierror.Respond(w, r, "A location_id is required without a token", http.StatusBadRequest)
+				return
+			}
+			if param != "" {
+				locationID, err := strconv.Atoi(param)
+				if err != nil {
+					apierror.Respond(w, r, "Invalid location ID", http.StatusBadRequest)
+					return
+				}
+				ctx = auth.WithLocationID(ctx, uint(locationID))
+			}
+		}
+
+		entries, err := activeWaitlist(db.WithContext(ctx))
+		if err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		broker.Serve(w, r, waitlistTopic(ctx), waitlistEvent(entries))
+	}
+}
+
+func loadWaitlistEntry(w http.ResponseWriter, r *http.Request, db *database.Manager) (models.WaitlistEntry, bool) {
+	var entry models.WaitlistEntry
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		apierror.Respond(w, r, "Invalid waitlist entry ID", http.StatusBadRequest)
+		return entry, false
+	}
+
+	result := db.WithContext(r.Context()).First(&entry, id)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			apierror.Respond(w, r, "Waitlist entry not found", http.StatusNotFound)
+		} else {
+			apierror.Write(w, r, result.Error)
+		}
+		return entry, false
+	}
+	return entry, true
+}
+
+func activeWaitlist(tx *gorm.DB) ([]models.WaitlistEntry, error) {
+	var entries []models.WaitlistEntry
+	err := tx.Where("status IN ?", []string{models.WaitlistWaiting, models.WaitlistCalled}).
+		Order("created_at").Find(&entries).Error
+	return entries, err
+}
+
+// scopedTo limits ctx to locationID, so the tenant callbacks and the stream topic
+// follow the location of the entry rather than the caller's
+func scopedTo(ctx context.Context, locationID *uint) context.Context {
+	if locationID != nil {
+		return auth.WithLocationID(ctx, *locationID)
+	}
+	return ctx
+}
+
+func waitlistTopic(ctx context.Context) string {
+	locationID, _ := auth.LocationIDFromContext(ctx)
+	return fmt.Sprintf("waitlist:%d", locationID)
+}
+
+func waitlistEvent(entries []models.WaitlistEntry) WaitlistEvent {
+	event := WaitlistEvent{Entries: make([]WaitlistDisplayEntry, len(entries))}
+	for i, entry := range entries {
+		event.Entries[i] = WaitlistDisplayEntry{
+			QueueNumber:   entry.QueueNumber,
+			PartySize:     entry.PartySize,
+			Status:        entry.Status,
+			QuotedMinutes: entry.QuotedMinutes,
+		}
+	}
+	return event
+}
+
+// publishWaitlist sends the queue of a location, or of the whole business when
+// locationID is nil, to the displays following it
+func publishWaitlist(ctx context.Context, db *database.Manager, broker *stream.Broker, locationID *uint) {
+	ctx = scopedTo(ctx, locationID)
+	entries, err := activeWaitlist(db.WithContext(ctx))
+	if err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to load waitlist for publishing", "error", err)
+		return
+	}
+	if err := broker.Publish(waitlistTopic(ctx), waitlistEvent(entries)); err != nil {
+		logger.Logger.ErrorContext(ctx, "failed to publish waitlist", "error", err)
+	}
+}
+
+// estimateWait quotes a party's wait from the tables that fit it, when the parties
+// currently seated are expected to leave and how many parties are queued ahead.
+func estimateWait(tx *gorm.DB, partySize int, excludeEntryID uint, now time.Time) (WaitQuote, error) {
+	quote := WaitQuote{PartySize: partySize}
+
+	var tables []models.Table
+	if err := tx.Where("capacity >= ?", partySize).Find(&tables).Error; err != nil {
+		return quote, err
+	}
+	if len(tables) == 0 {
+		return quote, errNoTableFitsParty
+	}
+	maxCapacity := 0
+	for _, table := range tables {
+		if table.Capacity > maxCapacity {
+			maxCapacity = table.Capacity
+		}
+	}
+
+	seating, err := averageSeatingDuration(tx, now)
+	if err != nil {
+		return quote, err
+	}
+
+	var openOrders []models.Order
+	err = tx.Where("status IN ? AND table_number <> ''", []string{models.OrderPending, models.OrderPreparing, models.OrderServed}).
+		Find(&openOrders).Error
+	if err != nil {
+		return quote, err
+	}
+	seatedSince := make(map[string]time.Time, len(openOrders))
+	for _, order := range openOrders {
+		if since, ok := seatedSince[order.TableNumber]; !ok || order.CreatedAt.Before(since) {
+			seatedSince[order.TableNumber] = order.CreatedAt
+		}
+	}
+
+	freeAt := make(timeHeap, 0, len(tables))
+	for _, table := range tables {
+		free := now
+		if since, ok := seatedSince[table.Number]; ok {
+			free = since.Add(seating)
+			// Parties that overstay are assumed to leave shortly
+			if minimum := now.Add(5 * time.Minute); free.Before(minimum) {
+				free = minimum
+			}
+		}
+		freeAt = append(freeAt, free)
+	}
+	heap.Init(&freeAt)
+
+	var ahead []models.WaitlistEntry
+	err = tx.Where("status IN ? AND party_size <= ? AND id <> ?",
+		[]string{models.WaitlistWaiting, models.WaitlistCalled}, maxCapacity, excludeEntryID).
+		Find(&ahead).Error
+	if err != nil {
+		return quote, err
+	}
+	quote.PartiesAhead = len(ahead)
+
+	// Every party ahead takes the next table to free up and holds it for a full seating
+	for range ahead {
+		next := heap.Pop(&freeAt).(time.Time)
+		heap.Push(&freeAt, next.Add(seating))
+	}
+
+	wait := freeAt[0].Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	quote.QuotedMinutes = int((wait + time.Minute - 1) / time.Minute)
+	return quote, nil
+}
+
+// averageSeatingDuration is the mean time from order to completion over recent dine-in orders.
+// Locations without history fall back to the configured reservation turn time.
+func averageSeatingDuration(tx *gorm.DB, now time.Time) (time.Duration, error) {
+	var orders []models.Order
+	err := tx.Select("created_at", "completed_at").
+		Where("status = ? AND table_number <> '' AND completed_at >= ?", models.OrderCompleted, now.Add(-seatingHistoryWindow)).
+		Order("completed_at desc").Limit(500).Find(&orders).Error
+	if err != nil {
+		return 0, err
+	}
+
+	var total time.Duration
+	for _, order := range orders {
+		total += order.CompletedAt.Sub(order.CreatedAt)
+	}
+	if len(orders) > 0 && total > 0 {
+		return total / time.Duration(len(orders)), nil
+	}
+
+	info, err := database.RestaurantInfoFor(tx, callerLocation(tx.Statement.Context, nil))
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	return time.Duration(info.TurnMinutes()) * time.Minute, nil
+}
+
+func writeWaitlistError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, errNoTableFitsParty) {
+		apierror.Write(w, r, apierror.Wrap(err, http.StatusUnprocessableEntity))
+		return
+	}
+	apierror.Write(w, r, err)
+}
+
+// timeHeap is a min-heap of times
+type timeHeap []time.Time
+
+func (h timeHeap) Len() int            { return len(h) }
+func (h timeHeap) Less(i, j int) bool  { return h[i].Before(h[j]) }
+func (h timeHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *timeHeap) Push(x interface{}) { *h = append(*h, x.(time.Time)) }
+func (h *timeHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+)
+
+func TestWaitlistTopic(t *testing.T) {
+	three := uint(3)
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		locationID *uint
+		want       string
+	}{
+		{"head office adding to a location", context.Background(), &three, "waitlist:3"},
+		{"location adding to its own queue", auth.WithLocationID(context.Background(), 3), &three, "waitlist:3"},
+		{"head office queue", context.Background(), nil, "waitlist:0"},
+		{"location without an entry location", auth.WithLocationID(context.Background(), 5), nil, "waitlist:5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := waitlistTopic(scopedTo(tt.ctx, tt.locationID)); got != tt.want {
+				t.Errorf("topic = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// Sink delivers rendered tickets to a printer
+type Sink interface {
+	Send(ctx context.Context, address string, data []byte) error
+}
+
+// Network printers take raw ESC/POS on the ports print servers listen on
+const (
+	minPrinterPort = 9100
+	maxPrinterPort = 9109
+)
+
+var (
+	ErrInvalidPrinterAddress = errors.New("invalid printer address")
+
+	hostName = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?)*$`)
+)
+
+// ValidatePrinterAddress checks that address is a host:port on a private network and
+// a printer port, so a station cannot point the API at arbitrary services
+func ValidatePrinterAddress(address string) error {
+	host, portText, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: expected host:port", ErrInvalidPrinterAddress)
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil || port < minPrinterPort || port > maxPrinterPort {
+		return fmt.Errorf("%w: port must be between %d and %d", ErrInvalidPrinterAddress, minPrinterPort, maxPrinterPort)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if !ip.IsPrivate() {
+			return fmt.Errorf("%w: %s is not a private network address", ErrInvalidPrinterAddress, host)
+		}
+		return nil
+	}
+	if len(host) > 253 || !hostName.MatchString(host) {
+		return fmt.Errorf("%w: invalid host name", ErrInvalidPrinterAddress)
+	}
+	return nil
+}
+
+// dialPrivateOnly refuses connections to addresses off the private network, which
+// catches host names that resolve somewhere they should not
+func dialPrivateOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsPrivate() {
+		return fmt.Errorf("%w: %s is not a private network address", ErrInvalidPrinterAddress, host)
+	}
+	return nil
+}
+
+// NetworkSink sends raw ESC/POS bytes to printers listening on TCP, usually port 9100
+type NetworkSink struct {
+	Timeout time.Duration
+}
+
+func (s NetworkSink) Send(ctx context.Context, address string, data []byte) error {
+	if err := ValidatePrinterAddress(address); err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: s.Timeout, Control: dialPrivateOnly}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to connect to printer %s: %w", address, err)
+	}
+	defer conn.Close()
+
+	if s.Timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(s.Timeout))
+	}
+	if _, err := conn.Write(data); err != nil {
+		return fmt.Errorf("failed to write to printer %s: %w", address, err)
+	}
+	return nil
+}
+
+// FileSink writes every ticket to its own file in Dir instead of printing it
+type FileSink struct {
+	Dir   string
+	count atomic.Int64
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+func (s *FileSink) Send(ctx context.Context, address string, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%d-%d.bin", unsafeFileChars.ReplaceAllString(address, "_"), time.Now().UnixNano(), s.count.Add(1))
+	return os.WriteFile(filepath.Join(s.Dir, name), data, 0o644)
+}
+
+// PrintTickets renders the tickets and sends each to its station's printer.
+// Tickets of stations without a printer are only shown on the kitchen display.
+func PrintTickets(ctx context.Context, db *gorm.DB, sink Sink, ticketIDs []uint) error {
+	if sink == nil || len(ticketIDs) == 0 {
+		return nil
+	}
+
+	var tickets []models.Ticket
+	if err := db.Preload("Order").Preload("Station").Preload("Items").Where("id IN ?", ticketIDs).Find(&tickets).Error; err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, ticket := range tickets {
+		if ticket.Station == nil || ticket.Station.PrinterAddress == "" {
+			continue
+		}
+		if err := sink.Send(ctx, ticket.Station.PrinterAddress, RenderTicket(ticket, escpos.Width80mm)); err != nil {
+			logger.Logger.ErrorContext(ctx, "failed to print ticket", "ticket_id", ticket.ID, "station", ticket.Station.Name, "error", err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// TicketIDs returns the IDs of tickets
+func TicketIDs(tickets []models.Ticket) []uint {
+	ids := make([]uint, len(tickets))
+	for i, ticket := range tickets {
+		ids[i] = ticket.ID
+	}
+	return ids
+}
@@ -0,0 +1,39 @@
+package kitchen
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidatePrinterAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		valid   bool
+	}{
+		{"192.168.1.50:9100", true},
+		{"10.0.0.7:9109", true},
+		{"172.16.4.2:9105", true},
+		{"[fd00::1]:9100", true},
+		{"kitchen-printer.local:9100", true},
+		{"bar:9101", true},
+		{"192.168.1.50:9110", false},
+		{"192.168.1.50:22", false},
+		{"192.168.1.50:http", false},
+		{"192.168.1.50", false},
+		{"127.0.0.1:9100", false},
+		{"169.254.169.254:9100", false},
+		{"8.8.8.8:9100", false},
+		{"-printer:9100", false},
+		{"printer_1:9100", false},
+		{":9100", false},
+	}
+	for _, tt := range tests {
+		err := ValidatePrinterAddress(tt.address)
+		if tt.valid && err != nil {
+			t.Errorf("ValidatePrinterAddress(%q) = %v, want nil", tt.address, err)
+		}
+		if !tt.valid && !errors.Is(err, ErrInvalidPrinterAddress) {
+			t.Errorf("ValidatePrinterAddress(%q) = %v, want ErrInvalidPrinterAddress", tt.address, err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package kitchen
+
+import (
+	"context"
+	"sync"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+)
+
+// PrintQueue prints tickets in the background, so a slow or offline printer never
+// holds up taking an order. Tickets that are not printed can be reprinted.
+type PrintQueue struct {
+	db   *database.Manager
+	sink Sink
+
+	mu     sync.RWMutex
+	closed bool
+	jobs   chan printJob
+}
+
+type printJob struct {
+	ctx       context.Context
+	ticketIDs []uint
+}
+
+// NewPrintQueue returns a queue holding up to size print jobs for sink
+func NewPrintQueue(db *database.Manager, sink Sink, size int) *PrintQueue {
+	return &PrintQueue{db: db, sink: sink, jobs: make(chan printJob, size)}
+}
+
+// Enqueue queues the tickets for printing. The request ending does not cancel the
+// job, but its request ID stays on the logs. Tickets are dropped, with an error
+// logged, when the queue is full or closed.
+func (q *PrintQueue) Enqueue(ctx context.Context, ticketIDs []uint) {
+	if len(ticketIDs) == 0 {
+		return
+	}
+
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		logger.Logger.ErrorContext(ctx, "print queue closed, tickets not printed", "ticket_ids", ticketIDs)
+		return
+	}
+	select {
+	case q.jobs <- printJob{ctx: context.WithoutCancel(ctx), ticketIDs: ticketIDs}:
+	default:
+		logger.Logger.ErrorContext(ctx, "print queue full, tickets not printed", "ticket_ids", ticketIDs)
+	}
+}
+
+// Close stops the queue taking jobs; Run returns once the queued jobs are printed
+func (q *PrintQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+}
+
+// Run prints queued jobs with the given number of workers until the queue is closed
+// and empty. PrintTickets logs the tickets that fail.
+func (q *PrintQueue) Run(workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range q.jobs {
+				PrintTickets(job.ctx, q.db.WithContext(job.ctx), q.sink, job.ticketIDs)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,116 @@
+package kitchen
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// ErrNotScheduled is returned by Release for an order that was released or cancelled in the meantime
+var ErrNotScheduled = errors.New("order is no longer scheduled")
+
+// Release sends a scheduled order to the kitchen and splits it into station tickets.
+// The update only applies while the order is still scheduled, so an order cannot be
+// released twice or brought back once cancelled.
+func Release(tx *gorm.DB, order *models.Order, now time.Time) ([]models.Ticket, error) {
+	result := tx.Model(order).Where("status = ?", models.OrderScheduled).
+		Updates(map[string]interface{}{"status": models.OrderPending, "released_at": now})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotScheduled
+	}
+	order.Status = models.OrderPending
+	order.ReleasedAt = &now
+	return SplitOrder(tx, order)
+}
+
+// IsDue reports whether a scheduled order should be in the kitchen by now
+func IsDue(order models.Order, lead time.Duration, now time.Time) bool {
+	return order.ScheduledFor == nil || !order.ScheduledFor.Add(-lead).After(now)
+}
+
+// ReleaseDueOrders releases every scheduled order whose kitchen lead time has been reached
+// and queues its tickets for printing
+func ReleaseDueOrders(ctx context.Context, db *gorm.DB, printer *PrintQueue, now time.Time) (int, error) {
+	var orders []models.Order
+	if err := db.Where("status = ?", models.OrderScheduled).Find(&orders).Error; err != nil {
+		return 0, err
+	}
+	if len(orders) == 0 {
+		return 0, nil
+	}
+
+	var infos []models.RestaurantInfo
+	if err := db.Find(&infos).Error; err != nil {
+		return 0, err
+	}
+	leads := make(map[uint]time.Duration, len(infos))
+	defaultLead := models.RestaurantInfo{}.KitchenLead()
+	for _, info := range infos {
+		if info.LocationID == nil {
+			defaultLead = info.KitchenLead()
+			continue
+		}
+		leads[*info.LocationID] = info.KitchenLead()
+	}
+
+	released := 0
+	for i := range orders {
+		lead := defaultLead
+		if orders[i].LocationID != nil {
+			if locationLead, ok := leads[*orders[i].LocationID]; ok {
+				lead = locationLead
+			}
+		}
+		if !IsDue(orders[i], lead, now) {
+			continue
+		}
+
+		var tickets []models.Ticket
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			tickets, err = Release(tx, &orders[i], now)
+			return err
+		})
+		if errors.Is(err, ErrNotScheduled) {
+			// Cancelled or released elsewhere since the orders were listed
+			continue
+		}
+		if err != nil {
+			return released, err
+		}
+		released++
+		printer.Enqueue(ctx, TicketIDs(tickets))
+	}
+	return released, nil
+}
+
+// RunReleaser releases scheduled orders every interval until ctx is cancelled
+func RunReleaser(ctx context.Context, db *database.Manager, printer *PrintQueue, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			released, err := ReleaseDueOrders(ctx, db.WithContext(ctx), printer, now)
+			if err != nil {
+				logger.ErrorLogger.Printf("Failed to release scheduled orders: %v", err)
+				continue
+			}
+			if released > 0 {
+				logger.InfoLogger.Printf("Released %d scheduled orders to the kitchen", released)
+			}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package kitchen
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// SplitOrder creates one ticket per station for the items of an order. Items route to
+// their own station, then to their category's station; anything else shares a ticket
+// without a station.
+func SplitOrder(tx *gorm.DB, order *models.Order) ([]models.Ticket, error) {
+	var details []models.OrderDetail
+	if err := tx.Preload("SelectedAddOns").Where("order_id = ?", order.ID).Order("id").Find(&details).Error; err != nil {
+		return nil, err
+	}
+	if len(details) == 0 {
+		return nil, nil
+	}
+
+	itemIDs := make([]uint, len(details))
+	for i, detail := range details {
+		itemIDs[i] = detail.MenuItemID
+	}
+	var menuItems []models.MenuItem
+	if err := tx.Unscoped().Where("id IN ?", itemIDs).Find(&menuItems).Error; err != nil {
+		return nil, err
+	}
+
+	categoryIDs := make([]uint, 0, len(menuItems))
+	for _, item := range menuItems {
+		categoryIDs = append(categoryIDs, item.CategoryID)
+	}
+	var categories []models.Category
+	if err := tx.Unscoped().Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	categoryStations := make(map[uint]*uint, len(categories))
+	for _, category := range categories {
+		categoryStations[category.ID] = category.StationID
+	}
+	itemNames := make(map[uint]string, len(menuItems))
+	itemStations := make(map[uint]*uint, len(menuItems))
+	for _, item := range menuItems {
+		itemNames[item.ID] = item.Name
+		itemStations[item.ID] = item.StationID
+		if item.StationID == nil {
+			itemStations[item.ID] = categoryStations[item.CategoryID]
+		}
+	}
+
+	var tickets []models.Ticket
+	ticketIndex := make(map[uint]int)
+	for _, detail := range details {
+		stationID := itemStations[detail.MenuItemID]
+		key := uint(0)
+		if stationID != nil {
+			key = *stationID
+		}
+
+		i, ok := ticketIndex[key]
+		if !ok {
+			tickets = append(tickets, models.Ticket{
+				LocationID: order.LocationID,
+				OrderID:    order.ID,
+				StationID:  stationID,
+				Status:     models.TicketPending,
+			})
+			i = len(tickets) - 1
+			ticketIndex[key] = i
+		}
+
+		addOns := make([]string, len(detail.SelectedAddOns))
+		for j, addOn := range detail.SelectedAddOns {
+			addOns[j] = addOn.Name
+		}
+		tickets[i].Items = append(tickets[i].Items, models.TicketItem{
+			OrderDetailID:       detail.ID,
+			Name:                itemNames[detail.MenuItemID],
+			Quantity:            detail.Quantity,
+			AddOns:              strings.Join(addOns, ", "),
+			SpecialInstructions: detail.SpecialInstructions,
+		})
+	}
+
+	if err := tx.Create(&tickets).Error; err != nil {
+		return nil, err
+	}
+	return tickets, nil
+}
+
+// RenderTicket renders a ticket as an ESC/POS byte stream. The ticket needs its Order,
+// Station and Items loaded.
+func RenderTicket(ticket models.Ticket, paperWidth int) []byte {
+	w := escpos.NewWriter(paperWidth)
+
+	station := "Kitchen"
+	if ticket.Station != nil {
+		station = ticket.Station.Name
+	}
+	w.Align(escpos.AlignCenter).DoubleSize(true).Line(station).DoubleSize(false)
+
+	if ticket.Order != nil {
+		order := ticket.Order
+		w.Bold(true)
+		switch order.OrderType {
+		case models.OrderTypeTakeout, models.OrderTypeDelivery:
+			w.Line(fmt.Sprintf("%s #%d", strings.ToUpper(order.OrderType), order.ID))
+		default:
+			w.Line(fmt.Sprintf("Table %s  #%d", order.TableNumber, order.ID))
+		}
+		w.Bold(false)
+		if order.ScheduledFor != nil {
+			w.Line("Due " + order.ScheduledFor.Format("15:04"))
+		}
+	}
+	w.Line(ticket.CreatedAt.Format(time.DateTime))
+	w.Align(escpos.AlignLeft).Rule('-')
+
+	for _, item := range ticket.Items {
+		w.DoubleSize(true).Line(escpos.Truncate(fmt.Sprintf("%dx %s", item.Quantity, item.Name), paperWidth/2)).DoubleSize(false)
+		if item.AddOns != "" {
+			w.Line("  + " + item.AddOns)
+		}
+		if item.SpecialInstructions != "" {
+			w.Bold(true).Line("  ! " + item.SpecialInstructions).Bold(false)
+		}
+	}
+
+	return w.Rule('-').Feed(3).Cut().Bytes()
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type scopeKey struct{}
+
+// requestScope identifies the request a log record belongs to. The user is
+// filled in once the request's token has been checked, which happens after
+// the scope is created, so it is shared by pointer.
+type requestScope struct {
+	id string
+
+	mu         sync.Mutex
+	user       string
+	locationID *uint
+}
+
+// WithRequestID starts the log scope of a request
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, &requestScope{id: id})
+}
+
+// RequestID returns the ID of the request ctx belongs to, or ""
+func RequestID(ctx context.Context) string {
+	if scope := scopeFrom(ctx); scope != nil {
+		return scope.id
+	}
+	return ""
+}
+
+// SetUser records who made the request, for every record logged with it from now on
+func SetUser(ctx context.Context, username string, locationID *uint) {
+	scope := scopeFrom(ctx)
+	if scope == nil {
+		return
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	scope.user = username
+	scope.locationID = locationID
+}
+
+func scopeFrom(ctx context.Context) *requestScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(scopeKey{}).(*requestScope)
+	return scope
+}
+
+func (s *requestScope) attrs() []slog.Attr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attrs := []slog.Attr{slog.String("request_id", s.id)}
+	if s.user != "" {
+		attrs = append(attrs, slog.String("user", s.user))
+	}
+	if s.locationID != nil {
+		attrs = append(attrs, slog.Uint64("location_id", uint64(*s.locationID)))
+	}
+	return attrs
+}
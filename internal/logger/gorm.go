@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/metrics"
+)
+
+// GormLogger writes GORM's messages and SQL statements to Logger, tagged with
+// the request that ran them
+type GormLogger struct {
+	LogLevel gormlogger.LogLevel
+}
+
+// GetGormLogger returns a GORM logger instance
+func GetGormLogger(logLevel gormlogger.LogLevel) gormlogger.Interface {
+	return &GormLogger{
+		LogLevel: logLevel,
+	}
+}
+
+// LogMode sets the log level
+func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	newLogger := *l
+	newLogger.LogLevel = level
+	return &newLogger
+}
+
+// Info prints info
+func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= gormlogger.Info {
+		l.log(ctx, slog.LevelInfo, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn prints warn messages
+func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= gormlogger.Warn {
+		l.log(ctx, slog.LevelWarn, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error prints error messages
+func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.LogLevel >= gormlogger.Error {
+		l.log(ctx, slog.LevelError, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace times every statement for the metrics, and logs those that failed or,
+// at the Info level, all of them. A missing record is an ordinary outcome, not a failure.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	metrics.ObserveQuery(time.Since(begin), failed)
+
+	if l.LogLevel <= gormlogger.Silent {
+		return
+	}
+	if !failed && l.LogLevel < gormlogger.Info {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("elapsed_ms", float64(time.Since(begin).Microseconds())/1e3),
+	}
+	if failed {
+		l.log(ctx, slog.LevelError, "query failed", append(attrs, slog.String("error", err.Error()))...)
+	} else {
+		l.log(ctx, slog.LevelDebug, "query", attrs...)
+	}
+}
+
+// ParamsFilter hides the values bound to sensitive columns before GORM
+// renders a statement for Trace
+func (l *GormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	redacted := sensitiveParams(sql)
+	if len(redacted) == 0 {
+		return sql, params
+	}
+
+	filtered := make([]interface{}, len(params))
+	copy(filtered, params)
+	for n := range redacted {
+		if n >= 1 && n <= len(filtered) {
+			filtered[n-1] = "[redacted]"
+		}
+	}
+	return sql, filtered
+}
+
+func (l *GormLogger) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !Logger.Enabled(ctx, level) {
+		return
+	}
+	record := slog.NewRecord(time.Now(), level, msg, callerPC())
+	record.AddAttrs(attrs...)
+	Logger.Handler().Handle(ctx, record)
+}
+
+// callerPC finds the application code that ran the statement, above GORM and this logger
+func callerPC() uintptr {
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.File, "gorm.io/") && !strings.HasSuffix(frame.File, "/logger/gorm.go") {
+			return frame.PC
+		}
+		if !more {
+			return 0
+		}
+	}
+}
+
+var (
+	// sensitiveColumn matches columns whose values stay out of the logs
+	sensitiveColumn = regexp.MustCompile(`(?i)(^|_)(password|pin|token|secret|phone|email)($|_)`)
+
+	// comparison matches a column compared with or assigned a parameter, as in "pin" = $3
+	comparison = regexp.MustCompile(`(?i)"?(\w+)"?\s*(?:=|<>|!=|<=|>=|<|>|\bI?LIKE\b)\s*\$(\d+)`)
+	// membership matches a column tested against a parameter list, as in "phone" IN ($1,$2)
+	membership = regexp.MustCompile(`(?i)"?(\w+)"?\s+IN\s*\(((?:\s*\$\d+\s*,?)+)\)`)
+	// insert matches the column list and the values of an INSERT
+	insert      = regexp.MustCompile(`(?is)^\s*INSERT INTO \S+ \(([^)]*)\) VALUES (.*?)(?:\s+ON CONFLICT|\s+RETURNING|$)`)
+	placeholder = regexp.MustCompile(`\$(\d+)`)
+)
+
+// sensitiveParams returns the numbers of the $N parameters bound to sensitive columns in sql
+func sensitiveParams(sql string) map[int]bool {
+	redacted := make(map[int]bool)
+	add := func(text string) {
+		for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+			n, _ := strconv.Atoi(m[1])
+			redacted[n] = true
+		}
+	}
+
+	for _, m := range comparison.FindAllStringSubmatch(sql, -1) {
+		if sensitiveColumn.MatchString(m[1]) {
+			add("$" + m[2])
+		}
+	}
+	for _, m := range membership.FindAllStringSubmatch(sql, -1) {
+		if sensitiveColumn.MatchString(m[1]) {
+			add(m[2])
+		}
+	}
+
+	if m := insert.FindStringSubmatch(sql); m != nil {
+		columns := strings.Split(m[1], ",")
+		for i, p := range placeholder.FindAllString(m[2], -1) {
+			column := strings.Trim(strings.TrimSpace(columns[i%len(columns)]), `"`)
+			if sensitiveColumn.MatchString(column) {
+				add(p)
+			}
+		}
+	}
+	return redacted
+}
@@ -3,73 +3,81 @@ package logger
 import (
 	"context"
 	"log"
+	"log/slog"
 	"os"
-	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	gormlogger "gorm.io/gorm/logger"
 )
 
 var (
+	// Logger writes leveled JSON records to stdout. Records logged with a
+	// request's context carry its request ID and user.
+	Logger *slog.Logger
+
+	// InfoLogger and ErrorLogger feed Printf-style messages into Logger
 	InfoLogger  *log.Logger
 	ErrorLogger *log.Logger
+
+	level = new(slog.LevelVar)
 )
 
 func init() {
-	InfoLogger = log.New(os.Stdout, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
-	ErrorLogger = log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
-}
+	Logger = slog.New(contextHandler{slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		AddSource: true,
+		Level:     level,
+	})})
+	slog.SetDefault(Logger)
 
-// GormLogger wraps gorm.io/gorm/logger.Interface
-type GormLogger struct {
-	LogLevel gormlogger.LogLevel
+	InfoLogger = slog.NewLogLogger(Logger.Handler(), slog.LevelInfo)
+	ErrorLogger = slog.NewLogLogger(Logger.Handler(), slog.LevelError)
 }
 
-// GetGormLogger returns a GORM logger instance
-func GetGormLogger(logLevel gormlogger.LogLevel) gormlogger.Interface {
-	return &GormLogger{
-		LogLevel: logLevel,
+// SetLevel sets the lowest level written: debug, info, warn or error
+func SetLevel(name string) {
+	switch name {
+	case "debug":
+		level.Set(slog.LevelDebug)
+	case "warn":
+		level.Set(slog.LevelWarn)
+	case "error":
+		level.Set(slog.LevelError)
+	default:
+		level.Set(slog.LevelInfo)
 	}
 }
 
-// LogMode sets the log level
-func (l *GormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
-	newLogger := *l
-	newLogger.LogLevel = level
-	return &newLogger
-}
-
-// Info prints info
-func (l *GormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormlogger.Info {
-		InfoLogger.Printf(msg, data...)
+// GormLevel maps a configured log level to GORM's, logging every SQL statement only at debug
+func GormLevel(level string) gormlogger.LogLevel {
+	switch level {
+	case "debug":
+		return gormlogger.Info
+	case "error":
+		return gormlogger.Error
+	default:
+		return gormlogger.Warn
 	}
 }
 
-// Warn prints warn messages
-func (l *GormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormlogger.Warn {
-		InfoLogger.Printf(msg, data...)
-	}
+// contextHandler adds the request ID, user, location and trace of the record's context
+type contextHandler struct {
+	slog.Handler
 }
 
-// Error prints error messages
-func (l *GormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
-	if l.LogLevel >= gormlogger.Error {
-		ErrorLogger.Printf(msg, data...)
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if scope := scopeFrom(ctx); scope != nil {
+		record.AddAttrs(scope.attrs()...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		record.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
 	}
+	return h.Handler.Handle(ctx, record)
 }
 
-// Trace prints trace messages
-func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
-	if l.LogLevel <= gormlogger.Silent {
-		return
-	}
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
 
-	elapsed := time.Since(begin)
-	sql, rows := fc()
-	if err != nil {
-		ErrorLogger.Printf("[%.3fms] [rows:%v] %s; %s", float64(elapsed.Nanoseconds())/1e6, rows, sql, err)
-	} else if l.LogLevel >= gormlogger.Info {
-		InfoLogger.Printf("[%.3fms] [rows:%v] %s", float64(elapsed.Nanoseconds())/1e6, rows, sql)
-	}
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
 }
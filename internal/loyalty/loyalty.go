@@ -0,0 +1,215 @@
+// Package loyalty keeps the customer points ledger: points earned on completed
+// orders, redeemed as discounts, given back or taken back when orders are
+// cancelled or refunded, and written off when they expire
+package loyalty
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+var ErrNotEnoughPoints = errors.New("not enough loyalty points")
+
+// credits are the ledger entries holding points that can still be used
+var credits = []string{models.PointsEarned, models.PointsRestored}
+
+// Earn credits the customer of a completed order with points for what they paid
+func Earn(tx *gorm.DB, order models.Order, settings models.LoyaltySettings, now time.Time) error {
+	if order.CustomerID == nil {
+		return nil
+	}
+
+	var earned int64
+	err := tx.Model(&models.LoyaltyEntry{}).
+		Where("order_id = ? AND type = ?", order.ID, models.PointsEarned).Count(&earned).Error
+	if err != nil || earned > 0 {
+		return err
+	}
+
+	points := settings.PointsEarned(order.PaidAmount() - order.RefundedAmount())
+	if points <= 0 {
+		return nil
+	}
+	expiresAt := settings.PointsExpiry(now)
+	return tx.Create(&models.LoyaltyEntry{
+		CustomerID: *order.CustomerID,
+		OrderID:    &order.ID,
+		Type:       models.PointsEarned,
+		Points:     points,
+		Remaining:  points,
+		ExpiresAt:  &expiresAt,
+	}).Error
+}
+
+// Redeem uses the customer's oldest points first and records the redemption
+func Redeem(tx *gorm.DB, customerID, orderID uint, points int, now time.Time) error {
+	// Lock the customer so concurrent redemptions cannot spend the same points
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, customerID).Error; err != nil {
+		return err
+	}
+	if err := Expire(tx, customerID, now); err != nil {
+		return err
+	}
+
+	var entries []models.LoyaltyEntry
+	err := tx.Where("customer_id = ? AND type IN ? AND remaining > 0", customerID, credits).
+		Order("expires_at, id").Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	available := 0
+	for _, entry := range entries {
+		available += entry.Remaining
+	}
+	if available < points {
+		return ErrNotEnoughPoints
+	}
+	if _, err := use(tx, entries, points); err != nil {
+		return err
+	}
+
+	return tx.Create(&models.LoyaltyEntry{
+		CustomerID: customerID,
+		OrderID:    &orderID,
+		Type:       models.PointsRedeemed,
+		Points:     -points,
+	}).Error
+}
+
+// Restore gives the customer of a cancelled order back the points redeemed
+// on it, with a fresh expiry. Points already restored are not given twice.
+func Restore(tx *gorm.DB, order models.Order, settings models.LoyaltySettings, now time.Time) error {
+	if order.CustomerID == nil {
+		return nil
+	}
+
+	// Redemptions are negative and restorations positive, so what is owed is minus their sum
+	var owed int
+	err := tx.Model(&models.LoyaltyEntry{}).Select("COALESCE(-SUM(points), 0)").
+		Where("order_id = ? AND type IN ?", order.ID, []string{models.PointsRedeemed, models.PointsRestored}).
+		Scan(&owed).Error
+	if err != nil || owed <= 0 {
+		return err
+	}
+
+	expiresAt := settings.PointsExpiry(now)
+	return tx.Create(&models.LoyaltyEntry{
+		CustomerID: *order.CustomerID,
+		OrderID:    &order.ID,
+		Type:       models.PointsRestored,
+		Points:     owed,
+		Remaining:  owed,
+		ExpiresAt:  &expiresAt,
+	}).Error
+}
+
+// Reverse takes back the points earned on an order beyond what its paid
+// amount less refunds now earns. They come off the order's own entry first and
+// then the customer's oldest points; points already spent and not covered by
+// others are left for a later refund to take back.
+func Reverse(tx *gorm.DB, order models.Order, settings models.LoyaltySettings, now time.Time) error {
+	if order.CustomerID == nil {
+		return nil
+	}
+
+	var earned int
+	err := tx.Model(&models.LoyaltyEntry{}).Select("COALESCE(SUM(points), 0)").
+		Where("order_id = ? AND type IN ?", order.ID, []string{models.PointsEarned, models.PointsReversed}).
+		Scan(&earned).Error
+	if err != nil {
+		return err
+	}
+	excess := earned - settings.PointsEarned(order.PaidAmount()-order.RefundedAmount())
+	if excess <= 0 {
+		return nil
+	}
+
+	var customer models.Customer
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&customer, *order.CustomerID).Error; err != nil {
+		return err
+	}
+	if err := Expire(tx, customer.ID, now); err != nil {
+		return err
+	}
+
+	var entries []models.LoyaltyEntry
+	err = tx.Where("customer_id = ? AND type IN ? AND remaining > 0", customer.ID, credits).
+		Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "CASE WHEN order_id = ? THEN 0 ELSE 1 END, expires_at, id",
+			Vars: []interface{}{order.ID},
+		}}).
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+	taken, err := use(tx, entries, excess)
+	if err != nil || taken == 0 {
+		return err
+	}
+
+	return tx.Create(&models.LoyaltyEntry{
+		CustomerID: customer.ID,
+		OrderID:    &order.ID,
+		Type:       models.PointsReversed,
+		Points:     -taken,
+	}).Error
+}
+
+// use takes up to points from the remaining points of entries, in order,
+// and returns how many it took
+func use(tx *gorm.DB, entries []models.LoyaltyEntry, points int) (int, error) {
+	taken := 0
+	for _, entry := range entries {
+		if taken == points {
+			break
+		}
+		used := min(entry.Remaining, points-taken)
+		if err := tx.Model(&entry).Update("remaining", entry.Remaining-used).Error; err != nil {
+			return taken, err
+		}
+		taken += used
+	}
+	return taken, nil
+}
+
+// Expire writes off earned and restored points past their expiry
+func Expire(tx *gorm.DB, customerID uint, now time.Time) error {
+	var entries []models.LoyaltyEntry
+	err := tx.Where("customer_id = ? AND type IN ? AND remaining > 0 AND expires_at <= ?", customerID, credits, now).
+		Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		expired := models.LoyaltyEntry{
+			CustomerID: customerID,
+			OrderID:    entry.OrderID,
+			Type:       models.PointsExpired,
+			Points:     -entry.Remaining,
+		}
+		if err := tx.Create(&expired).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&entry).Update("remaining", 0).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Balance returns the customer's unexpired points
+func Balance(tx *gorm.DB, customerID uint, now time.Time) (int, error) {
+	var balance int
+	err := tx.Model(&models.LoyaltyEntry{}).Select("COALESCE(SUM(remaining), 0)").
+		Where("customer_id = ? AND type IN ? AND expires_at > ?", customerID, credits, now).
+		Scan(&balance).Error
+	return balance, err
+}
@@ -0,0 +1,66 @@
+// Package metrics exposes HTTP, database and business metrics in the
+// Prometheus text format
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry holds every metric served on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "HTTP requests handled, by route template, method and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by route template and method.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Time taken by SQL statements, by whether they failed.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbQueryDuration,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled request. route is the path template, such
+// as /api/orders/{id}, so IDs do not each become a series.
+func ObserveRequest(method, route string, status int, elapsed time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(elapsed.Seconds())
+}
+
+// ObserveQuery records an SQL statement
+func ObserveQuery(elapsed time.Duration, failed bool) {
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+	dbQueryDuration.WithLabelValues(result).Observe(elapsed.Seconds())
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+const (
+	// ticketTimeWindow is how far back served orders count towards the average ticket time
+	ticketTimeWindow = time.Hour
+	// collectTimeout bounds the queries run for one scrape
+	collectTimeout = 5 * time.Second
+)
+
+var (
+	ordersDesc = prometheus.NewDesc("restaurant_orders",
+		"Orders in each status.", []string{"status"}, nil)
+	openOrdersDesc = prometheus.NewDesc("restaurant_open_orders",
+		"Orders neither completed nor cancelled.", nil, nil)
+	ticketTimeDesc = prometheus.NewDesc("restaurant_ticket_time_seconds",
+		"Average time from an order being placed to it being served, over orders served in the last hour.", nil, nil)
+	ticketTimeOrdersDesc = prometheus.NewDesc("restaurant_ticket_time_orders",
+		"Orders served in the last hour, which the average ticket time covers.", nil, nil)
+)
+
+// ordersCollector reads order counts from the database on every scrape, across all locations
+type ordersCollector struct {
+	orders repository.Orders
+}
+
+// NewOrdersCollector returns a collector of the order gauges
+func NewOrdersCollector(orders repository.Orders) prometheus.Collector {
+	return ordersCollector{orders: orders}
+}
+
+func (c ordersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- ordersDesc
+	ch <- openOrdersDesc
+	ch <- ticketTimeDesc
+	ch <- ticketTimeOrdersDesc
+}
+
+func (c ordersCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	stats, err := c.orders.Stats(ctx, time.Now().Add(-ticketTimeWindow))
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(ordersDesc, err)
+		return
+	}
+
+	open := 0
+	for _, status := range []string{
+		models.OrderScheduled, models.OrderPending, models.OrderPreparing,
+		models.OrderServed, models.OrderCompleted, models.OrderCancelled,
+	} {
+		count := stats.ByStatus[status]
+		ch <- prometheus.MustNewConstMetric(ordersDesc, prometheus.GaugeValue, float64(count), status)
+		if status != models.OrderCompleted && status != models.OrderCancelled {
+			open += count
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(openOrdersDesc, prometheus.GaugeValue, float64(open))
+	ch <- prometheus.MustNewConstMetric(ticketTimeDesc, prometheus.GaugeValue, stats.AverageTicketTime.Seconds())
+	ch <- prometheus.MustNewConstMetric(ticketTimeOrdersDesc, prometheus.GaugeValue, float64(stats.Served))
+}
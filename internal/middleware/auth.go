@@ -2,48 +2,102 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
 	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
 	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(cfg *config.DatabaseConfig) func(http.HandlerFunc) http.HandlerFunc {
+var errInvalidAuthHeader = errors.New("invalid authorization header")
+
+func AuthMiddleware(cfg config.JWTConfig) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				http.Error(w, "Missing authorization header", http.StatusUnauthorized)
+				apierror.Respond(w, r, "Missing authorization header", http.StatusUnauthorized)
 				return
 			}
 
-			bearerToken := strings.Split(authHeader, " ")
-			if len(bearerToken) != 2 {
-				http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+			claims, err := parseToken(cfg, authHeader)
+			if err != nil {
+				if errors.Is(err, errInvalidAuthHeader) {
+					apierror.Respond(w, r, "Invalid authorization header", http.StatusUnauthorized)
+				} else {
+					apierror.Respond(w, r, "Invalid token", http.StatusUnauthorized)
+				}
 				return
 			}
 
-			token, err := jwt.Parse(bearerToken[1], func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(cfg.JWTSecret), nil
-			})
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		}
+	}
+}
 
-			if err != nil {
-				http.Error(w, "Invalid token", http.StatusUnauthorized)
+// TenantMiddleware requires a valid token and scopes the request to the caller's location.
+// Without a location in the token the caller is head office and sees every location.
+func TenantMiddleware(cfg config.JWTConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return AuthMiddleware(cfg)(next.ServeHTTP)
+	}
+}
+
+// OptionalTenantMiddleware scopes requests carrying a valid token like TenantMiddleware,
+// but lets requests without a token through anonymously. A bad token is still rejected.
+func OptionalTenantMiddleware(cfg config.JWTConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-				ctx := context.WithValue(r.Context(), auth.ContextUsername, claims["sub"])
-				next.ServeHTTP(w, r.WithContext(ctx))
-			} else {
-				http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+			claims, err := parseToken(cfg, authHeader)
+			if err != nil {
+				apierror.Respond(w, r, "Invalid token", http.StatusUnauthorized)
+				return
 			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+func parseToken(cfg config.JWTConfig, authHeader string) (*auth.Claims, error) {
+	bearerToken := strings.Split(authHeader, " ")
+	if len(bearerToken) != 2 {
+		return nil, errInvalidAuthHeader
+	}
+
+	claims := &auth.Claims{}
+	token, err := jwt.ParseWithClaims(bearerToken[1], claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		return []byte(cfg.Secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token claims")
+	}
+
+	return claims, nil
+}
+
+func withClaims(ctx context.Context, claims *auth.Claims) context.Context {
+	logger.SetUser(ctx, claims.Subject, claims.LocationID)
+	ctx = context.WithValue(ctx, auth.ContextUsername, claims.Subject)
+	if claims.LocationID != nil {
+		ctx = auth.WithLocationID(ctx, *claims.LocationID)
 	}
+	return ctx
 }
@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+)
+
+// ClientIPMiddleware records the client's address in the request context for the audit log.
+// The first X-Forwarded-For address is used when the API runs behind a proxy.
+func ClientIPMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip = strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+		next.ServeHTTP(w, r.WithContext(auth.WithClientIP(r.Context(), ip)))
+	})
+}
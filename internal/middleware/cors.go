@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+)
+
+// CORSMiddleware lets the configured browser origins call the API and answers
+// their preflight requests. It wraps the whole router because mux does not run
+// its middleware for OPTIONS requests that match no route.
+func CORSMiddleware(cfg config.CORSConfig) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool)
+	anyOrigin := false
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			anyOrigin = true
+		}
+		allowed[strings.TrimSuffix(origin, "/")] = true
+	}
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin == "" || !(anyOrigin || allowed[origin]) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Add("Vary", "Origin")
+			if anyOrigin {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if cfg.AllowCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				h.Set("Access-Control-Max-Age", maxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrUsage is returned by Run for a command it does not understand
+var ErrUsage = errors.New("usage: up [N] | down [N] | status")
+
+// Run carries out a migrate command (up [N], down [N] or status) and reports
+// the result to out
+func (m *Migrator) Run(args []string, out io.Writer) error {
+	if len(args) == 0 || len(args) > 2 {
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := count(args, 0)
+		if err != nil {
+			return err
+		}
+		done, err := m.Up(n)
+		return report(out, "Applied", done, err)
+	case "down":
+		n, err := count(args, 1)
+		if err != nil {
+			return err
+		}
+		done, err := m.Down(n)
+		return report(out, "Reverted", done, err)
+	case "status":
+		if len(args) != 1 {
+			return ErrUsage
+		}
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d  %-40s %s\n", status.Version, status.Name, applied)
+		}
+		return nil
+	}
+	return ErrUsage
+}
+
+// count reads the optional N argument
+func count(args []string, fallback int) (int, error) {
+	if len(args) < 2 {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid count %q", args[1])
+	}
+	return n, nil
+}
+
+// report lists the migrations that ran, including those before a failure
+func report(out io.Writer, verb string, done []Migration, err error) error {
+	for _, m := range done {
+		fmt.Fprintf(out, "%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+	if err == nil && len(done) == 0 {
+		fmt.Fprintln(out, "Nothing to do")
+	}
+	return err
+}
@@ -0,0 +1,323 @@
+// Package migrations applies the numbered SQL files in sql/ to the database and
+// records each applied version in the schema_migrations table
+package migrations
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+const (
+	versionTable = "schema_migrations"
+	// lockKey serialises migration runs through a Postgres advisory lock
+	lockKey = 7231604
+)
+
+var (
+	fileName  = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	nameClean = regexp.MustCompile(`[^a-z0-9]+`)
+
+	ErrSchemaMismatch = errors.New("schema version mismatch")
+)
+
+// Migration is one numbered schema change with the SQL to apply and revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus reports whether a migration has been applied and when
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"`
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns a migrator for the migrations built into the binary
+func New(db *gorm.DB) (*Migrator, error) {
+	migrations, err := load(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// load reads the up/down pairs in dir, ordered by version
+func load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// WithContext returns a migrator whose queries are bound to ctx
+func (m *Migrator) WithContext(ctx context.Context) *Migrator {
+	return &Migrator{db: m.db.WithContext(ctx), migrations: m.migrations}
+}
+
+// Latest is the version the database is at once every migration is applied
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the highest applied version, 0 for a database never migrated
+func (m *Migrator) Current() (int64, error) {
+	if !m.db.Migrator().HasTable(versionTable) {
+		return 0, nil
+	}
+	var version int64
+	err := m.db.Raw("SELECT COALESCE(MAX(version), 0) FROM " + versionTable).Scan(&version).Error
+	return version, err
+}
+
+// Check fails unless the database is at exactly the version this build expects
+func (m *Migrator) Check() error {
+	current, err := m.Current()
+	if err != nil {
+		return err
+	}
+	switch latest := m.Latest(); {
+	case current < latest:
+		return fmt.Errorf("%w: database is at version %d, this build expects %d; run migrate up", ErrSchemaMismatch, current, latest)
+	case current > latest:
+		return fmt.Errorf("%w: database is at version %d, newer than this build's %d", ErrSchemaMismatch, current, latest)
+	}
+	return nil
+}
+
+// Status lists every known migration with when it was applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(m.migrations))
+	for i, migration := range m.migrations {
+		statuses[i] = MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := applied[migration.Version]; ok {
+			statuses[i].AppliedAt = &at
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies up to n pending migrations in order, or all of them when n is 0
+func (m *Migrator) Up(n int) (done []Migration, err error) {
+	err = m.locked(func(m *Migrator) error {
+		if err := m.ensureVersionTable(); err != nil {
+			return err
+		}
+		applied, err := m.applied()
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if n > 0 && len(done) == n {
+				break
+			}
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			err := m.run(migration, migration.Up, func(tx *gorm.DB) error {
+				return tx.Exec("INSERT INTO "+versionTable+" (version, name, applied_at) VALUES (?, ?, ?)",
+					migration.Version, migration.Name, time.Now()).Error
+			})
+			if err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last n applied migrations, newest first
+func (m *Migrator) Down(n int) (done []Migration, err error) {
+	if n <= 0 {
+		return nil, errors.New("number of migrations to revert must be positive")
+	}
+	err = m.locked(func(m *Migrator) error {
+		if err := m.ensureVersionTable(); err != nil {
+			return err
+		}
+		applied, err := m.applied()
+		if err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < n; i-- {
+			migration := m.migrations[i]
+			if _, ok := applied[migration.Version]; !ok {
+				continue
+			}
+			if strings.TrimSpace(migration.Down) == "" {
+				return fmt.Errorf("migration %d_%s cannot be reverted", migration.Version, migration.Name)
+			}
+			err := m.run(migration, migration.Down, func(tx *gorm.DB) error {
+				return tx.Exec("DELETE FROM "+versionTable+" WHERE version = ?", migration.Version).Error
+			})
+			if err != nil {
+				return err
+			}
+			done = append(done, migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn with a migrator bound to one connection that holds the
+// migration lock, so a second migrator waits rather than reading the applied
+// versions before the first has finished changing them
+func (m *Migrator) locked(fn func(*Migrator) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		conn = conn.Session(&gorm.Session{})
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+			return err
+		}
+		// The lock belongs to the session, so it is released even if ctx was cancelled
+		defer conn.WithContext(context.WithoutCancel(conn.Statement.Context)).Exec("SELECT pg_advisory_unlock(?)", lockKey)
+		return fn(&Migrator{db: conn, migrations: m.migrations})
+	})
+}
+
+// run executes a migration's SQL and its version bookkeeping in one transaction.
+// Callers hold the migration lock.
+func (m *Migrator) run(migration Migration, script string, record func(*gorm.DB) error) error {
+	err := m.db.Transaction(func(tx *gorm.DB) error {
+		// Without arguments the script is sent as one multi-statement query
+		if err := tx.Exec(script).Error; err != nil {
+			return err
+		}
+		return record(tx)
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) ensureVersionTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS ` + versionTable + ` (
+		version bigint PRIMARY KEY,
+		name text NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) applied() (map[int64]time.Time, error) {
+	applied := make(map[int64]time.Time)
+	if !m.db.Migrator().HasTable(versionTable) {
+		return applied, nil
+	}
+
+	var rows []struct {
+		Version   int64
+		AppliedAt time.Time
+	}
+	if err := m.db.Raw("SELECT version, applied_at FROM " + versionTable).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+	return applied, nil
+}
+
+// placeholder fills a new migration file until it is written, so applying or
+// reverting it fails rather than silently recording a change that did nothing
+const placeholder = "DO $$ BEGIN RAISE EXCEPTION 'migration %04d_%s has no %s script yet'; END $$;\n"
+
+// Create writes an up/down pair of placeholders numbered after the newest
+// migration in dir and returns the paths of the new files
+func Create(dir, name string) ([]string, error) {
+	name = strings.Trim(nameClean.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return nil, errors.New("migration name is required")
+	}
+
+	migrations, err := load(os.DirFS(dir), ".")
+	if err != nil {
+		return nil, err
+	}
+	var next int64 = 1
+	if len(migrations) > 0 {
+		next = migrations[len(migrations)-1].Version + 1
+	}
+
+	var paths []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%04d_%s.%s.sql", next, name, direction))
+		content := fmt.Sprintf("-- %04d %s (%s)\n", next, name, direction) + fmt.Sprintf(placeholder, next, name, direction)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
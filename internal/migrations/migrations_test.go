@@ -0,0 +1,180 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// baselineSchema is recreated by TestUpFromAutoMigrate in the TEST_DB_NAME database
+const baselineSchema = "migrations_baseline"
+
+// TestUpFromAutoMigrate builds the schema the old AutoMigrate start-up created,
+// with a row in it, and checks "migrate up" brings it level with the models.
+// Like the repository tests it needs TEST_DB_NAME and the usual DB_* settings.
+func TestUpFromAutoMigrate(t *testing.T) {
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s",
+		getenv("DB_HOST", "localhost"), getenv("DB_USER", "postgres"), os.Getenv("DB_PASSWORD"),
+		name, getenv("DB_PORT", "5432"), getenv("DB_SSLMODE", "disable"))
+	config := &gorm.Config{Logger: gormlogger.Discard}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec("DROP SCHEMA IF EXISTS " + baselineSchema + " CASCADE").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.Exec("CREATE SCHEMA " + baselineSchema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA IF EXISTS " + baselineSchema + " CASCADE") })
+
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+baselineSchema), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The models as they were before the migrations, declared children first
+	type SelectedAddOn struct {
+		gorm.Model
+		OrderDetailID uint
+		AddOnID       uint
+		Name          string  `gorm:"not null"`
+		Price         float64 `gorm:"not null"`
+	}
+	type OrderDetail struct {
+		gorm.Model
+		OrderID             uint
+		MenuItemID          uint
+		Quantity            int     `gorm:"not null"`
+		UnitPrice           float64 `gorm:"not null"`
+		Subtotal            float64 `gorm:"not null"`
+		SpecialInstructions string
+		SelectedAddOns      []SelectedAddOn
+	}
+	type Order struct {
+		gorm.Model
+		TableNumber  string  `gorm:"not null"`
+		Status       string  `gorm:"not null"`
+		TotalAmount  float64 `gorm:"not null"`
+		OrderDetails []OrderDetail
+	}
+	type AddOn struct {
+		gorm.Model
+		MenuItemID uint
+		Name       string  `gorm:"not null"`
+		Price      float64 `gorm:"not null"`
+	}
+	type MenuItem struct {
+		gorm.Model
+		CategoryID  uint
+		Name        string `gorm:"not null"`
+		Description string
+		Price       float64 `gorm:"not null"`
+		ImageURL    string
+		IsAvailable bool `gorm:"not null;default:true"`
+		AddOns      []AddOn
+	}
+	type Category struct {
+		gorm.Model
+		Name         string `gorm:"uniqueIndex;not null"`
+		DisplayOrder int    `gorm:"not null"`
+		MenuItems    []MenuItem
+	}
+	type User struct {
+		gorm.Model
+		Username string `gorm:"uniqueIndex;not null"`
+		Password string `gorm:"not null"`
+		Name     string `gorm:"not null"`
+		Role     string `gorm:"not null"`
+	}
+	type RestaurantInfo struct {
+		gorm.Model
+		Name         string `gorm:"uniqueIndex"`
+		Description  string
+		Address      string
+		Phone        string
+		Email        string
+		LogoURL      string
+		BannerURL    string
+		OpeningHours string `gorm:"type:jsonb"`
+	}
+	err = db.AutoMigrate(&User{}, &Category{}, &MenuItem{}, &AddOn{}, &Order{}, &OrderDetail{}, &SelectedAddOn{}, &RestaurantInfo{})
+	if err != nil {
+		t.Fatalf("creating the baseline schema: %v", err)
+	}
+	if err := db.Create(&Order{TableNumber: "A1", Status: models.OrderPending, TotalAmount: 120}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&RestaurantInfo{Name: "Noodle Bar", OpeningHours: "{}"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrating the baseline schema: %v", err)
+	}
+	if err := migrator.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, model := range []interface{}{
+		&models.User{}, &models.Category{}, &models.MenuItem{}, &models.AddOn{}, &models.Order{},
+		&models.OrderDetail{}, &models.SelectedAddOn{}, &models.RestaurantInfo{},
+	} {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !db.Migrator().HasColumn(model, field.DBName) {
+				t.Errorf("%s has no %s column", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	for _, index := range []struct{ table, name string }{
+		{"users", "idx_users_username"},
+		{"categories", "idx_categories_name"},
+		{"restaurant_infos", "idx_restaurant_infos_name"},
+	} {
+		if db.Migrator().HasIndex(index.table, index.name) {
+			t.Errorf("%s still has the baseline index %s", index.table, index.name)
+		}
+	}
+
+	var order models.Order
+	if err := db.First(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.OrderType != models.OrderTypeDineIn || order.TaxAmount != 0 {
+		t.Errorf("existing order has type %q and tax %v, want %q and 0", order.OrderType, order.TaxAmount, models.OrderTypeDineIn)
+	}
+	var info models.RestaurantInfo
+	if err := db.First(&info).Error; err != nil {
+		t.Fatal(err)
+	}
+	if info.TurnTimeMinutes != 90 || info.SpendPerPoint != 100 {
+		t.Errorf("existing restaurant info has turn time %d and spend per point %v, want 90 and 100", info.TurnTimeMinutes, info.SpendPerPoint)
+	}
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
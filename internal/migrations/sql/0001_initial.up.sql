@@ -0,0 +1,677 @@
+-- Initial schema. Every statement is idempotent so a database created by the
+-- old AutoMigrate start-up can adopt version 1 by running "migrate up". The
+-- tables that start-up created are given the columns added since then.
+
+-- Unique indexes that also covered soft-deleted rows, replaced by partial indexes
+DROP INDEX IF EXISTS idx_locations_name;
+DROP INDEX IF EXISTS idx_locations_code;
+DROP INDEX IF EXISTS idx_users_username;
+DROP INDEX IF EXISTS idx_categories_name;
+DROP INDEX IF EXISTS idx_restaurant_infos_name;
+DROP INDEX IF EXISTS idx_customers_phone;
+DROP INDEX IF EXISTS idx_customers_email;
+
+CREATE TABLE IF NOT EXISTS locations (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    code text NOT NULL,
+    address text,
+    phone text,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_code_active ON locations (code) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_locations_deleted_at ON locations (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_locations_name_active ON locations (name) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    username text NOT NULL,
+    password text NOT NULL,
+    pin text,
+    name text NOT NULL,
+    role text NOT NULL,
+    PRIMARY KEY (id)
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS location_id bigint;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pin text;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_location_id ON users (location_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_active ON users (username) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS categories (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    station_id bigint,
+    name text NOT NULL,
+    display_order bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS location_id bigint;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS station_id bigint;
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_categories_location_id ON categories (location_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name_active ON categories (name) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS menu_items (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    category_id bigint,
+    station_id bigint,
+    name text NOT NULL,
+    description text,
+    price decimal NOT NULL,
+    image_url text,
+    is_available boolean NOT NULL DEFAULT true,
+    PRIMARY KEY (id)
+);
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS location_id bigint;
+ALTER TABLE menu_items ADD COLUMN IF NOT EXISTS station_id bigint;
+CREATE INDEX IF NOT EXISTS idx_menu_items_deleted_at ON menu_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_menu_items_location_id ON menu_items (location_id);
+
+CREATE TABLE IF NOT EXISTS add_ons (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    menu_item_id bigint,
+    name text NOT NULL,
+    price decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_add_ons_deleted_at ON add_ons (deleted_at);
+
+CREATE TABLE IF NOT EXISTS menu_item_prices (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint NOT NULL,
+    menu_item_id bigint NOT NULL,
+    price decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_menu_item_prices_deleted_at ON menu_item_prices (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_menu_item_prices_location_item ON menu_item_prices (location_id,menu_item_id);
+
+CREATE TABLE IF NOT EXISTS customers (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    name text NOT NULL,
+    phone text,
+    email text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_customers_deleted_at ON customers (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_email_active ON customers (email) WHERE deleted_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customers_phone_active ON customers (phone) WHERE deleted_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS customer_favorites (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    customer_id bigint NOT NULL,
+    menu_item_id bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_favorites_customer_item ON customer_favorites (customer_id,menu_item_id);
+CREATE INDEX IF NOT EXISTS idx_customer_favorites_deleted_at ON customer_favorites (deleted_at);
+
+CREATE TABLE IF NOT EXISTS loyalty_entries (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    customer_id bigint NOT NULL,
+    order_id bigint,
+    type text NOT NULL,
+    points bigint NOT NULL,
+    remaining bigint NOT NULL DEFAULT 0,
+    expires_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_customer_id ON loyalty_entries (customer_id);
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_deleted_at ON loyalty_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_loyalty_entries_order_id ON loyalty_entries (order_id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_type text NOT NULL DEFAULT 'dine_in',
+    table_number text NOT NULL,
+    customer_name text,
+    customer_phone text,
+    delivery_address text,
+    scheduled_for timestamptz,
+    released_at timestamptz,
+    server_id bigint,
+    customer_id bigint,
+    feedback_token text,
+    status text NOT NULL,
+    tax_amount decimal NOT NULL DEFAULT 0,
+    total_amount decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS location_id bigint;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_type text NOT NULL DEFAULT 'dine_in';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_name text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_phone text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_address text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS scheduled_for timestamptz;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS released_at timestamptz;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS server_id bigint;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS customer_id bigint;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS feedback_token text;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_amount decimal NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_orders_customer_id ON orders (customer_id);
+CREATE INDEX IF NOT EXISTS idx_orders_deleted_at ON orders (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_orders_feedback_token ON orders (feedback_token);
+CREATE INDEX IF NOT EXISTS idx_orders_location_id ON orders (location_id);
+CREATE INDEX IF NOT EXISTS idx_orders_scheduled_for ON orders (scheduled_for);
+CREATE INDEX IF NOT EXISTS idx_orders_server_id ON orders (server_id);
+
+CREATE TABLE IF NOT EXISTS feedbacks (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_id bigint NOT NULL,
+    rating bigint NOT NULL,
+    comment text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_feedbacks_deleted_at ON feedbacks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_feedbacks_location_id ON feedbacks (location_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feedbacks_order_id ON feedbacks (order_id);
+
+CREATE TABLE IF NOT EXISTS item_ratings (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    feedback_id bigint NOT NULL,
+    menu_item_id bigint NOT NULL,
+    rating bigint NOT NULL,
+    comment text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_item_ratings_deleted_at ON item_ratings (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_item_ratings_feedback_id ON item_ratings (feedback_id);
+CREATE INDEX IF NOT EXISTS idx_item_ratings_location_id ON item_ratings (location_id);
+CREATE INDEX IF NOT EXISTS idx_item_ratings_menu_item_id ON item_ratings (menu_item_id);
+
+CREATE TABLE IF NOT EXISTS order_details (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    order_id bigint,
+    menu_item_id bigint,
+    menu_item_name text,
+    quantity bigint NOT NULL,
+    unit_price decimal NOT NULL,
+    subtotal decimal NOT NULL,
+    special_instructions text,
+    PRIMARY KEY (id)
+);
+ALTER TABLE order_details ADD COLUMN IF NOT EXISTS menu_item_name text;
+CREATE INDEX IF NOT EXISTS idx_order_details_deleted_at ON order_details (deleted_at);
+
+CREATE TABLE IF NOT EXISTS selected_add_ons (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    order_detail_id bigint,
+    add_on_id bigint,
+    name text NOT NULL,
+    price decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_selected_add_ons_deleted_at ON selected_add_ons (deleted_at);
+
+CREATE TABLE IF NOT EXISTS order_discounts (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    order_id bigint NOT NULL,
+    description text NOT NULL,
+    amount decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_deleted_at ON order_discounts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_discounts_order_id ON order_discounts (order_id);
+
+CREATE TABLE IF NOT EXISTS order_adjustments (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_id bigint NOT NULL,
+    type text NOT NULL,
+    order_detail_id bigint,
+    quantity bigint NOT NULL DEFAULT 0,
+    amount decimal NOT NULL,
+    reason_code text NOT NULL,
+    note text,
+    method text,
+    requested_by text,
+    approved_by bigint,
+    drawer_session_id bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_deleted_at ON order_adjustments (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_drawer_session_id ON order_adjustments (drawer_session_id);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_location_id ON order_adjustments (location_id);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_order_id ON order_adjustments (order_id);
+CREATE INDEX IF NOT EXISTS idx_order_adjustments_type ON order_adjustments (type);
+
+CREATE TABLE IF NOT EXISTS payments (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_id bigint NOT NULL,
+    method text NOT NULL,
+    amount decimal NOT NULL,
+    tendered decimal NOT NULL DEFAULT 0,
+    reference text,
+    drawer_session_id bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_payments_deleted_at ON payments (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_payments_drawer_session_id ON payments (drawer_session_id);
+CREATE INDEX IF NOT EXISTS idx_payments_location_id ON payments (location_id);
+CREATE INDEX IF NOT EXISTS idx_payments_order_id ON payments (order_id);
+
+CREATE TABLE IF NOT EXISTS stations (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    name text NOT NULL,
+    printer_address text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_stations_deleted_at ON stations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_stations_location_id ON stations (location_id);
+
+CREATE TABLE IF NOT EXISTS tickets (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_id bigint NOT NULL,
+    station_id bigint,
+    status text NOT NULL,
+    ready_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tickets_deleted_at ON tickets (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tickets_location_id ON tickets (location_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_order_id ON tickets (order_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_station_id ON tickets (station_id);
+CREATE INDEX IF NOT EXISTS idx_tickets_status ON tickets (status);
+
+CREATE TABLE IF NOT EXISTS ticket_items (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    ticket_id bigint NOT NULL,
+    order_detail_id bigint NOT NULL,
+    name text NOT NULL,
+    quantity bigint NOT NULL,
+    add_ons text,
+    special_instructions text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_ticket_items_deleted_at ON ticket_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_ticket_items_ticket_id ON ticket_items (ticket_id);
+
+CREATE TABLE IF NOT EXISTS restaurant_infos (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    name text,
+    description text,
+    address text,
+    phone text,
+    email text,
+    logo_url text,
+    banner_url text,
+    opening_hours jsonb,
+    tax_id text,
+    approval_threshold decimal NOT NULL DEFAULT 0,
+    turn_time_minutes bigint NOT NULL DEFAULT 90,
+    no_show_grace_minutes bigint NOT NULL DEFAULT 15,
+    slot_capacity bigint NOT NULL DEFAULT 0,
+    kitchen_lead_minutes bigint NOT NULL DEFAULT 20,
+    tax_rate decimal NOT NULL DEFAULT 0,
+    tax_exclusive boolean NOT NULL DEFAULT false,
+    spend_per_point decimal NOT NULL DEFAULT 100,
+    point_value decimal NOT NULL DEFAULT 1,
+    points_expire_days bigint NOT NULL DEFAULT 365,
+    PRIMARY KEY (id)
+);
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS location_id bigint;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS tax_id text;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS approval_threshold decimal NOT NULL DEFAULT 0;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS turn_time_minutes bigint NOT NULL DEFAULT 90;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS no_show_grace_minutes bigint NOT NULL DEFAULT 15;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS slot_capacity bigint NOT NULL DEFAULT 0;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS kitchen_lead_minutes bigint NOT NULL DEFAULT 20;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS tax_rate decimal NOT NULL DEFAULT 0;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS tax_exclusive boolean NOT NULL DEFAULT false;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS spend_per_point decimal NOT NULL DEFAULT 100;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS point_value decimal NOT NULL DEFAULT 1;
+ALTER TABLE restaurant_infos ADD COLUMN IF NOT EXISTS points_expire_days bigint NOT NULL DEFAULT 365;
+CREATE INDEX IF NOT EXISTS idx_restaurant_infos_deleted_at ON restaurant_infos (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_restaurant_infos_location_name ON restaurant_infos (location_id,name);
+
+CREATE TABLE IF NOT EXISTS tables (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    number text NOT NULL,
+    capacity bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_tables_deleted_at ON tables (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_tables_location_id ON tables (location_id);
+
+CREATE TABLE IF NOT EXISTS reservations (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    table_id bigint,
+    party_size bigint NOT NULL,
+    reserved_at timestamptz NOT NULL,
+    turn_minutes bigint NOT NULL,
+    name text NOT NULL,
+    phone text NOT NULL,
+    notes text,
+    status text NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_reservations_deleted_at ON reservations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_location_id ON reservations (location_id);
+CREATE INDEX IF NOT EXISTS idx_reservations_reserved_at ON reservations (reserved_at);
+CREATE INDEX IF NOT EXISTS idx_reservations_status ON reservations (status);
+CREATE INDEX IF NOT EXISTS idx_reservations_table_id ON reservations (table_id);
+
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    queue_number bigint NOT NULL,
+    name text NOT NULL,
+    phone text,
+    party_size bigint NOT NULL,
+    status text NOT NULL,
+    quoted_minutes bigint NOT NULL,
+    called_at timestamptz,
+    seated_at timestamptz,
+    table_id bigint,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_deleted_at ON waitlist_entries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_location_id ON waitlist_entries (location_id);
+CREATE INDEX IF NOT EXISTS idx_waitlist_entries_status ON waitlist_entries (status);
+
+CREATE TABLE IF NOT EXISTS shifts (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    user_id bigint NOT NULL,
+    clock_in timestamptz NOT NULL,
+    clock_out timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_shifts_clock_in ON shifts (clock_in);
+CREATE INDEX IF NOT EXISTS idx_shifts_clock_out ON shifts (clock_out);
+CREATE INDEX IF NOT EXISTS idx_shifts_deleted_at ON shifts (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_shifts_location_id ON shifts (location_id);
+CREATE INDEX IF NOT EXISTS idx_shifts_user_id ON shifts (user_id);
+
+CREATE TABLE IF NOT EXISTS shift_breaks (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    shift_id bigint NOT NULL,
+    started_at timestamptz NOT NULL,
+    ended_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_shift_breaks_deleted_at ON shift_breaks (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_shift_breaks_shift_id ON shift_breaks (shift_id);
+
+CREATE TABLE IF NOT EXISTS drawer_sessions (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    user_id bigint NOT NULL,
+    opened_at timestamptz NOT NULL,
+    opening_float decimal NOT NULL,
+    closed_at timestamptz,
+    counted_cash decimal,
+    expected_cash decimal NOT NULL DEFAULT 0,
+    over_short decimal NOT NULL DEFAULT 0,
+    note text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_drawer_sessions_deleted_at ON drawer_sessions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_drawer_sessions_location_id ON drawer_sessions (location_id);
+CREATE INDEX IF NOT EXISTS idx_drawer_sessions_user_id ON drawer_sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS drawer_movements (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    drawer_session_id bigint NOT NULL,
+    type text NOT NULL,
+    amount decimal NOT NULL,
+    reason text NOT NULL,
+    recorded_by text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_drawer_movements_deleted_at ON drawer_movements (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_drawer_movements_drawer_session_id ON drawer_movements (drawer_session_id);
+
+CREATE TABLE IF NOT EXISTS invoice_number_ranges (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    period text NOT NULL,
+    track text NOT NULL,
+    start_no bigint NOT NULL,
+    end_no bigint NOT NULL,
+    next_no bigint NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_number_ranges_deleted_at ON invoice_number_ranges (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_invoice_number_ranges_location_id ON invoice_number_ranges (location_id);
+CREATE INDEX IF NOT EXISTS idx_invoice_number_ranges_period ON invoice_number_ranges (period);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    location_id bigint,
+    order_id bigint NOT NULL,
+    number text NOT NULL,
+    period text NOT NULL,
+    issued_at timestamptz NOT NULL,
+    random_number text NOT NULL,
+    seller_tax_id text NOT NULL,
+    seller_name text NOT NULL,
+    buyer_tax_id text NOT NULL,
+    carrier_type text,
+    carrier_id text,
+    donation_code text,
+    sales_amount bigint NOT NULL,
+    tax_amount bigint NOT NULL,
+    total_amount bigint NOT NULL,
+    status text NOT NULL,
+    voided_at timestamptz,
+    void_reason text,
+    uploaded_at timestamptz,
+    void_uploaded_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_invoices_deleted_at ON invoices (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_invoices_location_id ON invoices (location_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_number ON invoices (number);
+CREATE INDEX IF NOT EXISTS idx_invoices_order_id ON invoices (order_id);
+
+CREATE TABLE IF NOT EXISTS invoice_items (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    invoice_id bigint NOT NULL,
+    description text NOT NULL,
+    quantity bigint NOT NULL,
+    unit_price decimal NOT NULL,
+    amount decimal NOT NULL,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_items_deleted_at ON invoice_items (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_invoice_items_invoice_id ON invoice_items (invoice_id);
+
+CREATE TABLE IF NOT EXISTS invoice_allowances (
+    id bigserial,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    invoice_id bigint NOT NULL,
+    number text NOT NULL,
+    issued_at timestamptz NOT NULL,
+    amount bigint NOT NULL,
+    tax_amount bigint NOT NULL,
+    reason text NOT NULL,
+    uploaded_at timestamptz,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_invoice_allowances_deleted_at ON invoice_allowances (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_invoice_allowances_invoice_id ON invoice_allowances (invoice_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoice_allowances_number ON invoice_allowances (number);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id bigserial,
+    created_at timestamptz,
+    location_id bigint,
+    actor text NOT NULL,
+    action text NOT NULL,
+    entity text NOT NULL,
+    entity_id text,
+    before jsonb,
+    after jsonb,
+    changes jsonb,
+    ip text,
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs (actor);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity,entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_location_id ON audit_logs (location_id);
+
+-- Foreign keys are dropped and re-added so their ON DELETE actions are current
+ALTER TABLE menu_items DROP CONSTRAINT IF EXISTS fk_categories_menu_items;
+ALTER TABLE menu_items ADD CONSTRAINT fk_categories_menu_items FOREIGN KEY (category_id) REFERENCES categories(id) ON DELETE RESTRICT ON UPDATE CASCADE;
+ALTER TABLE add_ons DROP CONSTRAINT IF EXISTS fk_menu_items_add_ons;
+ALTER TABLE add_ons ADD CONSTRAINT fk_menu_items_add_ons FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE customer_favorites DROP CONSTRAINT IF EXISTS fk_customer_favorites_menu_item;
+ALTER TABLE customer_favorites ADD CONSTRAINT fk_customer_favorites_menu_item FOREIGN KEY (menu_item_id) REFERENCES menu_items(id);
+ALTER TABLE customer_favorites DROP CONSTRAINT IF EXISTS fk_customers_favorites;
+ALTER TABLE customer_favorites ADD CONSTRAINT fk_customers_favorites FOREIGN KEY (customer_id) REFERENCES customers(id);
+ALTER TABLE item_ratings DROP CONSTRAINT IF EXISTS fk_feedbacks_item_ratings;
+ALTER TABLE item_ratings ADD CONSTRAINT fk_feedbacks_item_ratings FOREIGN KEY (feedback_id) REFERENCES feedbacks(id);
+ALTER TABLE order_details DROP CONSTRAINT IF EXISTS fk_order_details_menu_item;
+ALTER TABLE order_details ADD CONSTRAINT fk_order_details_menu_item FOREIGN KEY (menu_item_id) REFERENCES menu_items(id) ON DELETE RESTRICT;
+ALTER TABLE order_details DROP CONSTRAINT IF EXISTS fk_orders_order_details;
+ALTER TABLE order_details ADD CONSTRAINT fk_orders_order_details FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
+ALTER TABLE selected_add_ons DROP CONSTRAINT IF EXISTS fk_selected_add_ons_add_on;
+ALTER TABLE selected_add_ons ADD CONSTRAINT fk_selected_add_ons_add_on FOREIGN KEY (add_on_id) REFERENCES add_ons(id) ON DELETE RESTRICT;
+ALTER TABLE selected_add_ons DROP CONSTRAINT IF EXISTS fk_order_details_selected_add_ons;
+ALTER TABLE selected_add_ons ADD CONSTRAINT fk_order_details_selected_add_ons FOREIGN KEY (order_detail_id) REFERENCES order_details(id) ON DELETE CASCADE;
+ALTER TABLE order_discounts DROP CONSTRAINT IF EXISTS fk_orders_discounts;
+ALTER TABLE order_discounts ADD CONSTRAINT fk_orders_discounts FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE CASCADE;
+ALTER TABLE order_adjustments DROP CONSTRAINT IF EXISTS fk_drawer_sessions_refunds;
+ALTER TABLE order_adjustments ADD CONSTRAINT fk_drawer_sessions_refunds FOREIGN KEY (drawer_session_id) REFERENCES drawer_sessions(id);
+ALTER TABLE order_adjustments DROP CONSTRAINT IF EXISTS fk_orders_adjustments;
+ALTER TABLE order_adjustments ADD CONSTRAINT fk_orders_adjustments FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS fk_orders_payments;
+ALTER TABLE payments ADD CONSTRAINT fk_orders_payments FOREIGN KEY (order_id) REFERENCES orders(id) ON DELETE RESTRICT;
+ALTER TABLE payments DROP CONSTRAINT IF EXISTS fk_drawer_sessions_payments;
+ALTER TABLE payments ADD CONSTRAINT fk_drawer_sessions_payments FOREIGN KEY (drawer_session_id) REFERENCES drawer_sessions(id);
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS fk_tickets_station;
+ALTER TABLE tickets ADD CONSTRAINT fk_tickets_station FOREIGN KEY (station_id) REFERENCES stations(id);
+ALTER TABLE tickets DROP CONSTRAINT IF EXISTS fk_tickets_order;
+ALTER TABLE tickets ADD CONSTRAINT fk_tickets_order FOREIGN KEY (order_id) REFERENCES orders(id);
+ALTER TABLE ticket_items DROP CONSTRAINT IF EXISTS fk_tickets_items;
+ALTER TABLE ticket_items ADD CONSTRAINT fk_tickets_items FOREIGN KEY (ticket_id) REFERENCES tickets(id);
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS fk_reservations_table;
+ALTER TABLE reservations ADD CONSTRAINT fk_reservations_table FOREIGN KEY (table_id) REFERENCES tables(id);
+ALTER TABLE shift_breaks DROP CONSTRAINT IF EXISTS fk_shifts_breaks;
+ALTER TABLE shift_breaks ADD CONSTRAINT fk_shifts_breaks FOREIGN KEY (shift_id) REFERENCES shifts(id);
+ALTER TABLE drawer_movements DROP CONSTRAINT IF EXISTS fk_drawer_sessions_movements;
+ALTER TABLE drawer_movements ADD CONSTRAINT fk_drawer_sessions_movements FOREIGN KEY (drawer_session_id) REFERENCES drawer_sessions(id);
+ALTER TABLE invoice_items DROP CONSTRAINT IF EXISTS fk_invoices_items;
+ALTER TABLE invoice_items ADD CONSTRAINT fk_invoices_items FOREIGN KEY (invoice_id) REFERENCES invoices(id);
+ALTER TABLE invoice_allowances DROP CONSTRAINT IF EXISTS fk_invoices_allowances;
+ALTER TABLE invoice_allowances ADD CONSTRAINT fk_invoices_allowances FOREIGN KEY (invoice_id) REFERENCES invoices(id);
+
+-- The audit log only takes inserts
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs;
+CREATE TRIGGER audit_logs_append_only BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
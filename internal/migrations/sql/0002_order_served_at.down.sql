@@ -0,0 +1,2 @@
+-- 0002 order_served_at (down)
+ALTER TABLE orders DROP COLUMN IF EXISTS served_at;
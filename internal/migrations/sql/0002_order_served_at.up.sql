@@ -0,0 +1,4 @@
+-- 0002 order_served_at (up)
+-- When an order reached the table, for the average ticket time metric.
+-- Orders served before this migration have no time and are left out.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS served_at timestamptz;
@@ -0,0 +1,2 @@
+-- 0003 order_completed_at (down)
+ALTER TABLE orders DROP COLUMN IF EXISTS completed_at;
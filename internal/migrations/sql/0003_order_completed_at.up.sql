@@ -0,0 +1,4 @@
+-- 0003 order_completed_at (up)
+-- When an order was completed, ending its party's stay at the table, for
+-- waitlist estimates. Orders completed before this migration are left out.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS completed_at timestamptz;
@@ -0,0 +1,3 @@
+-- 0004 waitlist_queue_date (down)
+DROP INDEX IF EXISTS idx_waitlist_entries_queue_number;
+ALTER TABLE waitlist_entries DROP COLUMN IF EXISTS queue_date;
@@ -0,0 +1,9 @@
+-- 0004 waitlist_queue_date (up)
+-- The day a queue number was handed out. Numbers restart every day, so they
+-- are unique per location and day among the live entries.
+ALTER TABLE waitlist_entries ADD COLUMN IF NOT EXISTS queue_date date;
+UPDATE waitlist_entries SET queue_date = created_at::date WHERE queue_date IS NULL;
+ALTER TABLE waitlist_entries ALTER COLUMN queue_date SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_waitlist_entries_queue_number
+    ON waitlist_entries (COALESCE(location_id, 0), queue_date, queue_number)
+    WHERE deleted_at IS NULL;
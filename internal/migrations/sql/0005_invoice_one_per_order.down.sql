@@ -0,0 +1,2 @@
+-- 0005 invoice_one_per_order (down)
+DROP INDEX IF EXISTS idx_invoices_issued_order;
@@ -0,0 +1,5 @@
+-- 0005 invoice_one_per_order (up)
+-- An order has at most one issued invoice; a voided invoice can be reissued.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_issued_order
+    ON invoices (order_id)
+    WHERE status = 'issued' AND deleted_at IS NULL;
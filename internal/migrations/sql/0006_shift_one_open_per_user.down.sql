@@ -0,0 +1,2 @@
+-- 0006 shift_one_open_per_user (down)
+DROP INDEX IF EXISTS idx_shifts_open_user;
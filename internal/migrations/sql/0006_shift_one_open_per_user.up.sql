@@ -0,0 +1,5 @@
+-- 0006 shift_one_open_per_user (up)
+-- A staff member has at most one open shift, so two clock-ins cannot both succeed.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shifts_open_user
+    ON shifts (user_id)
+    WHERE clock_out IS NULL AND deleted_at IS NULL;
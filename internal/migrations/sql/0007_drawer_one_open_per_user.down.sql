@@ -0,0 +1,2 @@
+-- 0007 drawer_one_open_per_user (down)
+DROP INDEX IF EXISTS idx_drawer_sessions_open_user;
@@ -0,0 +1,5 @@
+-- 0007 drawer_one_open_per_user (up)
+-- A cashier has at most one open drawer, so two opens cannot both succeed.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_drawer_sessions_open_user
+    ON drawer_sessions (user_id)
+    WHERE closed_at IS NULL AND deleted_at IS NULL;
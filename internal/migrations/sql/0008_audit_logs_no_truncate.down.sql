@@ -0,0 +1,2 @@
+-- 0008 audit_logs_no_truncate (down)
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
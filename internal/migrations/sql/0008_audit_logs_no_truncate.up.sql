@@ -0,0 +1,6 @@
+-- 0008 audit_logs_no_truncate (up)
+-- The row trigger from 0001 refuses updates and deletes; TRUNCATE skips row
+-- triggers, so it is refused by a statement trigger as well.
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
@@ -0,0 +1,5 @@
+-- 0009 category_name_per_location (down)
+-- Fails if two locations now share a category name; rename one first.
+DROP INDEX IF EXISTS idx_categories_location_name_active;
+DROP INDEX IF EXISTS idx_categories_master_name_active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name_active ON categories (name) WHERE deleted_at IS NULL;
@@ -0,0 +1,10 @@
+-- 0009 category_name_per_location (up)
+-- Category names are unique within a location, and among the shared master
+-- categories, so locations may reuse each other's names and shadow a master one.
+DROP INDEX IF EXISTS idx_categories_name_active;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_location_name_active
+    ON categories (location_id, name)
+    WHERE deleted_at IS NULL AND location_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_master_name_active
+    ON categories (name)
+    WHERE deleted_at IS NULL AND location_id IS NULL;
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrawerSessionClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		session   DrawerSession
+		counted   float64
+		expected  float64
+		overShort float64
+	}{
+		{
+			name:      "float only",
+			session:   DrawerSession{OpeningFloat: 2000},
+			counted:   2000,
+			expected:  2000,
+			overShort: 0,
+		},
+		{
+			name: "sales, refunds and movements",
+			session: DrawerSession{
+				OpeningFloat: 2000,
+				Payments:     []Payment{{Amount: 350}, {Amount: 1280.5}},
+				Refunds:      []OrderAdjustment{{Amount: 120}},
+				Movements: []DrawerMovement{
+					{Type: DrawerPaidIn, Amount: 500},
+					{Type: DrawerPaidOut, Amount: 300},
+					{Type: DrawerPaidOut, Amount: 45.25},
+				},
+			},
+			counted:   3665.25,
+			expected:  3665.25,
+			overShort: 0,
+		},
+		{
+			name:      "over",
+			session:   DrawerSession{OpeningFloat: 1000, Payments: []Payment{{Amount: 100.1}}},
+			counted:   1100.2,
+			expected:  1100.1,
+			overShort: 0.1,
+		},
+		{
+			name:      "short",
+			session:   DrawerSession{OpeningFloat: 1000, Payments: []Payment{{Amount: 0.1}, {Amount: 0.2}}},
+			counted:   990,
+			expected:  1000.3,
+			overShort: -10.3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			session := tt.session
+			session.Close(tt.counted, now)
+
+			if session.ExpectedCash != tt.expected {
+				t.Errorf("ExpectedCash = %v, want %v", session.ExpectedCash, tt.expected)
+			}
+			if session.OverShort != tt.overShort {
+				t.Errorf("OverShort = %v, want %v", session.OverShort, tt.overShort)
+			}
+			if !session.IsClosed() || !session.ClosedAt.Equal(now) || *session.CountedCash != tt.counted {
+				t.Errorf("closed at %v with %v counted, want %v with %v", session.ClosedAt, session.CountedCash, now, tt.counted)
+			}
+		})
+	}
+}
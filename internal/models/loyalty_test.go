@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoyaltySettings(t *testing.T) {
+	configured := LoyaltySettings{SpendPerPoint: 50, PointValue: 0.5, PointsExpireDays: 30}
+	unset := LoyaltySettings{}
+	earnedAt := time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+
+	earnTests := []struct {
+		name     string
+		settings LoyaltySettings
+		amount   float64
+		want     int
+	}{
+		{"whole points", configured, 250, 5},
+		{"rounded down", configured, 99.99, 1},
+		{"below one point", configured, 49, 0},
+		{"nothing spent", configured, 0, 0},
+		{"refund", configured, -100, 0},
+		{"default spend", unset, 250, 2},
+		{"negative spend uses default", LoyaltySettings{SpendPerPoint: -1}, 250, 2},
+	}
+	for _, tt := range earnTests {
+		t.Run("PointsEarned/"+tt.name, func(t *testing.T) {
+			if got := tt.settings.PointsEarned(tt.amount); got != tt.want {
+				t.Errorf("PointsEarned(%v) = %d, want %d", tt.amount, got, tt.want)
+			}
+		})
+	}
+
+	valueTests := []struct {
+		name     string
+		settings LoyaltySettings
+		points   int
+		want     float64
+	}{
+		{"configured value", configured, 7, 3.5},
+		{"no points", configured, 0, 0},
+		{"default value", unset, 7, 7},
+		{"rounded to cents", LoyaltySettings{PointValue: 0.333}, 10, 3.33},
+	}
+	for _, tt := range valueTests {
+		t.Run("RedemptionValue/"+tt.name, func(t *testing.T) {
+			if got := tt.settings.RedemptionValue(tt.points); got != tt.want {
+				t.Errorf("RedemptionValue(%d) = %v, want %v", tt.points, got, tt.want)
+			}
+		})
+	}
+
+	expiryTests := []struct {
+		name     string
+		settings LoyaltySettings
+		want     time.Time
+	}{
+		{"configured days", configured, time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)},
+		{"default days", unset, time.Date(2025, time.January, 30, 12, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range expiryTests {
+		t.Run("PointsExpiry/"+tt.name, func(t *testing.T) {
+			if got := tt.settings.PointsExpiry(earnedAt); !got.Equal(tt.want) {
+				t.Errorf("PointsExpiry(%v) = %v, want %v", earnedAt, got, tt.want)
+			}
+		})
+	}
+}
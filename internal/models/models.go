@@ -4,35 +4,104 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// RestaurantZone is the time zone opening hours, business days and invoice
+// periods are kept in. Taiwan has no daylight saving, so a fixed offset serves.
+var RestaurantZone = time.FixedZone("Asia/Taipei", 8*60*60)
+
+// MasterRecord is implemented by models whose rows without a LocationID make up
+// the master data shared by every location
+type MasterRecord interface {
+	IsMasterRecord() bool
+}
+
+type Location struct {
+	gorm.Model
+	Name    string `gorm:"uniqueIndex:idx_locations_name_active,where:deleted_at IS NULL;not null"`
+	Code    string `gorm:"uniqueIndex:idx_locations_code_active,where:deleted_at IS NULL;not null"`
+	Address string
+	Phone   string
+}
+
+// User roles allowed to approve voids and refunds
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+)
+
 type User struct {
 	gorm.Model
-	Username string `gorm:"uniqueIndex;not null"`
-	Password string `gorm:"not null"`
-	Name     string `gorm:"not null"`
-	Role     string `gorm:"not null"`
+	LocationID *uint  `gorm:"index"`
+	Username   string `gorm:"uniqueIndex:idx_users_username_active,where:deleted_at IS NULL;not null"`
+	Password   string `gorm:"not null"`
+	// PIN is a short bcrypt-hashed code managers use to approve voids and refunds at the till
+	PIN  string
+	Name string `gorm:"not null"`
+	Role string `gorm:"not null"`
+}
+
+// CanApprove reports whether the user may approve voids and refunds
+func (u User) CanApprove() bool {
+	return u.Role == RoleManager || u.Role == RoleAdmin
+}
+
+// Station is a kitchen section, such as the bar or the grill, that prepares part of an order
+type Station struct {
+	gorm.Model
+	LocationID *uint  `gorm:"index"`
+	Name       string `gorm:"not null"`
+	// PrinterAddress is the host:port of the station's network ticket printer, on a private network and port 9100-9109
+	PrinterAddress string
 }
 
 type Category struct {
 	gorm.Model
-	Name         string `gorm:"uniqueIndex;not null"`
-	DisplayOrder int    `gorm:"not null"`
-	MenuItems    []MenuItem
+	LocationID *uint `gorm:"index;uniqueIndex:idx_categories_location_name_active,where:deleted_at IS NULL AND location_id IS NOT NULL"`
+	StationID  *uint
+	// Name is unique within a location and among the master categories, which
+	// idx_categories_master_name_active covers in migration 0009
+	Name         string     `gorm:"uniqueIndex:idx_categories_location_name_active,where:deleted_at IS NULL AND location_id IS NOT NULL;not null"`
+	DisplayOrder int        `gorm:"not null"`
+	MenuItems    []MenuItem `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (c Category) IsMasterRecord() bool {
+	return c.LocationID == nil
 }
 
 type MenuItem struct {
 	gorm.Model
-	CategoryID  uint
+	LocationID *uint `gorm:"index"`
+	CategoryID uint
+	// StationID overrides the station of the item's category
+	StationID   *uint
 	Name        string `gorm:"not null"`
 	Description string
 	Price       float64 `gorm:"not null"`
 	ImageURL    string
-	IsAvailable bool `gorm:"not null;default:true"`
-	AddOns      []AddOn
+	IsAvailable bool    `gorm:"not null;default:true"`
+	AddOns      []AddOn `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	// AverageRating and RatingCount summarise guest ratings and are not stored
+	AverageRating float64 `gorm:"-"`
+	RatingCount   int     `gorm:"-"`
+}
+
+func (m MenuItem) IsMasterRecord() bool {
+	return m.LocationID == nil
+}
+
+// MenuItemPrice overrides the master menu price of an item at a single location
+type MenuItemPrice struct {
+	gorm.Model
+	LocationID uint    `gorm:"uniqueIndex:idx_menu_item_prices_location_item;not null"`
+	MenuItemID uint    `gorm:"uniqueIndex:idx_menu_item_prices_location_item;not null"`
+	Price      float64 `gorm:"not null"`
 }
 
 type AddOn struct {
@@ -42,33 +111,629 @@ type AddOn struct {
 	Price      float64 `gorm:"not null"`
 }
 
+// Order types
+const (
+	OrderTypeDineIn   = "dine_in"
+	OrderTypeTakeout  = "takeout"
+	OrderTypeDelivery = "delivery"
+)
+
+// Order statuses
+const (
+	OrderScheduled = "scheduled"
+	OrderPending   = "pending"
+	OrderPreparing = "preparing"
+	OrderServed    = "served"
+	OrderCompleted = "completed"
+	OrderCancelled = "cancelled"
+)
+
+// PickupSlotLength is the window used to cap how many takeout and delivery orders the kitchen takes
+const PickupSlotLength = 15 * time.Minute
+
 type Order struct {
 	gorm.Model
-	TableNumber  string  `gorm:"not null"`
-	Status       string  `gorm:"not null"`
-	TotalAmount  float64 `gorm:"not null"`
-	OrderDetails []OrderDetail
+	LocationID      *uint  `gorm:"index"`
+	OrderType       string `gorm:"not null;default:dine_in"`
+	TableNumber     string `gorm:"not null"`
+	CustomerName    string
+	CustomerPhone   string
+	DeliveryAddress string
+	// ScheduledFor is the requested pickup or delivery time of takeout and delivery orders
+	ScheduledFor *time.Time `gorm:"index"`
+	// ReleasedAt is when the order was sent to the kitchen
+	ReleasedAt *time.Time
+	// ServedAt is when the order reached the table
+	ServedAt *time.Time
+	// CompletedAt is when the order was closed, ending the party's stay at the table
+	CompletedAt *time.Time
+	// ServerID is the staff member who took the order
+	ServerID *uint `gorm:"index"`
+	// CustomerID links the order to a customer account for history and loyalty points
+	CustomerID *uint `gorm:"index"`
+	// FeedbackToken is the secret in the link guests use to rate the order
+	FeedbackToken *string           `gorm:"uniqueIndex"`
+	Status        string            `gorm:"not null"`
+	TaxAmount     float64           `gorm:"not null;default:0"`
+	TotalAmount   float64           `gorm:"not null"`
+	OrderDetails  []OrderDetail     `gorm:"constraint:OnDelete:CASCADE"`
+	Discounts     []OrderDiscount   `gorm:"constraint:OnDelete:CASCADE"`
+	Payments      []Payment         `gorm:"constraint:OnDelete:RESTRICT"`
+	Adjustments   []OrderAdjustment `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+// ItemsTotal returns the sum of the order's line subtotals less voided items
+func (o Order) ItemsTotal() float64 {
+	total := 0.0
+	for _, detail := range o.OrderDetails {
+		total += detail.Subtotal
+	}
+	for _, adjustment := range o.Adjustments {
+		if adjustment.Type == AdjustmentVoid {
+			total -= adjustment.Amount
+		}
+	}
+	return total
+}
+
+// RefundedAmount returns the sum of the money refunded for the order
+func (o Order) RefundedAmount() float64 {
+	total := 0.0
+	for _, adjustment := range o.Adjustments {
+		if adjustment.Type == AdjustmentRefund {
+			total += adjustment.Amount
+		}
+	}
+	return total
+}
+
+// AdjustedQuantity returns how many units of a line have been voided or refunded
+func (o Order) AdjustedQuantity(orderDetailID uint) int {
+	quantity := 0
+	for _, adjustment := range o.Adjustments {
+		if adjustment.OrderDetailID != nil && *adjustment.OrderDetailID == orderDetailID {
+			quantity += adjustment.Quantity
+		}
+	}
+	return quantity
+}
+
+// DiscountTotal returns the sum of the order's discounts
+func (o Order) DiscountTotal() float64 {
+	total := 0.0
+	for _, discount := range o.Discounts {
+		total += discount.Amount
+	}
+	return total
+}
+
+// PaidAmount returns the sum of the payments taken for the order
+func (o Order) PaidAmount() float64 {
+	total := 0.0
+	for _, payment := range o.Payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+// Balance returns what is left to pay
+func (o Order) Balance() float64 {
+	return o.TotalAmount - o.PaidAmount()
+}
+
+// ApplyTotals recalculates the tax and total from the loaded details and discounts.
+// Tax is worked out of the discounted amount unless it is charged on top of prices.
+func (o *Order) ApplyTotals(tax TaxSettings) {
+	net := o.ItemsTotal() - o.DiscountTotal()
+	if net < 0 {
+		net = 0
+	}
+
+	if tax.TaxExclusive {
+		o.TaxAmount = roundCents(net * tax.TaxRate)
+		o.TotalAmount = roundCents(net + o.TaxAmount)
+		return
+	}
+	o.TaxAmount = roundCents(net - net/(1+tax.TaxRate))
+	o.TotalAmount = roundCents(net)
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// Adjustment types
+const (
+	AdjustmentVoid   = "void"
+	AdjustmentRefund = "refund"
+)
+
+// Adjustment reason codes
+const (
+	ReasonWrongItem         = "wrong_item"
+	ReasonQualityIssue      = "quality_issue"
+	ReasonCustomerComplaint = "customer_complaint"
+	ReasonKitchenError      = "kitchen_error"
+	ReasonDuplicate         = "duplicate"
+	ReasonOther             = "other"
+)
+
+// OrderAdjustment records a void of items before payment or a refund after it.
+// The order details it refers to are never changed, so reports can show both.
+type OrderAdjustment struct {
+	gorm.Model
+	LocationID *uint  `gorm:"index"`
+	OrderID    uint   `gorm:"index;not null"`
+	Type       string `gorm:"not null;index"`
+	// OrderDetailID is empty for refunds of an amount rather than of items
+	OrderDetailID *uint
+	Quantity      int     `gorm:"not null;default:0"`
+	Amount        float64 `gorm:"not null"`
+	ReasonCode    string  `gorm:"not null"`
+	Note          string
+	// Method is how a refund was paid out
+	Method      string
+	RequestedBy string
+	ApprovedBy  *uint
+	// DrawerSessionID is the cash drawer a cash refund was paid from
+	DrawerSessionID *uint `gorm:"index"`
+}
+
+type OrderDiscount struct {
+	gorm.Model
+	OrderID     uint    `gorm:"index;not null"`
+	Description string  `gorm:"not null"`
+	Amount      float64 `gorm:"not null"`
+	// RedeemedPoints are the loyalty points a new discount takes off the order's
+	// customer when the order is updated; they are not stored with the discount
+	RedeemedPoints int `gorm:"-" json:"-"`
+}
+
+// Payment methods
+const (
+	PaymentCash   = "cash"
+	PaymentCard   = "card"
+	PaymentMobile = "mobile"
+)
+
+type Payment struct {
+	gorm.Model
+	LocationID *uint   `gorm:"index"`
+	OrderID    uint    `gorm:"index;not null"`
+	Method     string  `gorm:"not null"`
+	Amount     float64 `gorm:"not null"`
+	// Tendered is the cash handed over when it exceeds Amount
+	Tendered  float64 `gorm:"not null;default:0"`
+	Reference string
+	// DrawerSessionID is the cash drawer a cash payment went into
+	DrawerSessionID *uint `gorm:"index"`
+}
+
+// Change returns the cash handed back for the payment
+func (p Payment) Change() float64 {
+	if p.Tendered > p.Amount {
+		return p.Tendered - p.Amount
+	}
+	return 0
+}
+
+// IsOffPremises reports whether the order is picked up or delivered
+func (o Order) IsOffPremises() bool {
+	return o.OrderType == OrderTypeTakeout || o.OrderType == OrderTypeDelivery
+}
+
+// PickupSlot returns the start of the window the order's pickup or delivery time falls in
+func PickupSlot(t time.Time) time.Time {
+	return t.Truncate(PickupSlotLength)
 }
 
 type OrderDetail struct {
 	gorm.Model
-	OrderID             uint
-	MenuItemID          uint
+	OrderID    uint
+	MenuItemID uint
+	MenuItem   *MenuItem `json:",omitempty" gorm:"constraint:OnDelete:RESTRICT"`
+	// MenuItemName is the item's name when it was ordered
+	MenuItemName        string
 	Quantity            int     `gorm:"not null"`
 	UnitPrice           float64 `gorm:"not null"`
 	Subtotal            float64 `gorm:"not null"`
 	SpecialInstructions string
-	SelectedAddOns      []SelectedAddOn
+	SelectedAddOns      []SelectedAddOn `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// Ticket statuses
+const (
+	TicketPending = "pending"
+	TicketReady   = "ready"
+)
+
+// Ticket is the part of an order prepared at one station
+type Ticket struct {
+	gorm.Model
+	LocationID *uint  `gorm:"index"`
+	OrderID    uint   `gorm:"index;not null"`
+	Order      *Order `json:",omitempty"`
+	// StationID is empty for items no station has been configured for
+	StationID *uint    `gorm:"index"`
+	Station   *Station `json:",omitempty"`
+	Status    string   `gorm:"not null;index"`
+	ReadyAt   *time.Time
+	Items     []TicketItem
+}
+
+type TicketItem struct {
+	gorm.Model
+	TicketID            uint   `gorm:"index;not null"`
+	OrderDetailID       uint   `gorm:"not null"`
+	Name                string `gorm:"not null"`
+	Quantity            int    `gorm:"not null"`
+	AddOns              string
+	SpecialInstructions string
 }
 
 type SelectedAddOn struct {
 	gorm.Model
 	OrderDetailID uint
 	AddOnID       uint
+	AddOn         *AddOn  `json:",omitempty" gorm:"constraint:OnDelete:RESTRICT"`
 	Name          string  `gorm:"not null"`
 	Price         float64 `gorm:"not null"`
 }
 
+type Table struct {
+	gorm.Model
+	LocationID *uint  `gorm:"index"`
+	Number     string `gorm:"not null"`
+	Capacity   int    `gorm:"not null"`
+}
+
+// Reservation statuses
+const (
+	ReservationBooked    = "booked"
+	ReservationSeated    = "seated"
+	ReservationCompleted = "completed"
+	ReservationCancelled = "cancelled"
+	ReservationNoShow    = "no_show"
+)
+
+type Reservation struct {
+	gorm.Model
+	LocationID  *uint `gorm:"index"`
+	TableID     *uint `gorm:"index"`
+	Table       *Table
+	PartySize   int       `gorm:"not null"`
+	ReservedAt  time.Time `gorm:"not null;index"`
+	TurnMinutes int       `gorm:"not null"`
+	Name        string    `gorm:"not null"`
+	Phone       string    `gorm:"not null"`
+	Notes       string
+	Status      string `gorm:"not null;index"`
+}
+
+// EndsAt returns when the table is expected to be free again
+func (r Reservation) EndsAt() time.Time {
+	return r.ReservedAt.Add(time.Duration(r.TurnMinutes) * time.Minute)
+}
+
+// Overlaps reports whether the reservation holds its table at any point in [start, end)
+func (r Reservation) Overlaps(start, end time.Time) bool {
+	return r.ReservedAt.Before(end) && r.EndsAt().After(start)
+}
+
+// IsActive reports whether the reservation still holds a table
+func (r Reservation) IsActive() bool {
+	return r.Status == ReservationBooked || r.Status == ReservationSeated
+}
+
+// Waitlist statuses
+const (
+	WaitlistWaiting = "waiting"
+	WaitlistCalled  = "called"
+	WaitlistSeated  = "seated"
+	WaitlistRemoved = "removed"
+)
+
+type WaitlistEntry struct {
+	gorm.Model
+	LocationID  *uint `gorm:"index"`
+	QueueNumber int   `gorm:"not null"`
+	// QueueDate is the day QueueNumber was handed out; numbers restart every day
+	QueueDate     time.Time `gorm:"type:date;not null"`
+	Name          string    `gorm:"not null"`
+	Phone         string
+	PartySize     int    `gorm:"not null"`
+	Status        string `gorm:"not null;index"`
+	QuotedMinutes int    `gorm:"not null"`
+	CalledAt      *time.Time
+	SeatedAt      *time.Time
+	TableID       *uint
+}
+
+// Customer is a guest account, shared by every location. Staff are Users.
+type Customer struct {
+	gorm.Model
+	Name string `gorm:"not null"`
+	// Phone and Email are optional but unique; at least one is needed to sign up
+	Phone     *string `gorm:"uniqueIndex:idx_customers_phone_active,where:deleted_at IS NULL"`
+	Email     *string `gorm:"uniqueIndex:idx_customers_email_active,where:deleted_at IS NULL"`
+	Favorites []CustomerFavorite
+}
+
+type CustomerFavorite struct {
+	gorm.Model
+	CustomerID uint `gorm:"uniqueIndex:idx_customer_favorites_customer_item;not null"`
+	MenuItemID uint `gorm:"uniqueIndex:idx_customer_favorites_customer_item;not null"`
+	MenuItem   *MenuItem
+}
+
+// Feedback is a guest's rating of an order, left through the order's feedback link
+type Feedback struct {
+	gorm.Model
+	LocationID  *uint `gorm:"index"`
+	OrderID     uint  `gorm:"uniqueIndex;not null"`
+	Rating      int   `gorm:"not null"`
+	Comment     string
+	ItemRatings []ItemRating
+}
+
+// ItemRating is a guest's rating of one menu item on an order
+type ItemRating struct {
+	gorm.Model
+	LocationID *uint `gorm:"index"`
+	FeedbackID uint  `gorm:"index;not null"`
+	MenuItemID uint  `gorm:"index;not null"`
+	Rating     int   `gorm:"not null"`
+	Comment    string
+}
+
+// Loyalty ledger entry types
+const (
+	PointsEarned   = "earn"
+	PointsRedeemed = "redeem"
+	PointsExpired  = "expire"
+	// PointsRestored gives back the points redeemed on an order that is cancelled
+	PointsRestored = "restore"
+	// PointsReversed takes back points earned on an order that is refunded
+	PointsReversed = "reverse"
+)
+
+// LoyaltyEntry is one line of a customer's points ledger. Earned and restored
+// entries keep the points not yet used or expired in Remaining, oldest used first.
+type LoyaltyEntry struct {
+	gorm.Model
+	CustomerID uint   `gorm:"index;not null"`
+	OrderID    *uint  `gorm:"index"`
+	Type       string `gorm:"not null"`
+	Points     int    `gorm:"not null"`
+	Remaining  int    `gorm:"not null;default:0"`
+	ExpiresAt  *time.Time
+}
+
+// Drawer movement types
+const (
+	DrawerPaidIn  = "paid_in"
+	DrawerPaidOut = "paid_out"
+)
+
+// DrawerSession is a cashier's use of a cash drawer from opening float to the
+// closing count. Closed sessions are kept as they were counted.
+type DrawerSession struct {
+	gorm.Model
+	LocationID   *uint     `gorm:"index"`
+	UserID       uint      `gorm:"index;not null"`
+	OpenedAt     time.Time `gorm:"not null"`
+	OpeningFloat float64   `gorm:"not null"`
+	ClosedAt     *time.Time
+	CountedCash  *float64
+	// ExpectedCash and OverShort are fixed when the session is closed
+	ExpectedCash float64 `gorm:"not null;default:0"`
+	OverShort    float64 `gorm:"not null;default:0"`
+	Note         string
+	Movements    []DrawerMovement
+	Payments     []Payment
+	Refunds      []OrderAdjustment
+}
+
+// DrawerMovement is cash put into or taken out of a drawer outside of a sale
+type DrawerMovement struct {
+	gorm.Model
+	DrawerSessionID uint    `gorm:"index;not null"`
+	Type            string  `gorm:"not null"`
+	Amount          float64 `gorm:"not null"`
+	Reason          string  `gorm:"not null"`
+	RecordedBy      string
+}
+
+// IsClosed reports whether the session has been counted and closed
+func (d DrawerSession) IsClosed() bool {
+	return d.ClosedAt != nil
+}
+
+// CashSales returns the cash kept from payments, net of change given
+func (d DrawerSession) CashSales() float64 {
+	total := 0.0
+	for _, payment := range d.Payments {
+		total += payment.Amount
+	}
+	return total
+}
+
+// CashRefunds returns the cash paid back to customers from the drawer
+func (d DrawerSession) CashRefunds() float64 {
+	total := 0.0
+	for _, refund := range d.Refunds {
+		total += refund.Amount
+	}
+	return total
+}
+
+// PaidIn returns the sum of the cash put into the drawer
+func (d DrawerSession) PaidIn() float64 {
+	return d.movementTotal(DrawerPaidIn)
+}
+
+// PaidOut returns the sum of the cash taken out of the drawer
+func (d DrawerSession) PaidOut() float64 {
+	return d.movementTotal(DrawerPaidOut)
+}
+
+func (d DrawerSession) movementTotal(movementType string) float64 {
+	total := 0.0
+	for _, movement := range d.Movements {
+		if movement.Type == movementType {
+			total += movement.Amount
+		}
+	}
+	return total
+}
+
+// Expected returns the cash that should be in the drawer
+func (d DrawerSession) Expected() float64 {
+	return roundCents(d.OpeningFloat + d.CashSales() + d.PaidIn() - d.PaidOut() - d.CashRefunds())
+}
+
+// Close records the counted cash and fixes the expected cash and the amount
+// the count is over, or under when negative
+func (d *DrawerSession) Close(counted float64, now time.Time) {
+	d.ClosedAt = &now
+	d.CountedCash = &counted
+	d.ExpectedCash = d.Expected()
+	d.OverShort = roundCents(counted - d.ExpectedCash)
+}
+
+// Overtime thresholds under the Labor Standards Act
+const (
+	RegularHoursPerDay  = 8 * time.Hour
+	RegularHoursPerWeek = 40 * time.Hour
+)
+
+// Shift is one clock-in to clock-out period worked by a staff member
+type Shift struct {
+	gorm.Model
+	LocationID *uint      `gorm:"index"`
+	UserID     uint       `gorm:"index;not null"`
+	ClockIn    time.Time  `gorm:"not null;index"`
+	ClockOut   *time.Time `gorm:"index"`
+	Breaks     []ShiftBreak
+}
+
+type ShiftBreak struct {
+	gorm.Model
+	ShiftID   uint      `gorm:"index;not null"`
+	StartedAt time.Time `gorm:"not null"`
+	EndedAt   *time.Time
+}
+
+// OpenBreak returns the break the staff member is currently on, if any
+func (s Shift) OpenBreak() *ShiftBreak {
+	for i := range s.Breaks {
+		if s.Breaks[i].EndedAt == nil {
+			return &s.Breaks[i]
+		}
+	}
+	return nil
+}
+
+// BreakDuration returns the time spent on breaks, counting an open break up to now
+func (s Shift) BreakDuration(now time.Time) time.Duration {
+	var total time.Duration
+	for _, b := range s.Breaks {
+		end := now
+		if b.EndedAt != nil {
+			end = *b.EndedAt
+		}
+		total += end.Sub(b.StartedAt)
+	}
+	return total
+}
+
+// Worked returns the paid time of the shift, counting an open shift up to now
+func (s Shift) Worked(now time.Time) time.Duration {
+	end := now
+	if s.ClockOut != nil {
+		end = *s.ClockOut
+	}
+	worked := end.Sub(s.ClockIn) - s.BreakDuration(end)
+	if worked < 0 {
+		return 0
+	}
+	return worked
+}
+
+// InvoiceNumberRange is a block of uniform invoice numbers allocated by the tax authority
+// for one two-month period, e.g. track "AB" numbers 12345000 to 12345049
+type InvoiceNumberRange struct {
+	gorm.Model
+	LocationID *uint `gorm:"index"`
+	// Period is the ROC year and even month that ends the period, e.g. "11312" for Nov-Dec 2024
+	Period  string `gorm:"not null;index"`
+	Track   string `gorm:"not null"`
+	StartNo int    `gorm:"not null"`
+	EndNo   int    `gorm:"not null"`
+	NextNo  int    `gorm:"not null"`
+}
+
+// Invoice statuses
+const (
+	InvoiceIssued = "issued"
+	InvoiceVoided = "voided"
+)
+
+// Carrier types
+const (
+	CarrierMobileBarcode = "3J0002"
+	CarrierCitizenCert   = "CQ0001"
+)
+
+type Invoice struct {
+	gorm.Model
+	LocationID   *uint     `gorm:"index"`
+	OrderID      uint      `gorm:"index;not null"`
+	Number       string    `gorm:"uniqueIndex;not null"`
+	Period       string    `gorm:"not null"`
+	IssuedAt     time.Time `gorm:"not null"`
+	RandomNumber string    `gorm:"not null"`
+	SellerTaxID  string    `gorm:"not null"`
+	SellerName   string    `gorm:"not null"`
+	// BuyerTaxID is "0000000000" for consumers without a business tax ID
+	BuyerTaxID   string `gorm:"not null"`
+	CarrierType  string
+	CarrierID    string
+	DonationCode string
+	SalesAmount  int64  `gorm:"not null"`
+	TaxAmount    int64  `gorm:"not null"`
+	TotalAmount  int64  `gorm:"not null"`
+	Status       string `gorm:"not null"`
+	VoidedAt     *time.Time
+	VoidReason   string
+	UploadedAt   *time.Time
+	// VoidUploadedAt is when the void message reached the e-invoice platform
+	VoidUploadedAt *time.Time
+	Items          []InvoiceItem
+	Allowances     []InvoiceAllowance
+}
+
+type InvoiceItem struct {
+	gorm.Model
+	InvoiceID   uint    `gorm:"index;not null"`
+	Description string  `gorm:"not null"`
+	Quantity    int     `gorm:"not null"`
+	UnitPrice   float64 `gorm:"not null"`
+	Amount      float64 `gorm:"not null"`
+}
+
+// InvoiceAllowance (折讓) reduces the amount of an invoice from an earlier period
+type InvoiceAllowance struct {
+	gorm.Model
+	InvoiceID  uint      `gorm:"index;not null"`
+	Number     string    `gorm:"uniqueIndex;not null"`
+	IssuedAt   time.Time `gorm:"not null"`
+	Amount     int64     `gorm:"not null"`
+	TaxAmount  int64     `gorm:"not null"`
+	Reason     string    `gorm:"not null"`
+	UploadedAt *time.Time
+}
+
 type TimeRange struct {
 	Open  string `json:"open"`
 	Close string `json:"close"`
@@ -101,7 +766,8 @@ type OpeningHours struct {
 
 type RestaurantInfo struct {
 	gorm.Model
-	Name         string       `json:"name" gorm:"uniqueIndex"`
+	LocationID   *uint        `json:"location_id" gorm:"uniqueIndex:idx_restaurant_infos_location_name"`
+	Name         string       `json:"name" gorm:"uniqueIndex:idx_restaurant_infos_location_name"`
 	Description  string       `json:"description"`
 	Address      string       `json:"address"`
 	Phone        string       `json:"phone"`
@@ -109,6 +775,104 @@ type RestaurantInfo struct {
 	LogoURL      string       `json:"logo_url"`
 	BannerURL    string       `json:"banner_url"`
 	OpeningHours OpeningHours `gorm:"type:jsonb" json:"opening_hours"`
+	// TaxID is the business tax ID (統一編號) printed on invoices
+	TaxID string `json:"tax_id"`
+	// ApprovalThreshold is the largest void or refund staff may make without a manager; 0 requires approval for all
+	ApprovalThreshold float64 `gorm:"not null;default:0" json:"approval_threshold"`
+	// TurnTimeMinutes is how long a reservation holds its table
+	TurnTimeMinutes int `gorm:"not null;default:90" json:"turn_time_minutes"`
+	// NoShowGraceMinutes is how late a party may be before it can be marked as a no-show
+	NoShowGraceMinutes int `gorm:"not null;default:15" json:"no_show_grace_minutes"`
+	// SlotCapacity caps takeout and delivery orders per pickup slot, zero means unlimited
+	SlotCapacity int `gorm:"not null;default:0" json:"slot_capacity"`
+	// KitchenLeadMinutes is how long before pickup a scheduled order is sent to the kitchen
+	KitchenLeadMinutes int `gorm:"not null;default:20" json:"kitchen_lead_minutes"`
+	TaxSettings
+	LoyaltySettings
+}
+
+type TaxSettings struct {
+	// TaxRate is a fraction, 0.05 for Taiwan's business tax
+	TaxRate float64 `gorm:"not null;default:0" json:"tax_rate"`
+	// TaxExclusive adds tax on top of menu prices instead of treating it as included
+	TaxExclusive bool `gorm:"not null;default:false" json:"tax_exclusive"`
+}
+
+type LoyaltySettings struct {
+	// SpendPerPoint is the amount spent to earn one loyalty point
+	SpendPerPoint float64 `gorm:"not null;default:100" json:"spend_per_point"`
+	// PointValue is the discount one point is worth when redeemed
+	PointValue float64 `gorm:"not null;default:1" json:"point_value"`
+	// PointsExpireDays is how long earned points can be redeemed
+	PointsExpireDays int `gorm:"not null;default:365" json:"points_expire_days"`
+}
+
+// Defaults used when a location has not configured its loyalty program
+const (
+	DefaultSpendPerPoint    = 100
+	DefaultPointValue       = 1
+	DefaultPointsExpireDays = 365
+)
+
+// PointsEarned returns the whole points earned for spending amount
+func (l LoyaltySettings) PointsEarned(amount float64) int {
+	spend := l.SpendPerPoint
+	if spend <= 0 {
+		spend = DefaultSpendPerPoint
+	}
+	if amount <= 0 {
+		return 0
+	}
+	return int(math.Floor(amount / spend))
+}
+
+// RedemptionValue returns the discount for redeeming points
+func (l LoyaltySettings) RedemptionValue(points int) float64 {
+	value := l.PointValue
+	if value <= 0 {
+		value = DefaultPointValue
+	}
+	return roundCents(float64(points) * value)
+}
+
+// PointsExpiry returns when points earned at t expire
+func (l LoyaltySettings) PointsExpiry(t time.Time) time.Time {
+	days := l.PointsExpireDays
+	if days <= 0 {
+		days = DefaultPointsExpireDays
+	}
+	return t.AddDate(0, 0, days)
+}
+
+// Defaults used when a location has not configured its reservation settings
+const (
+	DefaultTurnTimeMinutes    = 90
+	DefaultNoShowGraceMinutes = 15
+	DefaultKitchenLeadMinutes = 20
+)
+
+// TurnMinutes returns how long a reservation holds its table, in minutes
+func (ri RestaurantInfo) TurnMinutes() int {
+	if ri.TurnTimeMinutes <= 0 {
+		return DefaultTurnTimeMinutes
+	}
+	return ri.TurnTimeMinutes
+}
+
+// NoShowGrace returns how late a party may arrive before it counts as a no-show
+func (ri RestaurantInfo) NoShowGrace() time.Duration {
+	if ri.NoShowGraceMinutes <= 0 {
+		return DefaultNoShowGraceMinutes * time.Minute
+	}
+	return time.Duration(ri.NoShowGraceMinutes) * time.Minute
+}
+
+// KitchenLead returns how long before pickup a scheduled order is sent to the kitchen
+func (ri RestaurantInfo) KitchenLead() time.Duration {
+	if ri.KitchenLeadMinutes <= 0 {
+		return DefaultKitchenLeadMinutes * time.Minute
+	}
+	return time.Duration(ri.KitchenLeadMinutes) * time.Minute
 }
 
 // Scan implements the sql.Scanner interface for OpeningHours
@@ -125,13 +889,81 @@ func (oh OpeningHours) Value() (driver.Value, error) {
 	return json.Marshal(oh)
 }
 
-// IsOpen checks if the restaurant is open at the given time
+// AuditData is a row or a set of changes stored as JSON in the audit log
+type AuditData map[string]interface{}
+
+// Scan implements the sql.Scanner interface for AuditData
+func (ad *AuditData) Scan(value interface{}) error {
+	if value == nil {
+		*ad = nil
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, ad)
+}
+
+// Value implements the driver.Valuer interface for AuditData
+func (ad AuditData) Value() (driver.Value, error) {
+	if ad == nil {
+		return nil, nil
+	}
+	return json.Marshal(ad)
+}
+
+// Audit log actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditLog records one write to one row. The table only takes inserts.
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+	LocationID *uint     `gorm:"index" json:"location_id"`
+	// Actor is the username from the request's token, or "system" for background jobs
+	Actor    string    `gorm:"index;not null" json:"actor"`
+	Action   string    `gorm:"not null" json:"action"`
+	Entity   string    `gorm:"not null;index:idx_audit_logs_entity" json:"entity"`
+	EntityID string    `gorm:"index:idx_audit_logs_entity" json:"entity_id"`
+	Before   AuditData `gorm:"type:jsonb" json:"before,omitempty"`
+	After    AuditData `gorm:"type:jsonb" json:"after,omitempty"`
+	// Changes maps each changed column to its old and new values
+	Changes AuditData `gorm:"type:jsonb" json:"changes,omitempty"`
+	IP      string    `json:"ip"`
+}
+
+// IsOpen checks if the restaurant is open at the given time, read in RestaurantZone
+// whatever offset t carries
 func (oh OpeningHours) IsOpen(t time.Time) bool {
+	t = t.In(RestaurantZone)
+	return isDayScheduleOpen(oh.scheduleFor(t), t)
+}
+
+// IsOpenThrough reports whether the restaurant is open for all of [start, end),
+// following on from one opening range to the next when they meet. Times are
+// read in RestaurantZone.
+func (oh OpeningHours) IsOpenThrough(start, end time.Time) bool {
+	for t := start.In(RestaurantZone); t.Before(end); {
+		closes, ok := closingTime(oh.scheduleFor(t), t)
+		if !ok || !closes.After(t) {
+			return false
+		}
+		t = closes
+	}
+	return true
+}
+
+func (oh OpeningHours) scheduleFor(t time.Time) DaySchedule {
 	// Check for special dates first
 	dateStr := t.Format("2006-01-02")
 	for _, specialDate := range oh.SpecialDates {
 		if specialDate.Date == dateStr {
-			return isDayScheduleOpen(specialDate.Schedule, t)
+			return specialDate.Schedule
 		}
 	}
 
@@ -153,16 +985,25 @@ func (oh OpeningHours) IsOpen(t time.Time) bool {
 	case time.Sunday:
 		daySchedule = oh.WeekSchedule.Sunday
 	}
-
-	return isDayScheduleOpen(daySchedule, t)
+	return daySchedule
 }
 
 func isDayScheduleOpen(schedule DaySchedule, t time.Time) bool {
+	_, ok := closingTime(schedule, t)
+	return ok
+}
+
+// closingTime returns when the opening range that t falls in closes
+func closingTime(schedule DaySchedule, t time.Time) (time.Time, bool) {
 	currentTime := t.Format("15:04")
 	for _, timeRange := range schedule.Ranges {
 		if currentTime >= timeRange.Open && currentTime < timeRange.Close {
-			return true
+			var hour, minute int
+			if _, err := fmt.Sscanf(timeRange.Close, "%d:%d", &hour, &minute); err != nil {
+				return time.Time{}, false
+			}
+			return time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location()), true
 		}
 	}
-	return false
+	return time.Time{}, false
 }
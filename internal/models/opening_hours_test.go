@@ -0,0 +1,74 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpeningHours(t *testing.T) {
+	lunchAndDinner := DaySchedule{Ranges: []TimeRange{{Open: "11:00", Close: "14:00"}, {Open: "17:00", Close: "21:00"}}}
+	allDay := DaySchedule{Ranges: []TimeRange{{Open: "11:00", Close: "15:00"}, {Open: "15:00", Close: "22:00"}}}
+	hours := OpeningHours{
+		WeekSchedule: WeekSchedule{Monday: lunchAndDinner, Saturday: allDay},
+		SpecialDates: []SpecialDate{{Date: "2024-06-10", Schedule: DaySchedule{}}},
+	}
+	// 2024-06-03 is a Monday and 2024-06-08 a Saturday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.June, day, hour, minute, 0, 0, RestaurantZone)
+	}
+	// utc is the same instant as at, as a client sending UTC would give it
+	utc := func(day, hour, minute int) time.Time {
+		return at(day, hour, minute).UTC()
+	}
+
+	openTests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"opening time", at(3, 11, 0), true},
+		{"before opening", at(3, 10, 59), false},
+		{"closing time", at(3, 14, 0), false},
+		{"between ranges", at(3, 15, 30), false},
+		{"second range", at(3, 20, 59), true},
+		{"day without hours", at(4, 12, 0), false},
+		{"special date closed", at(10, 12, 0), false},
+		{"UTC time in opening hours", utc(3, 19, 0), true},
+		{"UTC time outside opening hours", utc(3, 16, 0), false},
+		// 01:00 on Tuesday in UTC is 09:00 on Tuesday in Taipei, a day without hours
+		{"UTC time on another weekday", time.Date(2024, time.June, 4, 1, 0, 0, 0, time.UTC), false},
+		// 03:30 on Monday in UTC is 11:30 on Monday in Taipei
+		{"UTC time early on the day", time.Date(2024, time.June, 3, 3, 30, 0, 0, time.UTC), true},
+	}
+	for _, tt := range openTests {
+		t.Run("IsOpen/"+tt.name, func(t *testing.T) {
+			if got := hours.IsOpen(tt.t); got != tt.want {
+				t.Errorf("IsOpen(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+
+	throughTests := []struct {
+		name       string
+		start, end time.Time
+		want       bool
+	}{
+		{"within a range", at(3, 11, 30), at(3, 13, 0), true},
+		{"up to closing", at(3, 12, 30), at(3, 14, 0), true},
+		{"past closing", at(3, 13, 0), at(3, 14, 30), false},
+		{"across a break", at(3, 13, 30), at(3, 17, 30), false},
+		{"across adjoining ranges", at(8, 14, 0), at(8, 16, 30), true},
+		{"starting closed", at(3, 10, 30), at(3, 11, 30), false},
+		{"special date", at(10, 12, 0), at(10, 13, 0), false},
+		{"UTC times", utc(3, 19, 0), utc(3, 20, 30), true},
+		{"UTC times past closing", utc(3, 20, 0), utc(3, 21, 30), false},
+		{"mixed offsets", at(8, 14, 0), utc(8, 16, 30), true},
+	}
+	for _, tt := range throughTests {
+		t.Run("IsOpenThrough/"+tt.name, func(t *testing.T) {
+			if got := hours.IsOpenThrough(tt.start, tt.end); got != tt.want {
+				t.Errorf("IsOpenThrough(%v, %v) = %v, want %v", tt.start, tt.end, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package receipt
+
+import (
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+)
+
+// escposLogoHeight caps the height of the printed logo, in dots
+const escposLogoHeight = 160
+
+// ESCPOS renders the receipt for a thermal printer with paper of the given width
+// (escpos.Width58mm or escpos.Width80mm). The logo takes up to half the paper width.
+func ESCPOS(rec Receipt, paperWidth int) []byte {
+	w := escpos.NewWriter(paperWidth)
+
+	w.Align(escpos.AlignCenter)
+	if rec.Logo != nil {
+		w.Image(fit(rec.Logo, paperWidth*escpos.DotsPerChar/2, escposLogoHeight))
+	}
+	w.Bold(true).DoubleSize(true).Line(rec.RestaurantName).DoubleSize(false).Bold(false)
+	if rec.Address != "" {
+		w.Line(rec.Address)
+	}
+	if rec.Phone != "" {
+		w.Line(rec.Phone)
+	}
+
+	w.Align(escpos.AlignLeft).Rule('-')
+	w.Columns("Order "+rec.OrderNumber, rec.Service)
+	w.Line(rec.IssuedAt.Format("2006-01-02 15:04"))
+	w.Rule('-')
+
+	for _, item := range rec.Items {
+		w.Columns(item.Label, item.Amount)
+		if item.Detail != "" {
+			w.Line("  " + item.Detail)
+		}
+	}
+	w.Rule('-')
+
+	w.Columns("Subtotal", rec.Subtotal)
+	for _, discount := range rec.Discounts {
+		w.Columns(discount.Label, discount.Amount)
+	}
+	if rec.Tax != nil {
+		w.Columns(rec.Tax.Label, rec.Tax.Amount)
+	}
+	w.Bold(true).Columns("Total", rec.Total).Bold(false)
+
+	for _, payment := range rec.Payments {
+		w.Columns(payment.Label, payment.Amount)
+	}
+	if rec.Change != "" {
+		w.Columns("Change", rec.Change)
+	}
+	if rec.Balance != "" {
+		w.Bold(true).Columns("Balance due", rec.Balance).Bold(false)
+	}
+
+	return w.Rule('-').Align(escpos.AlignCenter).Line("Thank you!").Feed(3).Cut().Bytes()
+}
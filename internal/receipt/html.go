@@ -0,0 +1,55 @@
+package receipt
+
+import (
+	"html/template"
+	"io"
+)
+
+var htmlTemplate = template.Must(template.New("receipt").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.RestaurantName}} {{.OrderNumber}}</title>
+<style>
+body { font-family: sans-serif; max-width: 360px; margin: 0 auto; padding: 16px; }
+header { text-align: center; }
+header img { max-width: 160px; }
+table { width: 100%; border-collapse: collapse; }
+td.amount { text-align: right; white-space: nowrap; }
+td.detail { color: #666; font-size: 0.9em; padding-left: 1em; }
+tr.total td { font-weight: bold; border-top: 1px solid #000; }
+hr { border: none; border-top: 1px dashed #000; }
+</style>
+</head>
+<body>
+<header>
+{{if .LogoURL}}<img src="{{.LogoURL}}" alt="{{.RestaurantName}}">{{end}}
+<h1>{{.RestaurantName}}</h1>
+{{if .Address}}<div>{{.Address}}</div>{{end}}
+{{if .Phone}}<div>{{.Phone}}</div>{{end}}
+</header>
+<hr>
+<div>Order {{.OrderNumber}} &middot; {{.Service}}</div>
+<div>{{.IssuedAt.Format "2006-01-02 15:04"}}</div>
+<hr>
+<table>
+{{range .Items}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{if .Detail}}<tr><td class="detail" colspan="2">{{.Detail}}</td></tr>{{end}}
+{{end}}<tr class="total"><td>Subtotal</td><td class="amount">{{.Subtotal}}</td></tr>
+{{range .Discounts}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}{{with .Tax}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}<tr class="total"><td>Total</td><td class="amount">{{.Total}}</td></tr>
+{{range .Payments}}<tr><td>{{.Label}}</td><td class="amount">{{.Amount}}</td></tr>
+{{end}}{{if .Change}}<tr><td>Change</td><td class="amount">{{.Change}}</td></tr>
+{{end}}{{if .Balance}}<tr class="total"><td>Balance due</td><td class="amount">{{.Balance}}</td></tr>
+{{end}}</table>
+<hr>
+<footer style="text-align: center">Thank you!</footer>
+</body>
+</html>
+`))
+
+// WriteHTML renders the receipt as a standalone HTML page
+func WriteHTML(w io.Writer, rec Receipt) error {
+	return htmlTemplate.Execute(w, rec)
+}
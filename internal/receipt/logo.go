@@ -0,0 +1,166 @@
+package receipt
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+)
+
+const (
+	// maxLogoBytes and maxLogoPixels keep a logo from exhausting memory
+	maxLogoBytes  = 1 << 20
+	maxLogoPixels = 4096 * 4096
+	logoTimeout   = 3 * time.Second
+	// logoCacheTTL is how long a logo, or the failure to load it, is remembered
+	logoCacheTTL = time.Hour
+)
+
+var errPrivateLogoAddress = errors.New("logo address is not public")
+
+// Logos loads the restaurant logos printed on PDF and ESC/POS receipts. A logo is
+// a data: URL or an http(s) URL on a public address, so the API cannot be used
+// to reach internal services. Logos are cached, so receipts do not wait on them.
+type Logos struct {
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]cachedLogo
+}
+
+type cachedLogo struct {
+	img      image.Image
+	loadedAt time.Time
+}
+
+func NewLogos() *Logos {
+	dialer := &net.Dialer{Timeout: logoTimeout, Control: dialPublicOnly}
+	return &Logos{
+		client: &http.Client{
+			Timeout:   logoTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+		},
+		cache: make(map[string]cachedLogo),
+	}
+}
+
+// Get returns the logo at url, or nil when there is none or it cannot be loaded;
+// a receipt without its logo is better than no receipt
+func (l *Logos) Get(ctx context.Context, url string) image.Image {
+	if url == "" {
+		return nil
+	}
+
+	l.mu.Lock()
+	cached, ok := l.cache[url]
+	l.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < logoCacheTTL {
+		return cached.img
+	}
+
+	img, err := l.load(ctx, url)
+	if err != nil {
+		logger.Logger.WarnContext(ctx, "failed to load receipt logo", "url", url, "error", err)
+	}
+	l.mu.Lock()
+	l.cache[url] = cachedLogo{img: img, loadedAt: time.Now()}
+	l.mu.Unlock()
+	return img
+}
+
+func (l *Logos) load(ctx context.Context, url string) (image.Image, error) {
+	var data []byte
+	switch {
+	case strings.HasPrefix(url, "data:"):
+		header, encoded, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok || !strings.HasSuffix(header, ";base64") {
+			return nil, errors.New("logo data URL must be base64 encoded")
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		data = decoded
+	case strings.HasPrefix(url, "https://"), strings.HasPrefix(url, "http://"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := l.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("logo request returned %s", resp.Status)
+		}
+		data, err = io.ReadAll(io.LimitReader(resp.Body, maxLogoBytes+1))
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("logo URL must be a data: or http(s) URL")
+	}
+
+	if len(data) > maxLogoBytes {
+		return nil, fmt.Errorf("logo is larger than %d bytes", maxLogoBytes)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > maxLogoPixels {
+		return nil, fmt.Errorf("logo is larger than %d pixels", maxLogoPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	return img, err
+}
+
+// dialPublicOnly refuses connections to loopback, private and link-local addresses
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("%w: %s", errPrivateLogoAddress, host)
+	}
+	return nil
+}
+
+// fit scales img down with nearest-neighbour sampling, keeping its aspect ratio,
+// to at most maxWidth by maxHeight. Images that already fit are returned as they are.
+func fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= maxWidth && height <= maxHeight {
+		return img
+	}
+
+	scale := min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	scaledWidth := max(1, int(float64(width)*scale))
+	scaledHeight := max(1, int(float64(height)*scale))
+	scaled := image.NewRGBA(image.Rect(0, 0, scaledWidth, scaledHeight))
+	for y := 0; y < scaledHeight; y++ {
+		for x := 0; x < scaledWidth; x++ {
+			scaled.Set(x, y, img.At(bounds.Min.X+x*width/scaledWidth, bounds.Min.Y+y*height/scaledHeight))
+		}
+	}
+	return scaled
+}
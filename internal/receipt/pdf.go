@@ -0,0 +1,195 @@
+package receipt
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/escpos"
+)
+
+// The PDF uses MSung-Light, one of the standard Traditional Chinese fonts every
+// PDF reader provides, so Chinese menu items render without embedding a font.
+const (
+	pdfPageWidth  = 226.77 // 80mm
+	pdfMargin     = 14.0
+	pdfFontSize   = 9.0
+	pdfLineHeight = 13.0
+	// The logo is drawn within this box, in points, from an image of up to twice the resolution
+	pdfLogoWidth  = 96.0
+	pdfLogoHeight = 48.0
+)
+
+type pdfLine struct {
+	left, right string
+	size        float64
+	centered    bool
+}
+
+// PDF renders the receipt as a single page, 80mm wide PDF document
+func PDF(rec Receipt) []byte {
+	var lines []pdfLine
+	add := func(left, right string) {
+		lines = append(lines, pdfLine{left: left, right: right, size: pdfFontSize})
+	}
+	center := func(text string, size float64) {
+		lines = append(lines, pdfLine{left: text, size: size, centered: true})
+	}
+	rule := func() {
+		add(strings.Repeat("-", 40), "")
+	}
+
+	center(rec.RestaurantName, 14)
+	if rec.Address != "" {
+		center(rec.Address, pdfFontSize)
+	}
+	if rec.Phone != "" {
+		center(rec.Phone, pdfFontSize)
+	}
+	rule()
+	add("Order "+rec.OrderNumber, rec.Service)
+	add(rec.IssuedAt.Format("2006-01-02 15:04"), "")
+	rule()
+	for _, item := range rec.Items {
+		add(item.Label, item.Amount)
+		if item.Detail != "" {
+			add("    "+item.Detail, "")
+		}
+	}
+	rule()
+	add("Subtotal", rec.Subtotal)
+	for _, discount := range rec.Discounts {
+		add(discount.Label, discount.Amount)
+	}
+	if rec.Tax != nil {
+		add(rec.Tax.Label, rec.Tax.Amount)
+	}
+	add("Total", rec.Total)
+	for _, payment := range rec.Payments {
+		add(payment.Label, payment.Amount)
+	}
+	if rec.Change != "" {
+		add("Change", rec.Change)
+	}
+	if rec.Balance != "" {
+		add("Balance due", rec.Balance)
+	}
+	rule()
+	center("Thank you!", pdfFontSize)
+
+	var logo *pdfImage
+	logoSpace := 0.0
+	if rec.Logo != nil {
+		logo = newPDFImage(rec.Logo)
+		logoSpace = logo.height + pdfLineHeight/2
+	}
+
+	height := 2*pdfMargin + logoSpace + float64(len(lines)+1)*pdfLineHeight
+	var content bytes.Buffer
+	if logo != nil {
+		fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im1 Do Q\n",
+			logo.width, logo.height, (pdfPageWidth-logo.width)/2, height-pdfMargin-logo.height)
+	}
+	y := height - pdfMargin - logoSpace - pdfLineHeight
+	for _, line := range lines {
+		x := pdfMargin
+		if line.centered {
+			x = (pdfPageWidth - pdfTextWidth(line.left, line.size)) / 2
+		}
+		writePDFText(&content, line.left, x, y, line.size)
+		if line.right != "" {
+			writePDFText(&content, line.right, pdfPageWidth-pdfMargin-pdfTextWidth(line.right, line.size), y, line.size)
+		}
+		y -= pdfLineHeight
+		if line.size > pdfFontSize {
+			y -= line.size - pdfFontSize
+		}
+	}
+
+	resources := "/Font << /F1 5 0 R >>"
+	if logo != nil {
+		resources += " /XObject << /Im1 8 0 R >>"
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents 4 0 R >>", pdfPageWidth, height, resources),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		"<< /Type /Font /Subtype /Type0 /BaseFont /MSung-Light /Encoding /UniCNS-UCS2-H /DescendantFonts [6 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /MSung-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (CNS1) /Supplement 0 >> /FontDescriptor 7 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /MSung-Light /Flags 6 /FontBBox [-160 -249 1015 1071] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	}
+	if logo != nil {
+		objects = append(objects, fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode /Length %d >>\nstream\n%s\nendstream",
+			logo.pixelWidth, logo.pixelHeight, len(logo.data), logo.data))
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfImage is a logo as Flate-compressed RGB samples with the size it is drawn at
+type pdfImage struct {
+	width, height           float64
+	pixelWidth, pixelHeight int
+	data                    []byte
+}
+
+// newPDFImage lays img over white, as PDF 1.4 images without a soft mask are opaque
+func newPDFImage(img image.Image) *pdfImage {
+	img = fit(img, 2*pdfLogoWidth, 2*pdfLogoHeight)
+	bounds := img.Bounds()
+	logo := &pdfImage{pixelWidth: bounds.Dx(), pixelHeight: bounds.Dy()}
+	scale := min(pdfLogoWidth/float64(logo.pixelWidth), pdfLogoHeight/float64(logo.pixelHeight))
+	logo.width, logo.height = float64(logo.pixelWidth)*scale, float64(logo.pixelHeight)*scale
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	pixel := make([]byte, 3)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			white := 0xffff - a
+			pixel[0], pixel[1], pixel[2] = byte((r+white)>>8), byte((g+white)>>8), byte((b+white)>>8)
+			zw.Write(pixel)
+		}
+	}
+	zw.Close()
+	logo.data = compressed.Bytes()
+	return logo
+}
+
+func writePDFText(content *bytes.Buffer, text string, x, y, size float64) {
+	fmt.Fprintf(content, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, y, pdfHexString(text))
+}
+
+// pdfHexString encodes text as UCS-2 for the UniCNS-UCS2-H encoding
+func pdfHexString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > 0xffff || utf16.IsSurrogate(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+func pdfTextWidth(text string, size float64) float64 {
+	return float64(escpos.DisplayWidth(text)) * size / 2
+}
@@ -0,0 +1,155 @@
+package receipt
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+// Line is one row of a receipt
+type Line struct {
+	Label  string
+	Detail string
+	Amount string
+}
+
+// Receipt is the printable content of a paid or open order, shared by every output format
+type Receipt struct {
+	RestaurantName string
+	Address        string
+	Phone          string
+	LogoURL        string
+	// Logo is the image printed on PDF and ESC/POS receipts, nil for none; HTML links LogoURL
+	Logo image.Image
+
+	OrderNumber string
+	Service     string
+	IssuedAt    time.Time
+
+	Items     []Line
+	Subtotal  string
+	Discounts []Line
+	Tax       *Line
+	Total     string
+	Payments  []Line
+	Change    string
+	Balance   string
+}
+
+// New builds a receipt from an order with its details, selected add-ons, discounts and payments loaded
+func New(order models.Order, info models.RestaurantInfo, issuedAt time.Time) Receipt {
+	rec := Receipt{
+		RestaurantName: info.Name,
+		Address:        info.Address,
+		Phone:          info.Phone,
+		LogoURL:        info.LogoURL,
+		OrderNumber:    fmt.Sprintf("#%d", order.ID),
+		IssuedAt:       issuedAt,
+		Subtotal:       Money(order.ItemsTotal()),
+		Total:          Money(order.TotalAmount),
+	}
+
+	switch order.OrderType {
+	case models.OrderTypeTakeout:
+		rec.Service = "Takeout"
+	case models.OrderTypeDelivery:
+		rec.Service = "Delivery"
+	default:
+		rec.Service = "Table " + order.TableNumber
+	}
+
+	for _, detail := range order.OrderDetails {
+		addOns := make([]string, len(detail.SelectedAddOns))
+		for i, addOn := range detail.SelectedAddOns {
+			addOns[i] = addOn.Name
+		}
+		rec.Items = append(rec.Items, Line{
+			Label:  fmt.Sprintf("%d x %s", detail.Quantity, detailName(detail)),
+			Detail: strings.Join(addOns, ", "),
+			Amount: Money(detail.Subtotal),
+		})
+	}
+	for _, adjustment := range order.Adjustments {
+		if adjustment.Type != models.AdjustmentVoid {
+			continue
+		}
+		rec.Items = append(rec.Items, Line{
+			Label:  fmt.Sprintf("Void %d x %s", adjustment.Quantity, adjustedItemName(order, adjustment)),
+			Amount: Money(-adjustment.Amount),
+		})
+	}
+
+	for _, discount := range order.Discounts {
+		rec.Discounts = append(rec.Discounts, Line{Label: discount.Description, Amount: Money(-discount.Amount)})
+	}
+
+	if info.TaxRate > 0 {
+		label := fmt.Sprintf("Tax %g%%", info.TaxRate*100)
+		if !info.TaxExclusive {
+			label += " (included)"
+		}
+		rec.Tax = &Line{Label: label, Amount: Money(order.TaxAmount)}
+	}
+
+	change := 0.0
+	for _, payment := range order.Payments {
+		amount := payment.Amount
+		if payment.Tendered > 0 {
+			amount = payment.Tendered
+		}
+		rec.Payments = append(rec.Payments, Line{Label: paymentLabel(payment.Method), Amount: Money(amount)})
+		change += payment.Change()
+	}
+	if change > 0 {
+		rec.Change = Money(change)
+	}
+	for _, adjustment := range order.Adjustments {
+		if adjustment.Type == models.AdjustmentRefund {
+			rec.Payments = append(rec.Payments, Line{Label: "Refund " + paymentLabel(adjustment.Method), Amount: Money(-adjustment.Amount)})
+		}
+	}
+	if balance := order.Balance(); balance > 0.005 {
+		rec.Balance = Money(balance)
+	}
+
+	return rec
+}
+
+func adjustedItemName(order models.Order, adjustment models.OrderAdjustment) string {
+	for _, detail := range order.OrderDetails {
+		if adjustment.OrderDetailID != nil && detail.ID == *adjustment.OrderDetailID {
+			return detailName(detail)
+		}
+	}
+	return "item"
+}
+
+// Money formats an amount of New Taiwan dollars
+func Money(amount float64) string {
+	if amount < 0 {
+		return fmt.Sprintf("-$%.2f", -amount)
+	}
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+func detailName(detail models.OrderDetail) string {
+	if detail.MenuItemName != "" {
+		return detail.MenuItemName
+	}
+	return fmt.Sprintf("Item %d", detail.MenuItemID)
+}
+
+func paymentLabel(method string) string {
+	switch method {
+	case models.PaymentCash:
+		return "Cash"
+	case models.PaymentCard:
+		return "Card"
+	case models.PaymentMobile:
+		return "Mobile payment"
+	}
+	return method
+}
@@ -0,0 +1,62 @@
+// Package memory implements the repositories in process memory, for tests that
+// run without a database. It applies the same location scoping as the Postgres
+// tenant callbacks. It keeps no customers, cash drawers, loyalty points or
+// kitchen tickets, so order writes leave those alone.
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+// NewStore returns empty in-memory repositories
+func NewStore() repository.Store {
+	return repository.Store{
+		Users:          NewUsers(),
+		Menu:           NewMenu(),
+		Orders:         NewOrders(),
+		RestaurantInfo: NewRestaurantInfo(),
+	}
+}
+
+// visible reports whether the caller may read a row of locationID. Rows without
+// a location are master data when master is set.
+func visible(ctx context.Context, locationID *uint, master bool) bool {
+	caller, ok := auth.LocationIDFromContext(ctx)
+	if !ok {
+		return true
+	}
+	if locationID == nil {
+		return master
+	}
+	return *locationID == caller
+}
+
+// writable reports whether the caller may change a row of locationID
+func writable(ctx context.Context, locationID *uint) bool {
+	return visible(ctx, locationID, false)
+}
+
+// assign stamps a new row with the caller's location
+func assign(ctx context.Context, locationID **uint) {
+	if caller, ok := auth.LocationIDFromContext(ctx); ok {
+		*locationID = &caller
+	}
+}
+
+// sameLocation reports whether two rows belong to the same location, or both to the whole business
+func sameLocation(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// now matches the time zone the Postgres connection stamps rows with
+func now() time.Time {
+	return time.Now().In(models.RestaurantZone)
+}
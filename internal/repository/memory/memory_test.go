@@ -0,0 +1,55 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/repositorytest"
+)
+
+func TestStore(t *testing.T) {
+	var locations uint
+	repositorytest.Run(t,
+		func(*testing.T) repository.Store { return NewStore() },
+		func(*testing.T) uint { locations++; return locations })
+}
+
+func TestRatings(t *testing.T) {
+	menu := NewMenu()
+	first := auth.WithLocationID(context.Background(), 1)
+	second := auth.WithLocationID(context.Background(), 2)
+	item := models.MenuItem{Name: "Tea", Price: 60}
+	if err := menu.CreateItem(context.Background(), &item); err != nil {
+		t.Fatalf("CreateItem: %v", err)
+	}
+	menu.AddRating(first, item.ID, 4)
+	menu.AddRating(first, item.ID, 5)
+	menu.AddRating(first, item.ID, 5)
+	menu.AddRating(second, item.ID, 1)
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		count   int
+		average float64
+	}{
+		{"head office sees every rating", context.Background(), 4, 3.8},
+		{"location sees its own ratings", first, 3, 4.7},
+		{"other location", second, 1, 1},
+		{"location without ratings", auth.WithLocationID(context.Background(), 3), 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := menu.GetItem(tt.ctx, item.ID)
+			if err != nil {
+				t.Fatalf("GetItem: %v", err)
+			}
+			if got.RatingCount != tt.count || got.AverageRating != tt.average {
+				t.Errorf("ratings = %d averaging %v, want %d averaging %v", got.RatingCount, got.AverageRating, tt.count, tt.average)
+			}
+		})
+	}
+}
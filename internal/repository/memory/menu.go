@@ -0,0 +1,292 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type Menu struct {
+	mu          sync.Mutex
+	nextID      uint
+	categories  map[uint]models.Category
+	items       map[uint]models.MenuItem
+	prices      map[uint]map[uint]float64
+	ratings     []models.ItemRating
+	nextAddOnID uint
+}
+
+func NewMenu() *Menu {
+	return &Menu{
+		categories: make(map[uint]models.Category),
+		items:      make(map[uint]models.MenuItem),
+		prices:     make(map[uint]map[uint]float64),
+	}
+}
+
+// SetLocationPrice overrides the price of a menu item at one location
+func (m *Menu) SetLocationPrice(locationID, menuItemID uint, price float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.prices[locationID] == nil {
+		m.prices[locationID] = make(map[uint]float64)
+	}
+	m.prices[locationID][menuItemID] = price
+}
+
+// AddRating records a guest's rating of a menu item at the caller's location
+func (m *Menu) AddRating(ctx context.Context, menuItemID uint, rating int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	itemRating := models.ItemRating{MenuItemID: menuItemID, Rating: rating}
+	assign(ctx, &itemRating.LocationID)
+	m.ratings = append(m.ratings, itemRating)
+}
+
+func (m *Menu) ListCategories(ctx context.Context) ([]models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	categories := []models.Category{}
+	for _, category := range m.categories {
+		if visible(ctx, category.LocationID, true) {
+			categories = append(categories, category)
+		}
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		if categories[i].DisplayOrder != categories[j].DisplayOrder {
+			return categories[i].DisplayOrder < categories[j].DisplayOrder
+		}
+		return categories[i].ID < categories[j].ID
+	})
+	return categories, nil
+}
+
+func (m *Menu) GetCategory(ctx context.Context, id uint) (models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, ok := m.categories[id]
+	if !ok || !visible(ctx, category.LocationID, true) {
+		return models.Category{}, repository.ErrNotFound
+	}
+	return category, nil
+}
+
+func (m *Menu) CreateCategory(ctx context.Context, category *models.Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	assign(ctx, &category.LocationID)
+	if m.categoryNameTaken(category.LocationID, category.Name, 0) {
+		return repository.ErrConflict
+	}
+	m.nextID++
+	category.ID = m.nextID
+	category.CreatedAt = now()
+	category.UpdatedAt = category.CreatedAt
+	category.MenuItems = nil
+	m.categories[category.ID] = *category
+	return nil
+}
+
+func (m *Menu) UpdateCategory(ctx context.Context, category *models.Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.categories[category.ID]
+	if !ok || !writable(ctx, existing.LocationID) {
+		return repository.ErrNotFound
+	}
+	category.LocationID = existing.LocationID
+	if m.categoryNameTaken(category.LocationID, category.Name, category.ID) {
+		return repository.ErrConflict
+	}
+	category.CreatedAt = existing.CreatedAt
+	category.UpdatedAt = now()
+	category.MenuItems = nil
+	m.categories[category.ID] = *category
+	return nil
+}
+
+func (m *Menu) DeleteCategory(ctx context.Context, id uint, opts repository.CategoryDelete) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, ok := m.categories[id]
+	if !ok || !visible(ctx, category.LocationID, true) {
+		return 0, repository.ErrNotFound
+	}
+
+	var menuItems []models.MenuItem
+	for _, item := range m.items {
+		if item.CategoryID == id && visible(ctx, item.LocationID, true) {
+			menuItems = append(menuItems, item)
+		}
+	}
+	sort.Slice(menuItems, func(i, j int) bool { return menuItems[i].ID < menuItems[j].ID })
+
+	if len(menuItems) > 0 {
+		if opts.MoveTo != nil || opts.Cascade {
+			// Items the caller cannot change would be left in a deleted category
+			var locked []models.MenuItem
+			for _, item := range menuItems {
+				if !writable(ctx, item.LocationID) {
+					locked = append(locked, item)
+				}
+			}
+			if len(locked) > 0 {
+				return 0, &repository.MenuItemsError{MenuItems: locked}
+			}
+		}
+
+		switch {
+		case opts.MoveTo != nil:
+			if target, ok := m.categories[*opts.MoveTo]; !ok || !visible(ctx, target.LocationID, true) {
+				return 0, repository.ErrTargetNotFound
+			}
+		case opts.Cascade:
+		default:
+			return 0, &repository.MenuItemsError{MenuItems: menuItems}
+		}
+	}
+	if !writable(ctx, category.LocationID) {
+		return 0, repository.ErrNotFound
+	}
+
+	for _, item := range menuItems {
+		if opts.MoveTo != nil {
+			item.CategoryID = *opts.MoveTo
+			m.items[item.ID] = item
+		} else {
+			delete(m.items, item.ID)
+		}
+	}
+	delete(m.categories, id)
+	return len(menuItems), nil
+}
+
+func (m *Menu) ListItems(ctx context.Context) ([]models.MenuItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	menuItems := []models.MenuItem{}
+	for _, item := range m.items {
+		if visible(ctx, item.LocationID, true) {
+			menuItems = append(menuItems, m.priced(ctx, item))
+		}
+	}
+	sort.Slice(menuItems, func(i, j int) bool { return menuItems[i].ID < menuItems[j].ID })
+	return menuItems, nil
+}
+
+func (m *Menu) GetItem(ctx context.Context, id uint) (models.MenuItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[id]
+	if !ok || !visible(ctx, item.LocationID, true) {
+		return models.MenuItem{}, repository.ErrNotFound
+	}
+	return m.priced(ctx, item), nil
+}
+
+func (m *Menu) CreateItem(ctx context.Context, item *models.MenuItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	assign(ctx, &item.LocationID)
+	m.nextID++
+	item.ID = m.nextID
+	item.CreatedAt = now()
+	item.UpdatedAt = item.CreatedAt
+	m.storeItem(item)
+	return nil
+}
+
+func (m *Menu) UpdateItem(ctx context.Context, item *models.MenuItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, ok := m.items[item.ID]
+	if !ok || !writable(ctx, existing.LocationID) {
+		return repository.ErrNotFound
+	}
+	item.CreatedAt = existing.CreatedAt
+	item.UpdatedAt = now()
+	m.storeItem(item)
+	return nil
+}
+
+func (m *Menu) DeleteItem(ctx context.Context, id uint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[id]
+	if !ok || !writable(ctx, item.LocationID) {
+		return repository.ErrNotFound
+	}
+	delete(m.items, id)
+	return nil
+}
+
+// storeItem saves a copy of item with numbered add-ons
+func (m *Menu) storeItem(item *models.MenuItem) {
+	addOns := make([]models.AddOn, len(item.AddOns))
+	for i, addOn := range item.AddOns {
+		m.nextAddOnID++
+		addOn.ID = m.nextAddOnID
+		addOn.MenuItemID = item.ID
+		addOn.CreatedAt = item.UpdatedAt
+		addOn.UpdatedAt = item.UpdatedAt
+		addOns[i] = addOn
+	}
+	item.AddOns = addOns
+	item.AverageRating = 0
+	item.RatingCount = 0
+
+	stored := *item
+	stored.AddOns = append([]models.AddOn(nil), addOns...)
+	m.items[item.ID] = stored
+}
+
+// priced returns a copy of item at the caller location's price, with the
+// average of the ratings the caller can see
+func (m *Menu) priced(ctx context.Context, item models.MenuItem) models.MenuItem {
+	item.AddOns = append([]models.AddOn{}, item.AddOns...)
+	if locationID, ok := auth.LocationIDFromContext(ctx); ok {
+		if price, ok := m.prices[locationID][item.ID]; ok {
+			item.Price = price
+		}
+	}
+
+	total := 0
+	for _, rating := range m.ratings {
+		if rating.MenuItemID == item.ID && visible(ctx, rating.LocationID, false) {
+			total += rating.Rating
+			item.RatingCount++
+		}
+	}
+	if item.RatingCount > 0 {
+		item.AverageRating = math.Round(float64(total)/float64(item.RatingCount)*10) / 10
+	}
+	return item
+}
+
+// categoryNameTaken reports whether another category than id at the location,
+// or among the master categories when locationID is nil, has the name
+func (m *Menu) categoryNameTaken(locationID *uint, name string, id uint) bool {
+	for _, category := range m.categories {
+		if category.Name == name && category.ID != id && sameLocation(category.LocationID, locationID) {
+			return true
+		}
+	}
+	return false
+}
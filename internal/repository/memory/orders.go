@@ -0,0 +1,237 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type Orders struct {
+	mu     sync.Mutex
+	nextID uint
+	orders []models.Order
+	// nextChildID numbers order details, add-ons, discounts and payments
+	nextChildID uint
+}
+
+func NewOrders() *Orders {
+	return &Orders{}
+}
+
+// Add stores an order as it would be after checkout, for tests to read back
+func (o *Orders) Add(ctx context.Context, order models.Order) models.Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	assign(ctx, &order.LocationID)
+	o.nextID++
+	order.ID = o.nextID
+	if order.CreatedAt.IsZero() {
+		order.CreatedAt = now()
+	}
+	order.UpdatedAt = order.CreatedAt
+	o.orders = append(o.orders, clone(order))
+	return order
+}
+
+func (o *Orders) List(ctx context.Context, filter repository.OrderFilter) ([]models.Order, error) {
+	orders := o.matching(ctx, func(order models.Order) bool {
+		return (filter.Status == "" || order.Status == filter.Status) &&
+			(filter.OrderType == "" || order.OrderType == filter.OrderType)
+	})
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	return orders, nil
+}
+
+func (o *Orders) Get(ctx context.Context, id uint) (models.Order, error) {
+	orders := o.matching(ctx, func(order models.Order) bool { return order.ID == id })
+	if len(orders) == 0 {
+		return models.Order{}, repository.ErrNotFound
+	}
+	return orders[0], nil
+}
+
+func (o *Orders) KitchenQueue(ctx context.Context) ([]models.Order, error) {
+	orders := o.matching(ctx, func(order models.Order) bool {
+		return order.Status == models.OrderPending || order.Status == models.OrderPreparing
+	})
+	sort.SliceStable(orders, func(i, j int) bool { return releasedBefore(orders[i].ReleasedAt, orders[j].ReleasedAt) })
+	return orders, nil
+}
+
+func (o *Orders) Stats(ctx context.Context, servedSince time.Time) (repository.OrderStats, error) {
+	stats := repository.OrderStats{ByStatus: make(map[string]int)}
+	var total time.Duration
+	for _, order := range o.matching(ctx, func(models.Order) bool { return true }) {
+		stats.ByStatus[order.Status]++
+		if order.ServedAt != nil && !order.ServedAt.Before(servedSince) {
+			stats.Served++
+			total += order.ServedAt.Sub(order.CreatedAt)
+		}
+	}
+	if stats.Served > 0 {
+		stats.AverageTicketTime = total / time.Duration(stats.Served)
+	}
+	return stats, nil
+}
+
+func (o *Orders) Scheduled(ctx context.Context, from, to time.Time) ([]models.Order, error) {
+	orders := o.matching(ctx, func(order models.Order) bool {
+		return order.IsOffPremises() && order.Status != models.OrderCancelled && order.ScheduledFor != nil &&
+			!order.ScheduledFor.Before(from) && order.ScheduledFor.Before(to)
+	})
+	sort.SliceStable(orders, func(i, j int) bool { return orders[i].ScheduledFor.Before(*orders[j].ScheduledFor) })
+	return orders, nil
+}
+
+// Create keeps no customers, so it does not check the order's customer, and it
+// splits no station tickets, so a released order comes back without any
+func (o *Orders) Create(ctx context.Context, order *models.Order, slotCapacity int, releaseAt *time.Time) ([]models.Ticket, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	assign(ctx, &order.LocationID)
+	if order.IsOffPremises() && order.ScheduledFor != nil && slotCapacity > 0 {
+		slot := models.PickupSlot(*order.ScheduledFor)
+		booked := 0
+		for _, existing := range o.orders {
+			if sameLocation(existing.LocationID, order.LocationID) && existing.IsOffPremises() &&
+				existing.Status != models.OrderCancelled && existing.ScheduledFor != nil &&
+				!existing.ScheduledFor.Before(slot) && existing.ScheduledFor.Before(slot.Add(models.PickupSlotLength)) {
+				booked++
+			}
+		}
+		if booked >= slotCapacity {
+			return nil, repository.ErrSlotFull
+		}
+	}
+
+	o.nextID++
+	order.ID = o.nextID
+	order.Status = models.OrderScheduled
+	order.ReleasedAt = nil
+	if releaseAt != nil {
+		released := *releaseAt
+		order.Status = models.OrderPending
+		order.ReleasedAt = &released
+	}
+	order.CreatedAt = now()
+	order.UpdatedAt = order.CreatedAt
+	for i := range order.OrderDetails {
+		detail := &order.OrderDetails[i]
+		o.nextChildID++
+		detail.ID = o.nextChildID
+		detail.OrderID = order.ID
+		for j := range detail.SelectedAddOns {
+			o.nextChildID++
+			detail.SelectedAddOns[j].ID = o.nextChildID
+			detail.SelectedAddOns[j].OrderDetailID = detail.ID
+		}
+	}
+	o.orders = append(o.orders, clone(*order))
+	return nil, nil
+}
+
+// Update keeps no cash drawers, loyalty ledger or invoices, so payments and
+// refunds go into no drawer, redeemed points are not checked, status changes
+// and refunds move no points and refunds issue no allowances
+func (o *Orders) Update(ctx context.Context, id uint, change func(order *models.Order) error) (models.Order, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i, stored := range o.orders {
+		if stored.ID != id || !visible(ctx, stored.LocationID, false) {
+			continue
+		}
+
+		order := clone(stored)
+		discounts, payments, adjustments := len(order.Discounts), len(order.Payments), len(order.Adjustments)
+		if err := change(&order); err != nil {
+			return models.Order{}, err
+		}
+
+		// Only what the Postgres store saves is kept
+		stored = clone(stored)
+		stored.Status = order.Status
+		stored.ServedAt = order.ServedAt
+		stored.CompletedAt = order.CompletedAt
+		stored.FeedbackToken = order.FeedbackToken
+		stored.TaxAmount = order.TaxAmount
+		stored.TotalAmount = order.TotalAmount
+		stored.UpdatedAt = now()
+		for j := discounts; j < len(order.Discounts); j++ {
+			discount := order.Discounts[j]
+			o.nextChildID++
+			discount.ID = o.nextChildID
+			discount.OrderID = order.ID
+			discount.RedeemedPoints = 0
+			discount.CreatedAt = stored.UpdatedAt
+			discount.UpdatedAt = stored.UpdatedAt
+			stored.Discounts = append(stored.Discounts, discount)
+		}
+		for j := payments; j < len(order.Payments); j++ {
+			payment := order.Payments[j]
+			o.nextChildID++
+			payment.ID = o.nextChildID
+			payment.OrderID = order.ID
+			payment.DrawerSessionID = nil
+			assign(ctx, &payment.LocationID)
+			payment.CreatedAt = stored.UpdatedAt
+			payment.UpdatedAt = stored.UpdatedAt
+			stored.Payments = append(stored.Payments, payment)
+		}
+		for j := adjustments; j < len(order.Adjustments); j++ {
+			adjustment := order.Adjustments[j]
+			o.nextChildID++
+			adjustment.ID = o.nextChildID
+			adjustment.OrderID = order.ID
+			adjustment.DrawerSessionID = nil
+			assign(ctx, &adjustment.LocationID)
+			adjustment.CreatedAt = stored.UpdatedAt
+			adjustment.UpdatedAt = stored.UpdatedAt
+			stored.Adjustments = append(stored.Adjustments, adjustment)
+		}
+		o.orders[i] = stored
+		return clone(stored), nil
+	}
+	return models.Order{}, repository.ErrNotFound
+}
+
+func (o *Orders) matching(ctx context.Context, match func(models.Order) bool) []models.Order {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	orders := []models.Order{}
+	for _, order := range o.orders {
+		if visible(ctx, order.LocationID, false) && match(order) {
+			orders = append(orders, clone(order))
+		}
+	}
+	return orders
+}
+
+// clone copies an order with its children, so callers cannot change what is stored
+func clone(order models.Order) models.Order {
+	details := make([]models.OrderDetail, len(order.OrderDetails))
+	for i, detail := range order.OrderDetails {
+		detail.SelectedAddOns = append([]models.SelectedAddOn{}, detail.SelectedAddOns...)
+		details[i] = detail
+	}
+	order.OrderDetails = details
+	order.Discounts = append([]models.OrderDiscount{}, order.Discounts...)
+	order.Payments = append([]models.Payment{}, order.Payments...)
+	order.Adjustments = append([]models.OrderAdjustment{}, order.Adjustments...)
+	return order
+}
+
+// releasedBefore orders like Postgres, which sorts unreleased orders last
+func releasedBefore(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a != nil && b == nil
+	}
+	return a.Before(*b)
+}
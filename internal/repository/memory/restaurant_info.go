@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type RestaurantInfo struct {
+	mu     sync.Mutex
+	nextID uint
+	infos  []models.RestaurantInfo
+}
+
+func NewRestaurantInfo() *RestaurantInfo {
+	return &RestaurantInfo{}
+}
+
+func (ri *RestaurantInfo) Get(ctx context.Context) (models.RestaurantInfo, error) {
+	return ri.latest(ctx, func(models.RestaurantInfo) bool { return true })
+}
+
+func (ri *RestaurantInfo) ForLocation(ctx context.Context, locationID *uint) (models.RestaurantInfo, error) {
+	return ri.latest(ctx, func(info models.RestaurantInfo) bool { return sameLocation(info.LocationID, locationID) })
+}
+
+// latest returns the most recently updated info visible to the caller that matches
+func (ri *RestaurantInfo) latest(ctx context.Context, match func(models.RestaurantInfo) bool) (models.RestaurantInfo, error) {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	var latest *models.RestaurantInfo
+	for i := range ri.infos {
+		info := &ri.infos[i]
+		if visible(ctx, info.LocationID, false) && match(*info) && (latest == nil || info.UpdatedAt.After(latest.UpdatedAt)) {
+			latest = info
+		}
+	}
+	if latest == nil {
+		return models.RestaurantInfo{}, repository.ErrNotFound
+	}
+	return *latest, nil
+}
+
+func (ri *RestaurantInfo) Save(ctx context.Context, info *models.RestaurantInfo) error {
+	ri.mu.Lock()
+	defer ri.mu.Unlock()
+
+	for i, existing := range ri.infos {
+		if !visible(ctx, existing.LocationID, false) {
+			continue
+		}
+		info.ID = existing.ID
+		info.CreatedAt = existing.CreatedAt
+		info.LocationID = existing.LocationID
+		info.UpdatedAt = now()
+		ri.infos[i] = *info
+		return nil
+	}
+
+	assign(ctx, &info.LocationID)
+	ri.nextID++
+	info.ID = ri.nextID
+	info.CreatedAt = now()
+	info.UpdatedAt = info.CreatedAt
+	ri.infos = append(ri.infos, *info)
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type Users struct {
+	mu     sync.Mutex
+	nextID uint
+	users  map[uint]models.User
+}
+
+func NewUsers() *Users {
+	return &Users{users: make(map[uint]models.User)}
+}
+
+func (u *Users) List(ctx context.Context) ([]models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	users := []models.User{}
+	for _, user := range u.users {
+		if visible(ctx, user.LocationID, false) {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (u *Users) Get(ctx context.Context, id uint) (models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	user, ok := u.users[id]
+	if !ok || !visible(ctx, user.LocationID, false) {
+		return models.User{}, repository.ErrNotFound
+	}
+	return user, nil
+}
+
+func (u *Users) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for _, user := range u.users {
+		if user.Username == username && visible(ctx, user.LocationID, false) {
+			return user, nil
+		}
+	}
+	return models.User{}, repository.ErrNotFound
+}
+
+func (u *Users) Create(ctx context.Context, user *models.User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.taken(user.Username, 0) {
+		return repository.ErrConflict
+	}
+	assign(ctx, &user.LocationID)
+	u.nextID++
+	user.ID = u.nextID
+	user.CreatedAt = now()
+	user.UpdatedAt = user.CreatedAt
+	u.users[user.ID] = *user
+	return nil
+}
+
+func (u *Users) Update(ctx context.Context, user *models.User) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	existing, ok := u.users[user.ID]
+	if !ok || !writable(ctx, existing.LocationID) {
+		return repository.ErrNotFound
+	}
+	if u.taken(user.Username, user.ID) {
+		return repository.ErrConflict
+	}
+	user.CreatedAt = existing.CreatedAt
+	user.UpdatedAt = now()
+	u.users[user.ID] = *user
+	return nil
+}
+
+func (u *Users) Delete(ctx context.Context, id uint) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	user, ok := u.users[id]
+	if !ok || !writable(ctx, user.LocationID) {
+		return repository.ErrNotFound
+	}
+	delete(u.users, id)
+	return nil
+}
+
+// taken reports whether another user than id has the username
+func (u *Users) taken(username string, id uint) bool {
+	for _, user := range u.users {
+		if user.Username == username && user.ID != id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type Menu struct {
+	db *database.Manager
+}
+
+func (m *Menu) ListCategories(ctx context.Context) ([]models.Category, error) {
+	var categories []models.Category
+	err := m.db.WithContext(ctx).Order("display_order").Find(&categories).Error
+	return categories, translate(err)
+}
+
+func (m *Menu) GetCategory(ctx context.Context, id uint) (models.Category, error) {
+	var category models.Category
+	err := m.db.WithContext(ctx).First(&category, id).Error
+	return category, translate(err)
+}
+
+func (m *Menu) CreateCategory(ctx context.Context, category *models.Category) error {
+	return translate(m.db.WithContext(ctx).Create(category).Error)
+}
+
+func (m *Menu) UpdateCategory(ctx context.Context, category *models.Category) error {
+	return translate(m.db.WithContext(ctx).Save(category).Error)
+}
+
+func (m *Menu) DeleteCategory(ctx context.Context, id uint, opts repository.CategoryDelete) (int, error) {
+	var count int
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var category models.Category
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&category, id).Error; err != nil {
+			return err
+		}
+
+		var menuItems []models.MenuItem
+		if err := tx.Where("category_id = ?", category.ID).Order("id").Find(&menuItems).Error; err != nil {
+			return err
+		}
+		count = len(menuItems)
+
+		if len(menuItems) > 0 {
+			itemIDs := make([]uint, len(menuItems))
+			for i, item := range menuItems {
+				itemIDs[i] = item.ID
+			}
+			if opts.MoveTo != nil || opts.Cascade {
+				// The tenant callbacks would skip items the caller cannot change, leaving them in a deleted category
+				if locked := lockedItems(ctx, menuItems); len(locked) > 0 {
+					return &repository.MenuItemsError{MenuItems: locked}
+				}
+			}
+
+			switch {
+			case opts.MoveTo != nil:
+				if err := tx.First(&models.Category{}, *opts.MoveTo).Error; err != nil {
+					if errors.Is(err, gorm.ErrRecordNotFound) {
+						return repository.ErrTargetNotFound
+					}
+					return err
+				}
+				if err := tx.Model(&models.MenuItem{}).Where("id IN ?", itemIDs).Update("category_id", *opts.MoveTo).Error; err != nil {
+					return err
+				}
+			case opts.Cascade:
+				if err := tx.Where("menu_item_id IN ?", itemIDs).Delete(&models.AddOn{}).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("id IN ?", itemIDs).Delete(&models.MenuItem{}).Error; err != nil {
+					return err
+				}
+			default:
+				return &repository.MenuItemsError{MenuItems: menuItems}
+			}
+		}
+
+		result := tx.Delete(&category)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	})
+	return count, translate(err)
+}
+
+func (m *Menu) ListItems(ctx context.Context) ([]models.MenuItem, error) {
+	var menuItems []models.MenuItem
+	if err := m.db.WithContext(ctx).Preload("AddOns").Find(&menuItems).Error; err != nil {
+		return nil, translate(err)
+	}
+	if err := m.decorate(ctx, menuItems); err != nil {
+		return nil, err
+	}
+	return menuItems, nil
+}
+
+func (m *Menu) GetItem(ctx context.Context, id uint) (models.MenuItem, error) {
+	var menuItem models.MenuItem
+	if err := m.db.WithContext(ctx).Preload("AddOns").First(&menuItem, id).Error; err != nil {
+		return menuItem, translate(err)
+	}
+
+	menuItems := []models.MenuItem{menuItem}
+	if err := m.decorate(ctx, menuItems); err != nil {
+		return menuItem, err
+	}
+	return menuItems[0], nil
+}
+
+func (m *Menu) CreateItem(ctx context.Context, item *models.MenuItem) error {
+	return translate(m.db.WithContext(ctx).Create(item).Error)
+}
+
+func (m *Menu) UpdateItem(ctx context.Context, item *models.MenuItem) error {
+	addOns := item.AddOns
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("menu_item_id = ?", item.ID).Delete(&models.AddOn{}).Error; err != nil {
+			return err
+		}
+		for i := range addOns {
+			addOns[i].ID = 0
+			addOns[i].MenuItemID = item.ID
+		}
+		if len(addOns) > 0 {
+			if err := tx.Create(&addOns).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Omit("AddOns").Save(item).Error
+	})
+	if err != nil {
+		return translate(err)
+	}
+	return translate(m.db.WithContext(ctx).Preload("AddOns").First(item, item.ID).Error)
+}
+
+func (m *Menu) DeleteItem(ctx context.Context, id uint) error {
+	return translate(m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("menu_item_id = ?", id).Delete(&models.AddOn{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.MenuItem{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return repository.ErrNotFound
+		}
+		return nil
+	}))
+}
+
+// lockedItems returns the menu items a location caller cannot change: master
+// items and those of other locations
+func lockedItems(ctx context.Context, menuItems []models.MenuItem) []models.MenuItem {
+	caller, ok := auth.LocationIDFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	var locked []models.MenuItem
+	for _, item := range menuItems {
+		if item.LocationID == nil || *item.LocationID != caller {
+			locked = append(locked, item)
+		}
+	}
+	return locked
+}
+
+// decorate prices menu items for the caller's location and adds their ratings
+func (m *Menu) decorate(ctx context.Context, menuItems []models.MenuItem) error {
+	if err := database.ApplyLocationPrices(m.db.WithContext(ctx), menuItems); err != nil {
+		return err
+	}
+	return applyRatings(m.db.WithContext(ctx), menuItems)
+}
+
+// applyRatings fills in the average guest rating of each menu item
+func applyRatings(tx *gorm.DB, menuItems []models.MenuItem) error {
+	if len(menuItems) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, len(menuItems))
+	for i, item := range menuItems {
+		ids[i] = item.ID
+	}
+
+	var summaries []struct {
+		MenuItemID uint
+		Average    float64
+		Count      int
+	}
+	err := tx.Model(&models.ItemRating{}).
+		Select("menu_item_id, AVG(rating) AS average, COUNT(*) AS count").
+		Where("menu_item_id IN ?", ids).Group("menu_item_id").Scan(&summaries).Error
+	if err != nil {
+		return err
+	}
+
+	for _, summary := range summaries {
+		for i := range menuItems {
+			if menuItems[i].ID == summary.MenuItemID {
+				menuItems[i].AverageRating = math.Round(summary.Average*10) / 10
+				menuItems[i].RatingCount = summary.Count
+			}
+		}
+	}
+	return nil
+}
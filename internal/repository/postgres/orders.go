@@ -0,0 +1,243 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/einvoice"
+	"github.com/darrenjon/restaurant-ordering-system/internal/kitchen"
+	"github.com/darrenjon/restaurant-ordering-system/internal/loyalty"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+// orderLockKey serialises order creation per location through a Postgres advisory lock
+const orderLockKey = 7231606
+
+type Orders struct {
+	db *database.Manager
+}
+
+func (o *Orders) withChildren(ctx context.Context) *gorm.DB {
+	return preloadChildren(o.db.WithContext(ctx))
+}
+
+func preloadChildren(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("OrderDetails.SelectedAddOns").Preload("Discounts").Preload("Payments").Preload("Adjustments")
+}
+
+func (o *Orders) List(ctx context.Context, filter repository.OrderFilter) ([]models.Order, error) {
+	query := o.withChildren(ctx).Order("created_at desc")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.OrderType != "" {
+		query = query.Where("order_type = ?", filter.OrderType)
+	}
+
+	var orders []models.Order
+	err := query.Find(&orders).Error
+	return orders, translate(err)
+}
+
+func (o *Orders) Get(ctx context.Context, id uint) (models.Order, error) {
+	var order models.Order
+	err := o.withChildren(ctx).First(&order, id).Error
+	return order, translate(err)
+}
+
+func (o *Orders) KitchenQueue(ctx context.Context) ([]models.Order, error) {
+	var orders []models.Order
+	err := o.db.WithContext(ctx).Preload("OrderDetails.SelectedAddOns").
+		Where("status IN ?", []string{models.OrderPending, models.OrderPreparing}).
+		Order("released_at").Find(&orders).Error
+	return orders, translate(err)
+}
+
+func (o *Orders) Scheduled(ctx context.Context, from, to time.Time) ([]models.Order, error) {
+	var orders []models.Order
+	err := o.db.WithContext(ctx).
+		Where("order_type IN ? AND status <> ? AND scheduled_for >= ? AND scheduled_for < ?",
+			[]string{models.OrderTypeTakeout, models.OrderTypeDelivery}, models.OrderCancelled, from, to).
+		Order("scheduled_for").Find(&orders).Error
+	return orders, translate(err)
+}
+
+func (o *Orders) Stats(ctx context.Context, servedSince time.Time) (repository.OrderStats, error) {
+	var counts []struct {
+		Status string
+		Count  int
+	}
+	err := o.db.WithContext(ctx).Model(&models.Order{}).
+		Select("status, count(*) AS count").Group("status").Scan(&counts).Error
+	if err != nil {
+		return repository.OrderStats{}, translate(err)
+	}
+
+	var served struct {
+		Count   int
+		Seconds *float64
+	}
+	err = o.db.WithContext(ctx).Model(&models.Order{}).
+		Select("count(*) AS count, avg(extract(epoch FROM served_at - created_at)) AS seconds").
+		Where("served_at >= ?", servedSince).Scan(&served).Error
+	if err != nil {
+		return repository.OrderStats{}, translate(err)
+	}
+
+	stats := repository.OrderStats{ByStatus: make(map[string]int), Served: served.Count}
+	for _, count := range counts {
+		stats.ByStatus[count.Status] = count.Count
+	}
+	if served.Seconds != nil {
+		stats.AverageTicketTime = time.Duration(*served.Seconds * float64(time.Second))
+	}
+	return stats, nil
+}
+
+func (o *Orders) Create(ctx context.Context, order *models.Order, slotCapacity int, releaseAt *time.Time) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	err := o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var lockLocation uint
+		if order.LocationID != nil {
+			lockLocation = *order.LocationID
+		}
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", orderLockKey, lockLocation).Error; err != nil {
+			return err
+		}
+
+		if order.CustomerID != nil {
+			if err := tx.First(&models.Customer{}, *order.CustomerID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return repository.ErrCustomerNotFound
+				}
+				return err
+			}
+		}
+		if order.IsOffPremises() && order.ScheduledFor != nil && slotCapacity > 0 {
+			slot := models.PickupSlot(*order.ScheduledFor)
+			var count int64
+			err := database.AtLocation(tx.Model(&models.Order{}), order.LocationID).
+				Where("order_type IN ? AND status <> ? AND scheduled_for >= ? AND scheduled_for < ?",
+					[]string{models.OrderTypeTakeout, models.OrderTypeDelivery}, models.OrderCancelled, slot, slot.Add(models.PickupSlotLength)).
+				Count(&count).Error
+			if err != nil {
+				return err
+			}
+			if count >= int64(slotCapacity) {
+				return repository.ErrSlotFull
+			}
+		}
+
+		order.ID = 0
+		order.Status = models.OrderScheduled
+		order.ReleasedAt = nil
+		if err := tx.Create(order).Error; err != nil {
+			return err
+		}
+		if releaseAt != nil {
+			var err error
+			tickets, err = kitchen.Release(tx, order, *releaseAt)
+			return err
+		}
+		return nil
+	})
+	return tickets, translate(err)
+}
+
+func (o *Orders) Update(ctx context.Context, id uint, change func(order *models.Order) error) (models.Order, error) {
+	var order models.Order
+	err := o.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := preloadChildren(tx.Clauses(clause.Locking{Strength: "UPDATE"})).First(&order, id).Error; err != nil {
+			return err
+		}
+
+		status, discounts, payments, adjustments := order.Status, len(order.Discounts), len(order.Payments), len(order.Adjustments)
+		if err := change(&order); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := discounts; i < len(order.Discounts); i++ {
+			discount := &order.Discounts[i]
+			discount.ID = 0
+			discount.OrderID = order.ID
+			if discount.RedeemedPoints > 0 {
+				if order.CustomerID == nil {
+					return errors.New("points redeemed on an order without a customer")
+				}
+				if err := loyalty.Redeem(tx, *order.CustomerID, order.ID, discount.RedeemedPoints, now); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(discount).Error; err != nil {
+				return err
+			}
+		}
+		for i := payments; i < len(order.Payments); i++ {
+			payment := &order.Payments[i]
+			payment.ID = 0
+			payment.OrderID = order.ID
+			payment.DrawerSessionID = nil
+			if payment.Method == models.PaymentCash {
+				payment.DrawerSessionID = database.CashDrawerID(tx)
+			}
+			if err := tx.Create(payment).Error; err != nil {
+				return err
+			}
+		}
+		var refunds []models.OrderAdjustment
+		for i := adjustments; i < len(order.Adjustments); i++ {
+			adjustment := &order.Adjustments[i]
+			adjustment.ID = 0
+			adjustment.OrderID = order.ID
+			adjustment.DrawerSessionID = nil
+			if adjustment.Type == models.AdjustmentRefund && adjustment.Method == models.PaymentCash {
+				adjustment.DrawerSessionID = database.CashDrawerID(tx)
+			}
+			if err := tx.Create(adjustment).Error; err != nil {
+				return err
+			}
+			if adjustment.Type == models.AdjustmentRefund {
+				refunds = append(refunds, *adjustment)
+			}
+		}
+		err := tx.Model(&order).Select("Status", "ServedAt", "CompletedAt", "FeedbackToken", "TaxAmount", "TotalAmount").Updates(&order).Error
+		if err != nil {
+			return err
+		}
+
+		for _, refund := range refunds {
+			if err := einvoice.RefundAllowance(tx, refund, now); err != nil {
+				return err
+			}
+		}
+
+		closed := order.Status != status && (order.Status == models.OrderCompleted || order.Status == models.OrderCancelled)
+		if order.CustomerID == nil || !closed && len(refunds) == 0 {
+			return nil
+		}
+		info, err := database.RestaurantInfoFor(tx, order.LocationID)
+		if err != nil {
+			return err
+		}
+		if len(refunds) > 0 {
+			if err := loyalty.Reverse(tx, order, info.LoyaltySettings, now); err != nil {
+				return err
+			}
+		}
+		if !closed {
+			return nil
+		}
+		if order.Status == models.OrderCompleted {
+			return loyalty.Earn(tx, order, info.LoyaltySettings, now)
+		}
+		return loyalty.Restore(tx, order, info.LoyaltySettings, now)
+	})
+	return order, translate(err)
+}
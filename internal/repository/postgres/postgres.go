@@ -0,0 +1,38 @@
+// Package postgres implements the repositories on the gorm connection of a
+// database.Manager, relying on its tenant callbacks for location scoping
+package postgres
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+// uniqueViolation is the Postgres error code for a duplicate key
+const uniqueViolation = "23505"
+
+// NewStore returns the Postgres repositories
+func NewStore(db *database.Manager) repository.Store {
+	return repository.Store{
+		Users:          &Users{db: db},
+		Menu:           &Menu{db: db},
+		Orders:         &Orders{db: db},
+		RestaurantInfo: &RestaurantInfo{db: db},
+	}
+}
+
+// translate maps gorm and Postgres errors onto the repository errors
+func translate(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repository.ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Join(repository.ErrConflict, err)
+	}
+	return err
+}
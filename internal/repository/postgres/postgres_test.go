@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/migrations"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository/repositorytest"
+)
+
+// TestStore runs the repository suite against the database named by TEST_DB_NAME,
+// connecting with the usual DB_* settings. Every table but the audit log is
+// emptied before each test, so never point it at a database you want to keep.
+func TestStore(t *testing.T) {
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	db, err := database.NewManager(&config.DatabaseConfig{
+		Host:     getenv("DB_HOST", "localhost"),
+		Port:     getenv("DB_PORT", "5432"),
+		User:     getenv("DB_USER", "postgres"),
+		Password: os.Getenv("DB_PASSWORD"),
+		DBName:   name,
+		SSLMode:  getenv("DB_SSLMODE", "disable"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrator, err := migrations.New(db.GetDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(0); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+
+	var tables []string
+	err = db.GetDB().Raw(`SELECT tablename FROM pg_tables WHERE schemaname = current_schema()
+		AND tablename NOT IN ('audit_logs', 'schema_migrations')`).Scan(&tables).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var locations int
+	repositorytest.Run(t,
+		func(t *testing.T) repository.Store {
+			if err := db.GetDB().Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+				t.Fatalf("emptying the test database: %v", err)
+			}
+			return NewStore(db)
+		},
+		func(t *testing.T) uint {
+			locations++
+			location := models.Location{Name: fmt.Sprintf("Test %d", locations), Code: fmt.Sprintf("T%d", locations)}
+			if err := db.GetDB().Create(&location).Error; err != nil {
+				t.Fatalf("creating a location: %v", err)
+			}
+			return location.ID
+		})
+}
+
+func getenv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
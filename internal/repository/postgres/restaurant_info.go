@@ -0,0 +1,43 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+type RestaurantInfo struct {
+	db *database.Manager
+}
+
+func (ri *RestaurantInfo) Get(ctx context.Context) (models.RestaurantInfo, error) {
+	var info models.RestaurantInfo
+	err := ri.db.WithContext(ctx).Order("updated_at desc").First(&info).Error
+	return info, translate(err)
+}
+
+func (ri *RestaurantInfo) ForLocation(ctx context.Context, locationID *uint) (models.RestaurantInfo, error) {
+	info, err := database.RestaurantInfoFor(ri.db.WithContext(ctx), locationID)
+	return info, translate(err)
+}
+
+func (ri *RestaurantInfo) Save(ctx context.Context, info *models.RestaurantInfo) error {
+	var existing models.RestaurantInfo
+	err := ri.db.WithContext(ctx).First(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		info.ID = 0
+		return translate(ri.db.WithContext(ctx).Create(info).Error)
+	}
+	if err != nil {
+		return err
+	}
+
+	info.ID = existing.ID
+	info.CreatedAt = existing.CreatedAt
+	info.LocationID = existing.LocationID
+	return translate(ri.db.WithContext(ctx).Save(info).Error)
+}
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/database"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+type Users struct {
+	db *database.Manager
+}
+
+func (u *Users) List(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	err := u.db.WithContext(ctx).Find(&users).Error
+	return users, translate(err)
+}
+
+func (u *Users) Get(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	err := u.db.WithContext(ctx).First(&user, id).Error
+	return user, translate(err)
+}
+
+func (u *Users) GetByUsername(ctx context.Context, username string) (models.User, error) {
+	var user models.User
+	err := u.db.WithContext(ctx).Where("username = ?", username).First(&user).Error
+	return user, translate(err)
+}
+
+func (u *Users) Create(ctx context.Context, user *models.User) error {
+	return translate(u.db.WithContext(ctx).Create(user).Error)
+}
+
+func (u *Users) Update(ctx context.Context, user *models.User) error {
+	return translate(u.db.WithContext(ctx).Save(user).Error)
+}
+
+func (u *Users) Delete(ctx context.Context, id uint) error {
+	result := u.db.WithContext(ctx).Delete(&models.User{}, id)
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+// Package repository defines the storage used by the user, menu, order and
+// restaurant info handlers. The postgres package implements it for production
+// and the memory package for tests that run without a database.
+//
+// Every method scopes its work to the location in ctx, as the Postgres tenant
+// callbacks do: a location sees its own rows and the master data, and writes
+// only its own rows.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+)
+
+var (
+	ErrNotFound = errors.New("record not found")
+	// ErrConflict is returned when a write would break a unique constraint
+	ErrConflict = errors.New("record already exists")
+	// ErrTargetNotFound is returned when menu items are moved to a missing category
+	ErrTargetNotFound = errors.New("target category not found")
+	// ErrSlotFull is returned when an order's pickup slot has no capacity left
+	ErrSlotFull = errors.New("the requested pickup slot is full")
+	// ErrCustomerNotFound is returned when an order names a missing customer
+	ErrCustomerNotFound = errors.New("customer not found")
+)
+
+// MenuItemsError is returned when a category cannot be deleted while it still
+// has menu items
+type MenuItemsError struct {
+	MenuItems []models.MenuItem
+}
+
+func (e *MenuItemsError) Error() string {
+	return "category still has menu items"
+}
+
+// Store groups the repositories the handlers depend on
+type Store struct {
+	Users          Users
+	Menu           Menu
+	Orders         Orders
+	RestaurantInfo RestaurantInfo
+}
+
+type Users interface {
+	List(ctx context.Context) ([]models.User, error)
+	Get(ctx context.Context, id uint) (models.User, error)
+	GetByUsername(ctx context.Context, username string) (models.User, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User) error
+	Delete(ctx context.Context, id uint) error
+}
+
+// CategoryDelete says what happens to the menu items of a deleted category.
+// With neither set, a category with items is not deleted.
+type CategoryDelete struct {
+	// MoveTo is the category the items are moved to
+	MoveTo *uint
+	// Cascade deletes the items with their add-ons
+	Cascade bool
+}
+
+// Menu stores categories and menu items. Menu items are returned with their
+// add-ons, priced for the caller's location and with their guest ratings.
+// Menu items the caller cannot change, such as master items in a location's
+// category, keep their category from being deleted.
+type Menu interface {
+	ListCategories(ctx context.Context) ([]models.Category, error)
+	GetCategory(ctx context.Context, id uint) (models.Category, error)
+	CreateCategory(ctx context.Context, category *models.Category) error
+	UpdateCategory(ctx context.Context, category *models.Category) error
+	// DeleteCategory returns how many menu items were moved or deleted, or a
+	// *MenuItemsError when the category still has items
+	DeleteCategory(ctx context.Context, id uint, opts CategoryDelete) (int, error)
+
+	ListItems(ctx context.Context) ([]models.MenuItem, error)
+	GetItem(ctx context.Context, id uint) (models.MenuItem, error)
+	CreateItem(ctx context.Context, item *models.MenuItem) error
+	// UpdateItem saves the item and replaces its add-ons with item.AddOns
+	UpdateItem(ctx context.Context, item *models.MenuItem) error
+	// DeleteItem deletes the item with its add-ons
+	DeleteItem(ctx context.Context, id uint) error
+}
+
+type OrderFilter struct {
+	Status    string
+	OrderType string
+}
+
+// Orders stores orders with their details, discounts, payments and adjustments
+type Orders interface {
+	// List returns matching orders, newest first
+	List(ctx context.Context, filter OrderFilter) ([]models.Order, error)
+	Get(ctx context.Context, id uint) (models.Order, error)
+	// KitchenQueue returns the pending and preparing orders, oldest release first
+	KitchenQueue(ctx context.Context) ([]models.Order, error)
+	// Scheduled returns the takeout and delivery orders, other than cancelled
+	// ones, scheduled in [from, to)
+	Scheduled(ctx context.Context, from, to time.Time) ([]models.Order, error)
+	// Stats counts orders by status and averages the ticket time of orders served since servedSince
+	Stats(ctx context.Context, servedSince time.Time) (OrderStats, error)
+
+	// Create saves a new, already priced order as scheduled. An off-premises order
+	// is refused with ErrSlotFull when its pickup slot already holds slotCapacity
+	// orders of its location, unless slotCapacity is 0; orders at one location are
+	// created one at a time, so the count holds until the order is saved. With
+	// releaseAt set the order goes straight to the kitchen and its station tickets
+	// are returned.
+	Create(ctx context.Context, order *models.Order, slotCapacity int, releaseAt *time.Time) ([]models.Ticket, error)
+	// Update locks the order against other updates and passes it to change. Unless
+	// change fails, it saves the order's status, served and completed times,
+	// feedback token and totals, and the discounts, payments and adjustments
+	// change appended. Cash payments and refunds go through the signed-in
+	// cashier's open drawer. A discount's RedeemedPoints are taken off the
+	// customer, failing with loyalty.ErrNotEnoughPoints when they have too few.
+	// Completing an order credits its customer with loyalty points, cancelling
+	// one gives back the points redeemed on it, and a refund takes back points
+	// and issues an allowance against the order's invoice.
+	Update(ctx context.Context, id uint, change func(order *models.Order) error) (models.Order, error)
+}
+
+// OrderStats summarises orders for monitoring
+type OrderStats struct {
+	ByStatus map[string]int
+	// Served is the number of orders AverageTicketTime covers
+	Served int
+	// AverageTicketTime is the mean time from an order being placed to it being served
+	AverageTicketTime time.Duration
+}
+
+type RestaurantInfo interface {
+	// Get returns the caller's most recently updated restaurant info
+	Get(ctx context.Context) (models.RestaurantInfo, error)
+	// ForLocation returns the restaurant info of a location, or of the whole
+	// business when locationID is nil
+	ForLocation(ctx context.Context, locationID *uint) (models.RestaurantInfo, error)
+	// Save replaces the caller's restaurant info, creating it the first time
+	Save(ctx context.Context, info *models.RestaurantInfo) error
+}
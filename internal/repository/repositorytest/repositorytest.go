@@ -0,0 +1,433 @@
+// Package repositorytest checks that a repository.Store behaves the way the
+// handlers rely on, so the Postgres and in-memory stores can run the same suite
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/auth"
+	"github.com/darrenjon/restaurant-ordering-system/internal/models"
+	"github.com/darrenjon/restaurant-ordering-system/internal/repository"
+)
+
+// Run runs the suite. newStore returns an empty store and newLocation adds a
+// location to it, returning the location's ID.
+func Run(t *testing.T, newStore func(t *testing.T) repository.Store, newLocation func(t *testing.T) uint) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint)
+	}{
+		{"Users", testUsers},
+		{"UsersByLocation", testUsersByLocation},
+		{"CategoryNames", testCategoryNames},
+		{"DeleteCategory", testDeleteCategory},
+		{"DeleteCategoryLockedItems", testDeleteCategoryLockedItems},
+		{"CreateOrder", testCreateOrder},
+		{"ReleaseOrder", testReleaseOrder},
+		{"SlotCapacity", testSlotCapacity},
+		{"UpdateOrder", testUpdateOrder},
+		{"UpdateOrderFails", testUpdateOrderFails},
+		{"RestaurantInfo", testRestaurantInfo},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newStore(t), newLocation)
+		})
+	}
+}
+
+func testUsers(t *testing.T, store repository.Store, _ func(t *testing.T) uint) {
+	ctx := context.Background()
+	user := models.User{Username: "amy", Password: "hash", Name: "Amy", Role: "staff"}
+	if err := store.Users.Create(ctx, &user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if user.ID == 0 {
+		t.Fatal("Create did not set the user ID")
+	}
+
+	got, err := store.Users.GetByUsername(ctx, "amy")
+	if err != nil || got.ID != user.ID {
+		t.Fatalf("GetByUsername = %d, %v; want %d", got.ID, err, user.ID)
+	}
+	if _, err := store.Users.GetByUsername(ctx, "bob"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUsername of a missing user = %v, want ErrNotFound", err)
+	}
+
+	duplicate := models.User{Username: "amy", Password: "hash", Name: "Other Amy", Role: "staff"}
+	if err := store.Users.Create(ctx, &duplicate); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("Create with a taken username = %v, want ErrConflict", err)
+	}
+
+	if err := store.Users.Delete(ctx, user.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Users.Get(ctx, user.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get of a deleted user = %v, want ErrNotFound", err)
+	}
+}
+
+func testUsersByLocation(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	first := auth.WithLocationID(context.Background(), newLocation(t))
+	second := auth.WithLocationID(context.Background(), newLocation(t))
+
+	user := models.User{Username: "amy", Password: "hash", Name: "Amy", Role: "staff"}
+	if err := store.Users.Create(first, &user); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.Users.Get(second, user.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get from another location = %v, want ErrNotFound", err)
+	}
+	if _, err := store.Users.GetByUsername(second, "amy"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetByUsername from another location = %v, want ErrNotFound", err)
+	}
+	if users, err := store.Users.List(second); err != nil || len(users) != 0 {
+		t.Errorf("List from another location = %d users, %v; want none", len(users), err)
+	}
+}
+
+func testCategoryNames(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	ctx := context.Background()
+	drinks := createCategory(t, ctx, store, "Drinks")
+	desserts := createCategory(t, ctx, store, "Desserts")
+
+	if err := store.Menu.CreateCategory(ctx, &models.Category{Name: "Drinks"}); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("CreateCategory with a taken name = %v, want ErrConflict", err)
+	}
+	desserts.Name = drinks.Name
+	if err := store.Menu.UpdateCategory(ctx, &desserts); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("UpdateCategory to a taken name = %v, want ErrConflict", err)
+	}
+
+	// Locations may shadow a master category and reuse each other's names
+	first := auth.WithLocationID(context.Background(), newLocation(t))
+	second := auth.WithLocationID(context.Background(), newLocation(t))
+	createCategory(t, first, store, "Drinks")
+	createCategory(t, second, store, "Drinks")
+	if err := store.Menu.CreateCategory(first, &models.Category{Name: "Drinks"}); !errors.Is(err, repository.ErrConflict) {
+		t.Errorf("CreateCategory with a name taken at the location = %v, want ErrConflict", err)
+	}
+}
+
+func testDeleteCategory(t *testing.T, store repository.Store, _ func(t *testing.T) uint) {
+	ctx := context.Background()
+	drinks := createCategory(t, ctx, store, "Drinks")
+	mains := createCategory(t, ctx, store, "Mains")
+	tea := createItem(t, ctx, store, drinks.ID, "Tea", 60)
+	coffee := createItem(t, ctx, store, drinks.ID, "Coffee", 80)
+
+	var itemsErr *repository.MenuItemsError
+	if _, err := store.Menu.DeleteCategory(ctx, drinks.ID, repository.CategoryDelete{}); !errors.As(err, &itemsErr) {
+		t.Fatalf("DeleteCategory with items = %v, want a MenuItemsError", err)
+	}
+	if len(itemsErr.MenuItems) != 2 || itemsErr.MenuItems[0].ID != tea.ID || itemsErr.MenuItems[1].ID != coffee.ID {
+		t.Errorf("MenuItemsError lists %v, want tea and coffee", itemsErr.MenuItems)
+	}
+
+	missing := mains.ID + 1000
+	if _, err := store.Menu.DeleteCategory(ctx, drinks.ID, repository.CategoryDelete{MoveTo: &missing}); !errors.Is(err, repository.ErrTargetNotFound) {
+		t.Errorf("DeleteCategory moving to a missing category = %v, want ErrTargetNotFound", err)
+	}
+
+	count, err := store.Menu.DeleteCategory(ctx, drinks.ID, repository.CategoryDelete{MoveTo: &mains.ID})
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteCategory moving items = %d, %v; want 2", count, err)
+	}
+	if _, err := store.Menu.GetCategory(ctx, drinks.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetCategory of a deleted category = %v, want ErrNotFound", err)
+	}
+	if item, err := store.Menu.GetItem(ctx, tea.ID); err != nil || item.CategoryID != mains.ID {
+		t.Errorf("moved item is in category %d, %v; want %d", item.CategoryID, err, mains.ID)
+	}
+
+	count, err = store.Menu.DeleteCategory(ctx, mains.ID, repository.CategoryDelete{Cascade: true})
+	if err != nil || count != 2 {
+		t.Fatalf("DeleteCategory cascading = %d, %v; want 2", count, err)
+	}
+	if _, err := store.Menu.GetItem(ctx, coffee.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("GetItem after cascading = %v, want ErrNotFound", err)
+	}
+}
+
+func testDeleteCategoryLockedItems(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	headOffice := context.Background()
+	location := auth.WithLocationID(context.Background(), newLocation(t))
+	specials := createCategory(t, location, store, "Specials")
+	soups := createCategory(t, location, store, "Soups")
+	master := createItem(t, headOffice, store, specials.ID, "House Tea", 50)
+	own := createItem(t, location, store, specials.ID, "Daily Soup", 90)
+
+	for _, opts := range []repository.CategoryDelete{{Cascade: true}, {MoveTo: &soups.ID}} {
+		var itemsErr *repository.MenuItemsError
+		if _, err := store.Menu.DeleteCategory(location, specials.ID, opts); !errors.As(err, &itemsErr) {
+			t.Fatalf("DeleteCategory(%+v) with a master item = %v, want a MenuItemsError", opts, err)
+		}
+		if len(itemsErr.MenuItems) != 1 || itemsErr.MenuItems[0].ID != master.ID {
+			t.Errorf("MenuItemsError lists %v, want only the master item", itemsErr.MenuItems)
+		}
+	}
+	for _, id := range []uint{master.ID, own.ID} {
+		if item, err := store.Menu.GetItem(location, id); err != nil || item.CategoryID != specials.ID {
+			t.Errorf("item %d is in category %d, %v; want it left in %d", id, item.CategoryID, err, specials.ID)
+		}
+	}
+}
+
+func testCreateOrder(t *testing.T, store repository.Store, _ func(t *testing.T) uint) {
+	ctx := context.Background()
+	order := newOrder(t, ctx, store, models.OrderTypeDineIn, nil)
+	tickets, err := store.Orders.Create(ctx, &order, 0, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(tickets) != 0 {
+		t.Errorf("Create without release returned %d tickets", len(tickets))
+	}
+	if order.ID == 0 || order.Status != models.OrderScheduled {
+		t.Errorf("created order has ID %d and status %q, want an ID and %q", order.ID, order.Status, models.OrderScheduled)
+	}
+
+	got, err := store.Orders.Get(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.OrderDetails) != 1 || got.OrderDetails[0].Quantity != 2 || got.OrderDetails[0].ID == 0 {
+		t.Errorf("stored order details = %+v, want one numbered line of 2", got.OrderDetails)
+	}
+	if got.TotalAmount != order.TotalAmount {
+		t.Errorf("stored total = %v, want %v", got.TotalAmount, order.TotalAmount)
+	}
+}
+
+func testReleaseOrder(t *testing.T, store repository.Store, _ func(t *testing.T) uint) {
+	ctx := context.Background()
+	order := newOrder(t, ctx, store, models.OrderTypeDineIn, nil)
+	releaseAt := time.Now().Truncate(time.Second)
+	if _, err := store.Orders.Create(ctx, &order, 0, &releaseAt); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	got, err := store.Orders.Get(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != models.OrderPending || got.ReleasedAt == nil || !got.ReleasedAt.Equal(releaseAt) {
+		t.Errorf("released order has status %q at %v, want %q at %v", got.Status, got.ReleasedAt, models.OrderPending, releaseAt)
+	}
+	if queue, err := store.Orders.KitchenQueue(ctx); err != nil || len(queue) != 1 || queue[0].ID != order.ID {
+		t.Errorf("KitchenQueue = %d orders, %v; want the released order", len(queue), err)
+	}
+}
+
+func testSlotCapacity(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	first := auth.WithLocationID(context.Background(), newLocation(t))
+	second := auth.WithLocationID(context.Background(), newLocation(t))
+	pickup := models.PickupSlot(time.Now().Add(24 * time.Hour)).Add(time.Minute)
+
+	order := newOrder(t, first, store, models.OrderTypeTakeout, &pickup)
+	if _, err := store.Orders.Create(first, &order, 1, nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	full := newOrder(t, first, store, models.OrderTypeTakeout, &pickup)
+	if _, err := store.Orders.Create(first, &full, 1, nil); !errors.Is(err, repository.ErrSlotFull) {
+		t.Errorf("Create in a full slot = %v, want ErrSlotFull", err)
+	}
+	dineIn := newOrder(t, first, store, models.OrderTypeDineIn, nil)
+	if _, err := store.Orders.Create(first, &dineIn, 1, nil); err != nil {
+		t.Errorf("Create of a dine-in order = %v, want no slot check", err)
+	}
+	unlimited := newOrder(t, first, store, models.OrderTypeTakeout, &pickup)
+	if _, err := store.Orders.Create(first, &unlimited, 0, nil); err != nil {
+		t.Errorf("Create without a slot capacity = %v", err)
+	}
+
+	other := newOrder(t, second, store, models.OrderTypeTakeout, &pickup)
+	if _, err := store.Orders.Create(second, &other, 1, nil); err != nil {
+		t.Errorf("Create at another location = %v, want its own slot", err)
+	}
+}
+
+func testUpdateOrder(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	ctx := auth.WithLocationID(context.Background(), newLocation(t))
+	order := newOrder(t, ctx, store, models.OrderTypeDineIn, nil)
+	if _, err := store.Orders.Create(ctx, &order, 0, nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	servedAt := time.Now().Truncate(time.Second)
+	token := fmt.Sprintf("feedback-%d", order.ID)
+	updated, err := store.Orders.Update(ctx, order.ID, func(order *models.Order) error {
+		order.Status = models.OrderServed
+		order.ServedAt = &servedAt
+		order.FeedbackToken = &token
+		order.Discounts = append(order.Discounts, models.OrderDiscount{Description: "Staff", Amount: 20})
+		order.Payments = append(order.Payments, models.Payment{Method: models.PaymentCard, Amount: 100})
+		order.Adjustments = append(order.Adjustments, models.OrderAdjustment{
+			Type:          models.AdjustmentRefund,
+			OrderDetailID: &order.OrderDetails[0].ID,
+			Quantity:      1,
+			Amount:        10,
+			ReasonCode:    models.ReasonWrongItem,
+			Method:        models.PaymentCard,
+		})
+		order.TotalAmount -= 20
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if len(updated.Payments) != 1 || updated.Payments[0].ID == 0 || updated.Payments[0].LocationID == nil {
+		t.Errorf("Update returned payments %+v, want one saved at the caller's location", updated.Payments)
+	}
+
+	got, err := store.Orders.Get(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != models.OrderServed || got.ServedAt == nil || !got.ServedAt.Equal(servedAt) {
+		t.Errorf("stored order has status %q served at %v, want %q at %v", got.Status, got.ServedAt, models.OrderServed, servedAt)
+	}
+	if got.TotalAmount != order.TotalAmount-20 {
+		t.Errorf("stored total = %v, want %v", got.TotalAmount, order.TotalAmount-20)
+	}
+	if len(got.Discounts) != 1 || got.Discounts[0].Amount != 20 {
+		t.Errorf("stored discounts = %+v, want one of 20", got.Discounts)
+	}
+	if len(got.Payments) != 1 || got.Payments[0].Amount != 100 {
+		t.Errorf("stored payments = %+v, want one of 100", got.Payments)
+	}
+	if len(got.Adjustments) != 1 || got.Adjustments[0].ID == 0 || got.Adjustments[0].Amount != 10 || got.RefundedAmount() != 10 {
+		t.Errorf("stored adjustments = %+v, want a refund of 10", got.Adjustments)
+	}
+	if got.FeedbackToken == nil || *got.FeedbackToken != token {
+		t.Errorf("stored feedback token = %v, want %q", got.FeedbackToken, token)
+	}
+
+	other := auth.WithLocationID(context.Background(), newLocation(t))
+	_, err = store.Orders.Update(other, order.ID, func(*models.Order) error { return nil })
+	if !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update from another location = %v, want ErrNotFound", err)
+	}
+}
+
+func testUpdateOrderFails(t *testing.T, store repository.Store, _ func(t *testing.T) uint) {
+	ctx := context.Background()
+	order := newOrder(t, ctx, store, models.OrderTypeDineIn, nil)
+	if _, err := store.Orders.Create(ctx, &order, 0, nil); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	refused := errors.New("refused")
+	_, err := store.Orders.Update(ctx, order.ID, func(order *models.Order) error {
+		order.Status = models.OrderCancelled
+		order.Payments = append(order.Payments, models.Payment{Method: models.PaymentCard, Amount: 100})
+		return refused
+	})
+	if !errors.Is(err, refused) {
+		t.Fatalf("Update = %v, want the change's error", err)
+	}
+
+	got, err := store.Orders.Get(ctx, order.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Status != models.OrderScheduled || len(got.Payments) != 0 {
+		t.Errorf("failed update left status %q and %d payments, want nothing saved", got.Status, len(got.Payments))
+	}
+
+	if _, err := store.Orders.Update(ctx, order.ID+1000, func(*models.Order) error { return nil }); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Update of a missing order = %v, want ErrNotFound", err)
+	}
+}
+
+func testRestaurantInfo(t *testing.T, store repository.Store, newLocation func(t *testing.T) uint) {
+	headOffice := context.Background()
+	locationID := newLocation(t)
+	location := auth.WithLocationID(context.Background(), locationID)
+
+	if _, err := store.RestaurantInfo.Get(location); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("Get before saving = %v, want ErrNotFound", err)
+	}
+
+	business := models.RestaurantInfo{Name: "Head Office", SlotCapacity: 5}
+	if err := store.RestaurantInfo.Save(headOffice, &business); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	branch := models.RestaurantInfo{Name: "Branch", SlotCapacity: 3}
+	if err := store.RestaurantInfo.Save(location, &branch); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	branch.SlotCapacity = 4
+	id := branch.ID
+	if err := store.RestaurantInfo.Save(location, &branch); err != nil {
+		t.Fatalf("Save again: %v", err)
+	}
+	if branch.ID != id {
+		t.Errorf("saving again created info %d, want %d replaced", branch.ID, id)
+	}
+
+	if got, err := store.RestaurantInfo.Get(location); err != nil || got.Name != "Branch" || got.SlotCapacity != 4 {
+		t.Errorf("Get = %q with capacity %d, %v; want Branch with 4", got.Name, got.SlotCapacity, err)
+	}
+	if got, err := store.RestaurantInfo.ForLocation(headOffice, &locationID); err != nil || got.ID != branch.ID {
+		t.Errorf("ForLocation(location) = %d, %v; want %d", got.ID, err, branch.ID)
+	}
+	if got, err := store.RestaurantInfo.ForLocation(headOffice, nil); err != nil || got.ID != business.ID {
+		t.Errorf("ForLocation(nil) = %d, %v; want %d", got.ID, err, business.ID)
+	}
+}
+
+func createCategory(t *testing.T, ctx context.Context, store repository.Store, name string) models.Category {
+	t.Helper()
+	category := models.Category{Name: name}
+	if err := store.Menu.CreateCategory(ctx, &category); err != nil {
+		t.Fatalf("CreateCategory(%q): %v", name, err)
+	}
+	return category
+}
+
+func createItem(t *testing.T, ctx context.Context, store repository.Store, categoryID uint, name string, price float64) models.MenuItem {
+	t.Helper()
+	item := models.MenuItem{CategoryID: categoryID, Name: name, Price: price, IsAvailable: true}
+	if err := store.Menu.CreateItem(ctx, &item); err != nil {
+		t.Fatalf("CreateItem(%q): %v", name, err)
+	}
+	return item
+}
+
+// newOrder returns an unsaved, priced order for two of a new menu item at the
+// location in ctx
+func newOrder(t *testing.T, ctx context.Context, store repository.Store, orderType string, scheduledFor *time.Time) models.Order {
+	t.Helper()
+	// The item goes in a master category, which every location can see
+	categories, err := store.Menu.ListCategories(context.Background())
+	if err != nil {
+		t.Fatalf("ListCategories: %v", err)
+	}
+	var category models.Category
+	if len(categories) > 0 {
+		category = categories[0]
+	} else {
+		category = createCategory(t, context.Background(), store, "Orders")
+	}
+	item := createItem(t, ctx, store, category.ID, "Noodles", 120)
+
+	return models.Order{
+		OrderType:    orderType,
+		TableNumber:  "A1",
+		ScheduledFor: scheduledFor,
+		OrderDetails: []models.OrderDetail{{
+			MenuItemID:   item.ID,
+			MenuItemName: item.Name,
+			Quantity:     2,
+			UnitPrice:    item.Price,
+			Subtotal:     2 * item.Price,
+		}},
+		TotalAmount: 2 * item.Price,
+	}
+}
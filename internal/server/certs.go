@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+)
+
+// CertReloader serves a certificate pair from disk and picks up renewed files
+// without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertReloader loads the pair once, failing if it cannot be used
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	c := &CertReloader{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the pair again. The current certificate is kept if the new one is unusable.
+func (c *CertReloader) Reload() error {
+	modTime, err := c.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS certificate: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+// GetCertificate is used as tls.Config.GetCertificate
+func (c *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// Watch reloads the pair when either file changes, checking every interval,
+// and whenever reload receives, until ctx is cancelled
+func (c *CertReloader) Watch(ctx context.Context, interval time.Duration, reload <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			modTime, err := c.latestModTime()
+			c.mu.RLock()
+			changed := err == nil && modTime.After(c.modTime)
+			c.mu.RUnlock()
+			if !changed {
+				continue
+			}
+		case <-reload:
+		}
+
+		if err := c.Reload(); err != nil {
+			logger.ErrorLogger.Printf("Failed to reload TLS certificate, keeping the current one: %v", err)
+			continue
+		}
+		logger.InfoLogger.Printf("Reloaded TLS certificate from %s", c.certFile)
+	}
+}
+
+func (c *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, path := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("reading TLS certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
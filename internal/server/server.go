@@ -0,0 +1,85 @@
+// Package server runs the API's http.Server until the process is told to stop
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+	"github.com/darrenjon/restaurant-ordering-system/internal/logger"
+)
+
+// certCheckInterval is how often the certificate files are checked for renewal
+const certCheckInterval = time.Minute
+
+// New returns a server for handler with the configured address and timeouts
+func New(cfg config.ServerConfig, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
+// Run serves until ctx is cancelled, keeps serving for cfg.ShutdownDelay so
+// load balancers notice the API is no longer ready, then stops accepting
+// connections and waits up to cfg.ShutdownTimeout for requests in flight to
+// finish. Anything registered with srv.RegisterOnShutdown, such as closing
+// event streams, runs as shutdown starts. reload, when not nil, signals that
+// the TLS certificate should be read again.
+func Run(ctx context.Context, srv *http.Server, cfg config.ServerConfig, reload <-chan struct{}) error {
+	serveErr := make(chan error, 1)
+
+	if cfg.TLSCertFile != "" {
+		certs, err := NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return err
+		}
+		go certs.Watch(ctx, certCheckInterval, reload)
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+
+		logger.InfoLogger.Printf("Starting server on %s with TLS", srv.Addr)
+		go func() { serveErr <- srv.ListenAndServeTLS("", "") }()
+	} else {
+		logger.InfoLogger.Printf("Starting server on %s", srv.Addr)
+		go func() { serveErr <- srv.ListenAndServe() }()
+	}
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+
+	if cfg.ShutdownDelay > 0 {
+		logger.InfoLogger.Printf("Shutting down in %s", cfg.ShutdownDelay)
+		select {
+		case <-time.After(cfg.ShutdownDelay):
+		case err := <-serveErr:
+			return err
+		}
+	}
+
+	logger.InfoLogger.Printf("Shutting down, waiting up to %s for requests in flight", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Cut off whatever is still running rather than hang the deploy
+		srv.Close()
+		return fmt.Errorf("shutting down server: %w", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/apierror"
+)
+
+// Broker fans out events to Server-Sent Events subscribers grouped by topic
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan []byte]struct{}
+	closed      bool
+	done        chan struct{}
+}
+
+// NewBroker returns an empty broker
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[string]map[chan []byte]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for topic. The returned function must be called to unsubscribe.
+func (b *Broker) Subscribe(topic string) (<-chan []byte, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan []byte, 16)
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[chan []byte]struct{})
+	}
+	b.subscribers[topic][ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[topic][ch]; ok {
+			delete(b.subscribers[topic], ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish sends event as JSON to every subscriber of topic. Slow subscribers miss events instead of blocking.
+func (b *Broker) Publish(topic string, event interface{}) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[topic] {
+		select {
+		case ch <- data:
+		default:
+		}
+	}
+	return nil
+}
+
+// Close disconnects every subscriber
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	close(b.done)
+	for topic, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			close(ch)
+		}
+		delete(b.subscribers, topic)
+	}
+}
+
+// Serve streams events of topic to w until the client disconnects or the broker is closed.
+// initial, if not nil, is sent before any published event.
+func (b *Broker) Serve(w http.ResponseWriter, r *http.Request, topic string, initial interface{}) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Respond(w, r, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	events, unsubscribe := b.Subscribe(topic)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	if initial != nil {
+		data, err := json.Marshal(initial)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.done:
+			return
+		case data, ok := <-events:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
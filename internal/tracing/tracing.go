@@ -0,0 +1,87 @@
+// Package tracing sets up OpenTelemetry so HTTP handlers and SQL statements
+// are recorded as spans of one trace per request
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/darrenjon/restaurant-ordering-system/internal/config"
+)
+
+// instrumentation names the spans this module creates
+const instrumentation = "github.com/darrenjon/restaurant-ordering-system"
+
+// Tracer returns the tracer handlers and database callbacks start spans with.
+// Until Setup installs a provider, its spans are not recorded.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Setup installs the configured exporter as the global tracer provider. The
+// returned function flushes spans still buffered and must be called on exit.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	var exporter sdktrace.SpanExporter
+	switch cfg.Exporter {
+	case config.ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case config.ExporterStdout:
+		var err error
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, fmt.Errorf("creating stdout exporter: %w", err)
+		}
+	case config.ExporterOTLP:
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		var err error
+		exporter, err = otlptracehttp.New(ctx, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+
+	provider := NewProvider(cfg, sdktrace.WithBatcher(exporter))
+	Install(provider)
+	return provider.Shutdown, nil
+}
+
+// NewProvider returns a tracer provider for the service named in cfg, sampled
+// at cfg.SampleRatio unless the caller's trace was already sampled
+func NewProvider(cfg config.TracingConfig, opts ...sdktrace.TracerProviderOption) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName))
+	opts = append([]sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	}, opts...)
+	return sdktrace.NewTracerProvider(opts...)
+}
+
+// Install makes provider the global tracer provider and accepts W3C trace
+// context from callers. Tests use it with an in-memory exporter:
+//
+//	exporter := tracetest.NewInMemoryExporter()
+//	tracing.Install(tracing.NewProvider(cfg, sdktrace.WithSyncer(exporter)))
+func Install(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+}